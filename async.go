@@ -0,0 +1,328 @@
+package httplog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBatchSize is the batch size of AsyncBatch when WithBatchSize is not
+// given.
+const DefaultBatchSize = 64
+
+// AsyncFunc passes records to a RecordFunc from background goroutines. It
+// is created with Async or AsyncBatch. An AsyncFunc is an Option so it can
+// be passed to Wrap directly.
+type AsyncFunc struct {
+	fn        func(recs []Record)
+	records   chan Record
+	done      chan struct{}
+	workers   int
+	batchSize int
+	policy    DropPolicy
+
+	mu     sync.RWMutex
+	closed bool
+
+	// fallback is the time the queue may stay full before Log writes
+	// records itself; zero disables the fallback.
+	fallback  time.Duration
+	fullSince atomic.Int64
+	live      atomic.Int32
+	degraded  atomic.Bool
+	syncMu    sync.Mutex
+}
+
+// AsyncOption configures an AsyncFunc.
+type AsyncOption func(*AsyncFunc)
+
+// WithWorkers sets the number of goroutines passing records to the sink,
+// 1 by default. With more than one worker records may reach the sink out of
+// order and the sink must be safe for concurrent use.
+func WithWorkers(n int) AsyncOption {
+	return func(a *AsyncFunc) {
+		a.workers = max(n, 1)
+	}
+}
+
+// WithBatchSize sets the maximum number of queued records a worker takes at
+// once. Workers never wait for a batch to fill, so batching adds no latency;
+// under load it lets sinks such as a database insert many records at a
+// time.
+func WithBatchSize(n int) AsyncOption {
+	return func(a *AsyncFunc) {
+		a.batchSize = max(n, 1)
+	}
+}
+
+// WithDropPolicy sets what Log does when the queue is full, DropNewest by
+// default. Each activation of a policy is counted in Stats.
+func WithDropPolicy(policy DropPolicy) AsyncOption {
+	return func(a *AsyncFunc) {
+		a.policy = policy
+	}
+}
+
+// WithSyncFallback makes the AsyncFunc pass records to the sink from Log
+// itself, so logging never silently stops, once every worker died from a
+// panic in the sink or the queue stayed full for longer than after. The
+// switch is logged with a TypePipeline record with the event
+// "async_fallback" and the reason "workers_stopped" or "queue_full", and
+// is permanent; Ping reports it. In this mode records are written one at a
+// time and panics in the sink are counted in Stats.WriteErrors. With the
+// Block policy Log waits at most after for room before falling back.
+func WithSyncFallback(after time.Duration) AsyncOption {
+	return func(a *AsyncFunc) {
+		a.fallback = after
+	}
+}
+
+// Async returns an AsyncFunc that queues up to size records for fn so slow
+// sinks, such as network writers, do not add latency to requests. Records
+// that do not fit in the queue are dropped unless WithDropPolicy says
+// otherwise. Call Close to flush the queue before the program exits.
+func Async(fn RecordFunc, size int, options ...AsyncOption) *AsyncFunc {
+	return AsyncBatch(func(recs []Record) {
+		for _, rec := range recs {
+			fn(rec)
+		}
+	}, size, options...)
+}
+
+// AsyncBatch is like Async for sinks that write many records at once. fn
+// receives between one and the batch size of WithBatchSize, DefaultBatchSize
+// by default, records and must not keep the slice after it returns.
+func AsyncBatch(fn func(recs []Record), size int, options ...AsyncOption) *AsyncFunc {
+	a := &AsyncFunc{
+		fn:        fn,
+		records:   make(chan Record, size),
+		done:      make(chan struct{}),
+		workers:   1,
+		batchSize: DefaultBatchSize,
+	}
+	for _, o := range options {
+		o(a)
+	}
+	var wg sync.WaitGroup
+	wg.Add(a.workers)
+	a.live.Store(int32(a.workers))
+	for i := 0; i < a.workers; i++ {
+		go func() {
+			defer wg.Done()
+			if a.fallback > 0 {
+				defer a.recoverWorker()
+			}
+			a.run()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(a.done)
+	}()
+	return a
+}
+
+func (a *AsyncFunc) run() {
+	batch := make([]Record, 0, a.batchSize)
+	for rec := range a.records {
+		batch = append(batch[:0], rec)
+	fill:
+		for len(batch) < a.batchSize {
+			select {
+			case rec, ok := <-a.records:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, rec)
+			default:
+				break fill
+			}
+		}
+		if a.degraded.Load() {
+			// Log writes records too so keep sinks from being called
+			// concurrently.
+			a.callLocked(batch)
+		} else {
+			a.fn(batch)
+		}
+		clear(batch)
+	}
+}
+
+// recoverWorker recovers a panic of the sink in a worker and falls back to
+// synchronous writes once no worker is left.
+func (a *AsyncFunc) recoverWorker() {
+	if v := recover(); v == nil {
+		return
+	}
+	stats.writeErrors.Add(1)
+	if a.live.Add(-1) == 0 {
+		a.degrade("workers_stopped")
+		// Nothing reads the queue anymore so write what is left.
+		for {
+			select {
+			case rec, ok := <-a.records:
+				if !ok {
+					return
+				}
+				a.logSync(rec)
+			default:
+				return
+			}
+		}
+	}
+}
+
+// degrade switches to synchronous writes, emitting the warning record once.
+func (a *AsyncFunc) degrade(reason string) {
+	if !a.degraded.CompareAndSwap(false, true) {
+		return
+	}
+	a.logSync(Record{
+		Type:     TypePipeline,
+		Time:     timeNow(),
+		minLevel: slog.LevelWarn,
+		Attrs: []slog.Attr{
+			slog.String("event", "async_fallback"),
+			slog.String("reason", reason),
+			slog.Int("queued", len(a.records)),
+		},
+	})
+}
+
+// callLocked passes recs to the sink while holding syncMu, releasing it
+// when the sink panics.
+func (a *AsyncFunc) callLocked(recs []Record) {
+	a.syncMu.Lock()
+	defer a.syncMu.Unlock()
+	a.fn(recs)
+}
+
+// logSync passes rec to the sink from the calling goroutine.
+func (a *AsyncFunc) logSync(rec Record) {
+	defer func() {
+		if v := recover(); v != nil {
+			stats.writeErrors.Add(1)
+		}
+	}()
+	a.callLocked([]Record{rec})
+}
+
+// queueFull reports, for WithSyncFallback, whether the queue has been full
+// for too long.
+func (a *AsyncFunc) queueFull() bool {
+	now := timeNow().UnixNano()
+	since := a.fullSince.Load()
+	if since == 0 {
+		a.fullSince.CompareAndSwap(0, now)
+		return false
+	}
+	return time.Duration(now-since) > a.fallback
+}
+
+// blockOrFallBack waits for room for rec in the queue for at most the
+// fallback time and then writes it synchronously.
+func (a *AsyncFunc) blockOrFallBack(rec Record) {
+	timer := time.NewTimer(a.fallback)
+	defer timer.Stop()
+	select {
+	case a.records <- rec:
+		a.fullSince.Store(0)
+	case <-timer.C:
+		a.degrade("queue_full")
+		a.logSync(rec)
+	}
+}
+
+// Log queues rec. When the queue is full rec is handled according to the
+// policy of WithDropPolicy; Log only blocks with Block. When the AsyncFunc
+// is closed rec is dropped.
+func (a *AsyncFunc) Log(rec Record) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		stats.queueDropped.Add(1)
+		return
+	}
+	if a.degraded.Load() {
+		a.logSync(rec)
+		return
+	}
+	// evicted is set once DropOldest made room, so the queue is still
+	// full as far as the fallback is concerned.
+	evicted := false
+	for {
+		select {
+		case a.records <- rec:
+			if a.fallback > 0 && !evicted && a.fullSince.Load() != 0 {
+				a.fullSince.Store(0)
+			}
+			return
+		default:
+		}
+		if a.fallback > 0 && a.queueFull() {
+			a.degrade("queue_full")
+			a.logSync(rec)
+			return
+		}
+		switch a.policy {
+		case Block:
+			stats.queueBlocked.Add(1)
+			if a.fallback > 0 {
+				a.blockOrFallBack(rec)
+				return
+			}
+			a.records <- rec
+			return
+		case DropOldest:
+			select {
+			case <-a.records:
+				evicted = true
+				stats.queueDropped.Add(1)
+				stats.queueDroppedOldest.Add(1)
+			default:
+				if cap(a.records) == 0 {
+					stats.queueDropped.Add(1)
+					return
+				}
+			}
+		default:
+			stats.queueDropped.Add(1)
+			return
+		}
+	}
+}
+
+func (a *AsyncFunc) apply(c *config) { RecordFunc(a.Log).apply(c) }
+
+// Close stops accepting records and waits until the queued records have been
+// passed to fn.
+func (a *AsyncFunc) Close() {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.records)
+	}
+	a.mu.Unlock()
+	<-a.done
+}
+
+// Ping reports an error once the AsyncFunc is closed or fell back to
+// synchronous writes, or while its queue is full.
+func (a *AsyncFunc) Ping(context.Context) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return errors.New("httplog: async queue closed")
+	}
+	if a.degraded.Load() {
+		return errors.New("httplog: async queue fell back to synchronous writes")
+	}
+	if len(a.records) == cap(a.records) {
+		return errors.New("httplog: async queue full")
+	}
+	return nil
+}
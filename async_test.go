@@ -0,0 +1,334 @@
+package httplog_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestAsync(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		count int
+	)
+	async := httplog.Async(func(httplog.Record) {
+		mu.Lock()
+		defer mu.Unlock()
+		count++
+	}, 10)
+
+	for i := 0; i < 10; i++ {
+		async.Log(httplog.Record{})
+	}
+	async.Close()
+	async.Log(httplog.Record{})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if count != 10 {
+		t.Errorf("expected 10 records got %d", count)
+	}
+}
+
+func TestAsyncBatch(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		count   int
+		largest int
+	)
+	release := make(chan struct{})
+	async := httplog.AsyncBatch(func(recs []httplog.Record) {
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		count += len(recs)
+		largest = max(largest, len(recs))
+	}, 100, httplog.WithWorkers(2), httplog.WithBatchSize(8))
+
+	for i := 0; i < 100; i++ {
+		async.Log(httplog.Record{})
+	}
+	close(release)
+	async.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if count != 100 {
+		t.Errorf("expected 100 records got %d", count)
+	}
+	if largest < 2 || largest > 8 {
+		t.Errorf("expected batches of up to 8 records got a batch of %d", largest)
+	}
+}
+
+func TestWithDropPolicy(t *testing.T) {
+	newSink := func() (httplog.RecordFunc, chan struct{}, chan struct{}, func() []int) {
+		var (
+			mu       sync.Mutex
+			statuses []int
+		)
+		started, release := make(chan struct{}, 1), make(chan struct{})
+		return func(rec httplog.Record) {
+				select {
+				case started <- struct{}{}:
+				default:
+				}
+				<-release
+				mu.Lock()
+				defer mu.Unlock()
+				statuses = append(statuses, rec.Status)
+			}, started, release, func() []int {
+				mu.Lock()
+				defer mu.Unlock()
+				return statuses
+			}
+	}
+
+	t.Run("drop oldest", func(t *testing.T) {
+		before := httplog.ReadStats()
+		fn, started, release, statuses := newSink()
+		async := httplog.Async(fn, 2, httplog.WithDropPolicy(httplog.DropOldest), httplog.WithBatchSize(1))
+		async.Log(httplog.Record{Status: 1})
+		<-started
+		for status := 2; status <= 5; status++ {
+			async.Log(httplog.Record{Status: status})
+		}
+		close(release)
+		async.Close()
+
+		if got := statuses(); !slices.Equal(got, []int{1, 4, 5}) {
+			t.Errorf("expected the newest records to be kept got %v", got)
+		}
+		if got := httplog.ReadStats().QueueDroppedOldest - before.QueueDroppedOldest; got != 2 {
+			t.Errorf("expected 2 records dropped for newer ones got %d", got)
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		before := httplog.ReadStats()
+		fn, started, release, statuses := newSink()
+		async := httplog.Async(fn, 1, httplog.WithDropPolicy(httplog.Block), httplog.WithBatchSize(1))
+		async.Log(httplog.Record{Status: 1})
+		<-started
+		async.Log(httplog.Record{Status: 2})
+		logged := make(chan struct{})
+		go func() {
+			defer close(logged)
+			async.Log(httplog.Record{Status: 3})
+		}()
+		for httplog.ReadStats().QueueBlocked == before.QueueBlocked {
+			time.Sleep(time.Millisecond)
+		}
+		close(release)
+		<-logged
+		async.Close()
+
+		if got := statuses(); !slices.Equal(got, []int{1, 2, 3}) {
+			t.Errorf("expected every record got %v", got)
+		}
+	})
+}
+
+func TestWithSyncFallback(t *testing.T) {
+	type sink struct {
+		mu      sync.Mutex
+		records []httplog.Record
+	}
+	record := func(s *sink, rec httplog.Record) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.records = append(s.records, rec)
+	}
+	fallbackReason := func(s *sink) string {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, rec := range s.records {
+			if rec.Type == httplog.TypePipeline {
+				for _, a := range rec.Attrs {
+					if a.Key == "reason" {
+						return a.Value.String()
+					}
+				}
+			}
+		}
+		return ""
+	}
+	hasStatus := func(s *sink, status int) bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, rec := range s.records {
+			if rec.Status == status {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("workers stopped", func(t *testing.T) {
+		var s sink
+		async := httplog.Async(func(rec httplog.Record) {
+			if rec.Status == 1 {
+				panic("broken sink")
+			}
+			record(&s, rec)
+		}, 10, httplog.WithSyncFallback(time.Hour))
+		defer async.Close()
+
+		async.Log(httplog.Record{Status: 1})
+		for async.Ping(context.Background()) == nil {
+			time.Sleep(time.Millisecond)
+		}
+		async.Log(httplog.Record{Status: 2})
+
+		if got := fallbackReason(&s); got != "workers_stopped" {
+			t.Errorf("expected a fallback warning for stopped workers got %q", got)
+		}
+		if !hasStatus(&s, 2) {
+			t.Errorf("expected the record to be written synchronously")
+		}
+	})
+
+	t.Run("queue full", func(t *testing.T) {
+		var s sink
+		release := make(chan struct{})
+		started := make(chan struct{})
+		async := httplog.Async(func(rec httplog.Record) {
+			if rec.Status == 1 {
+				close(started)
+				<-release
+			}
+			record(&s, rec)
+		}, 1, httplog.WithSyncFallback(10*time.Millisecond), httplog.WithBatchSize(1))
+
+		async.Log(httplog.Record{Status: 1})
+		<-started
+		async.Log(httplog.Record{Status: 2})
+		async.Log(httplog.Record{Status: 3})
+		time.Sleep(20 * time.Millisecond)
+		async.Log(httplog.Record{Status: 4})
+
+		if got := fallbackReason(&s); got != "queue_full" {
+			t.Errorf("expected a fallback warning for a full queue got %q", got)
+		}
+		if !hasStatus(&s, 4) {
+			t.Errorf("expected the record to be written synchronously")
+		}
+		if err := async.Ping(context.Background()); err == nil {
+			t.Errorf("expected Ping to report the fallback")
+		}
+		close(release)
+		async.Close()
+		if !hasStatus(&s, 2) {
+			t.Errorf("expected the queued record to be written")
+		}
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		var s sink
+		release := make(chan struct{})
+		started := make(chan struct{})
+		async := httplog.Async(func(rec httplog.Record) {
+			if rec.Status == 1 {
+				close(started)
+				<-release
+			}
+			record(&s, rec)
+		}, 1, httplog.WithDropPolicy(httplog.DropOldest), httplog.WithSyncFallback(10*time.Millisecond), httplog.WithBatchSize(1))
+
+		async.Log(httplog.Record{Status: 1})
+		<-started
+		async.Log(httplog.Record{Status: 2})
+		async.Log(httplog.Record{Status: 3})
+		time.Sleep(20 * time.Millisecond)
+		async.Log(httplog.Record{Status: 4})
+
+		if got := fallbackReason(&s); got != "queue_full" {
+			t.Errorf("expected a fallback warning for a full queue got %q", got)
+		}
+		if !hasStatus(&s, 4) {
+			t.Errorf("expected the record to be written synchronously")
+		}
+		close(release)
+		async.Close()
+	})
+
+	t.Run("panicking sink", func(t *testing.T) {
+		var s sink
+		release := make(chan struct{})
+		started := make(chan struct{})
+		async := httplog.Async(func(rec httplog.Record) {
+			switch rec.Status {
+			case 1:
+				close(started)
+				<-release
+			case 2, 5:
+				panic("broken sink")
+			}
+			record(&s, rec)
+		}, 1, httplog.WithSyncFallback(10*time.Millisecond), httplog.WithBatchSize(1))
+		defer async.Close()
+
+		async.Log(httplog.Record{Status: 1})
+		<-started
+		async.Log(httplog.Record{Status: 2})
+		async.Log(httplog.Record{Status: 3})
+		time.Sleep(20 * time.Millisecond)
+		async.Log(httplog.Record{Status: 4})
+		// The worker now panics on record 2 in degraded mode.
+		close(release)
+
+		logged := make(chan struct{})
+		go func() {
+			defer close(logged)
+			async.Log(httplog.Record{Status: 5})
+			async.Log(httplog.Record{Status: 6})
+		}()
+		select {
+		case <-logged:
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected Log to return after the sink panicked")
+		}
+		if !hasStatus(&s, 6) {
+			t.Errorf("expected the record after the panic to be written")
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		var s sink
+		release := make(chan struct{})
+		started := make(chan struct{})
+		async := httplog.Async(func(rec httplog.Record) {
+			if rec.Status == 1 {
+				close(started)
+				<-release
+			}
+			record(&s, rec)
+		}, 1, httplog.WithDropPolicy(httplog.Block), httplog.WithSyncFallback(10*time.Millisecond), httplog.WithBatchSize(1))
+
+		async.Log(httplog.Record{Status: 1})
+		<-started
+		async.Log(httplog.Record{Status: 2})
+		logged := make(chan struct{})
+		go func() {
+			defer close(logged)
+			async.Log(httplog.Record{Status: 3})
+		}()
+		select {
+		case <-logged:
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected Log to fall back instead of blocking")
+		}
+		if got := fallbackReason(&s); got != "queue_full" {
+			t.Errorf("expected a fallback warning for a full queue got %q", got)
+		}
+		if !hasStatus(&s, 3) {
+			t.Errorf("expected the record to be written synchronously")
+		}
+		close(release)
+		async.Close()
+	})
+}
@@ -0,0 +1,55 @@
+package httplog
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// BaggageHeader is the W3C Baggage header.
+const BaggageHeader = "Baggage"
+
+// WithBaggage adds the W3C baggage entries with the given keys to each
+// record as a "baggage" group. Entries with other keys are ignored so
+// arbitrary client supplied baggage does not end up in the logs.
+func WithBaggage(keys ...string) Option {
+	allowed := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		allowed[key] = struct{}{}
+	}
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			attrs := baggage(rec.Request.Header, allowed)
+			if len(attrs) == 0 {
+				return
+			}
+			rec.Attrs = append(rec.Attrs, slog.Attr{Key: "baggage", Value: slog.GroupValue(attrs...)})
+		})
+	})
+}
+
+// baggage parses the list members of the baggage headers in h and returns
+// those with an allowed key. Properties of list members are discarded.
+func baggage(h http.Header, allowed map[string]struct{}) []slog.Attr {
+	var attrs []slog.Attr
+	for _, value := range h.Values(BaggageHeader) {
+		for _, member := range strings.Split(value, ",") {
+			member, _, _ = strings.Cut(member, ";")
+			key, val, ok := strings.Cut(member, "=")
+			if !ok {
+				continue
+			}
+			key = strings.TrimSpace(key)
+			if _, ok := allowed[key]; !ok {
+				continue
+			}
+			val, err := url.PathUnescape(strings.TrimSpace(val))
+			if err != nil {
+				continue
+			}
+			attrs = append(attrs, slog.String(key, val))
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,55 @@
+package httplog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithBaggage(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithBaggage("team", "experiment"), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Add(httplog.BaggageHeader, "team=payments;ttl=30, user=secret")
+	r.Header.Add(httplog.BaggageHeader, "experiment=blue%20button")
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	if len(rec.Attrs) != 1 {
+		t.Fatalf("expected one attribute got %v", rec.Attrs)
+	}
+	group := rec.Attrs[0]
+	if group.Key != "baggage" {
+		t.Fatalf("expected baggage group got %q", group.Key)
+	}
+	got := map[string]string{}
+	for _, a := range group.Value.Group() {
+		got[a.Key] = a.Value.String()
+	}
+	want := map[string]string{"team": "payments", "experiment": "blue button"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v got %v", want, got)
+	}
+	for key, val := range want {
+		if got[key] != val {
+			t.Errorf("expected %s to be %q got %q", key, val, got[key])
+		}
+	}
+}
+
+func TestWithBaggage_absent(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithBaggage("team"), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(rec.Attrs) != 0 {
+		t.Errorf("expected no attributes got %v", rec.Attrs)
+	}
+}
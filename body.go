@@ -0,0 +1,43 @@
+package httplog
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// DefaultPeekLimit is the number of request body bytes read by body
+// inspecting options such as WithGraphQL.
+const DefaultPeekLimit = 64 << 10
+
+// peekBody reads up to limit bytes of the request body and replaces the body
+// so the handler still reads it in full. The returned slice is shorter than
+// the body when the body exceeds limit.
+func peekBody(req *http.Request, limit int64) []byte {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	p, err := io.ReadAll(io.LimitReader(req.Body, limit))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.MultiReader(bytes.NewReader(p), &errReader{err: err, r: req.Body}),
+		Closer: req.Body,
+	}
+	return p
+}
+
+// errReader returns err, if it is non nil, instead of reading from r. It keeps
+// an error hit while peeking visible to the handler.
+type errReader struct {
+	err error
+	r   io.Reader
+}
+
+func (e *errReader) Read(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	return e.r.Read(p)
+}
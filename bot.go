@@ -0,0 +1,48 @@
+package httplog
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// DefaultBotPatterns are the user agent substrings WithBotDetection matches
+// when it is not given any patterns.
+var DefaultBotPatterns = []string{
+	"bot", "crawler", "spider", "slurp", "crawl",
+	"curl/", "wget/", "python-requests", "go-http-client", "headlesschrome",
+	"facebookexternalhit", "bingpreview", "mediapartners-google", "lighthouse",
+}
+
+// WithBotDetection adds bot=true to records of requests whose user agent
+// contains one of patterns, ignoring case, or is empty, so dashboards can
+// separate crawler and script traffic from real users. DefaultBotPatterns is
+// used when no patterns are given.
+func WithBotDetection(patterns ...string) Option {
+	if len(patterns) == 0 {
+		patterns = DefaultBotPatterns
+	}
+	lower := make([]string, len(patterns))
+	for i, p := range patterns {
+		lower[i] = strings.ToLower(p)
+	}
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			if isBot(rec.UserAgent, lower) {
+				rec.Attrs = append(rec.Attrs, slog.Bool("bot", true))
+			}
+		})
+	})
+}
+
+func isBot(userAgent string, patterns []string) bool {
+	if userAgent == "" {
+		return true
+	}
+	userAgent = strings.ToLower(userAgent)
+	for _, p := range patterns {
+		if strings.Contains(userAgent, p) {
+			return true
+		}
+	}
+	return false
+}
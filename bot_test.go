@@ -0,0 +1,37 @@
+package httplog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithBotDetection(t *testing.T) {
+	for _, tt := range []struct {
+		Name      string
+		Patterns  []string
+		UserAgent string
+		Bot       bool
+	}{
+		{Name: "googlebot", UserAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", Bot: true},
+		{Name: "curl", UserAgent: "curl/8.4.0", Bot: true},
+		{Name: "empty", UserAgent: "", Bot: true},
+		{Name: "browser", UserAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0"},
+		{Name: "custom", Patterns: []string{"Uptime"}, UserAgent: "uptime-checker/1.0", Bot: true},
+		{Name: "custom replaces defaults", Patterns: []string{"Uptime"}, UserAgent: "curl/8.4.0"},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var rec httplog.Record
+			h := httplog.Wrap(http.NotFoundHandler(), httplog.WithBotDetection(tt.Patterns...), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("User-Agent", tt.UserAgent)
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got := recordFields(rec)["bot"] == true; got != tt.Bot {
+				t.Errorf("expected bot to be %t got %t", tt.Bot, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package httplog
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// BurstDetector watches for bursts of 401, 403 and 404 responses to a single
+// client IP, a sign of scanning or credential guessing. It is created with
+// NewBurstDetector. A BurstDetector is an Option so it can be passed to Wrap
+// directly.
+type BurstDetector struct {
+	threshold int
+	window    time.Duration
+	fn        RecordFunc
+
+	mu        sync.Mutex
+	clients   map[string][]burstHit
+	lastSweep time.Time
+}
+
+type burstHit struct {
+	time   time.Time
+	status int
+}
+
+// NewBurstDetector returns a BurstDetector that passes fn a record of type
+// TypeSecurity once a client IP gets threshold matching responses within
+// window. The record has the attrs event="status_burst", client_ip, count,
+// window and the count of each status in a "statuses" group. Counting starts
+// over after each event.
+func NewBurstDetector(threshold int, window time.Duration, fn RecordFunc) *BurstDetector {
+	return &BurstDetector{
+		threshold: threshold,
+		window:    window,
+		fn:        fn,
+		clients:   make(map[string][]burstHit),
+	}
+}
+
+// Log counts rec when it has a matching status.
+func (d *BurstDetector) Log(rec Record) {
+	switch rec.Status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+	default:
+		return
+	}
+	if rec.ClientIP == "" {
+		return
+	}
+	now := rec.Time
+	if now.IsZero() {
+		now = timeNow()
+	}
+	event, ok := d.count(rec.ClientIP, now, rec.Status)
+	if ok {
+		d.fn(event)
+	}
+}
+
+func (d *BurstDetector) apply(c *config) { RecordFunc(d.Log).apply(c) }
+
+func (d *BurstDetector) count(ip string, now time.Time, status int) (Record, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if now.Sub(d.lastSweep) > d.window {
+		d.sweep(now)
+	}
+	hits := append(withinWindow(d.clients[ip], now, d.window), burstHit{time: now, status: status})
+	if len(hits) < d.threshold {
+		d.clients[ip] = hits
+		return Record{}, false
+	}
+	delete(d.clients, ip)
+
+	counts := make(map[int]int)
+	for _, hit := range hits {
+		counts[hit.status]++
+	}
+	var statuses []slog.Attr
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound} {
+		if n := counts[status]; n > 0 {
+			statuses = append(statuses, slog.Int(strconv.Itoa(status), n))
+		}
+	}
+	return Record{
+		Type: TypeSecurity,
+		Time: now,
+		Attrs: []slog.Attr{
+			slog.String("event", "status_burst"),
+			slog.String("client_ip", ip),
+			slog.Int("count", len(hits)),
+			slog.Duration("window", d.window),
+			slog.Attr{Key: "statuses", Value: slog.GroupValue(statuses...)},
+		},
+	}, true
+}
+
+// sweep forgets clients without recent hits so the map does not grow with
+// every client ever seen.
+func (d *BurstDetector) sweep(now time.Time) {
+	for ip, hits := range d.clients {
+		if hits = withinWindow(hits, now, d.window); len(hits) == 0 {
+			delete(d.clients, ip)
+		} else {
+			d.clients[ip] = hits
+		}
+	}
+	d.lastSweep = now
+}
+
+func withinWindow(hits []burstHit, now time.Time, window time.Duration) []burstHit {
+	i := 0
+	for i < len(hits) && now.Sub(hits[i].time) > window {
+		i++
+	}
+	return hits[i:]
+}
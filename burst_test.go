@@ -0,0 +1,46 @@
+package httplog_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestBurstDetector(t *testing.T) {
+	var events []httplog.Record
+	d := httplog.NewBurstDetector(3, time.Minute, func(rec httplog.Record) { events = append(events, rec) })
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	log := func(ip string, status int, offset time.Duration) {
+		d.Log(httplog.Record{Time: start.Add(offset), ClientIP: ip, Status: status})
+	}
+	log("192.0.2.1", http.StatusNotFound, 0)
+	log("192.0.2.1", http.StatusOK, time.Second)
+	log("192.0.2.2", http.StatusNotFound, time.Second)
+	log("192.0.2.1", http.StatusUnauthorized, 2*time.Second)
+	if len(events) != 0 {
+		t.Fatalf("expected no event below the threshold got %d", len(events))
+	}
+	log("192.0.2.1", http.StatusNotFound, 3*time.Second)
+	if len(events) != 1 {
+		t.Fatalf("expected an event got %d", len(events))
+	}
+
+	event := events[0]
+	fields := recordFields(event)
+	if event.Type != httplog.TypeSecurity || fields["client_ip"] != "192.0.2.1" || fields["count"] != int64(3) {
+		t.Errorf("unexpected event %+v", event)
+	}
+	statuses, _ := groupFields(event, "statuses")
+	if statuses["404"] != int64(2) || statuses["401"] != int64(1) {
+		t.Errorf("unexpected statuses %v", statuses)
+	}
+
+	log("192.0.2.2", http.StatusNotFound, 2*time.Minute)
+	log("192.0.2.2", http.StatusNotFound, 2*time.Minute)
+	if len(events) != 1 {
+		t.Errorf("expected hits outside the window not to count got %d events", len(events))
+	}
+}
@@ -0,0 +1,34 @@
+package httplog
+
+// DropPolicy decides what happens to a record when its destination is full.
+type DropPolicy int
+
+const (
+	// DropNewest discards the record that does not fit.
+	DropNewest DropPolicy = iota
+	// Block waits until there is room for the record, adding latency to the
+	// request instead of losing the record.
+	Block
+	// DropOldest discards the oldest queued record to make room, keeping the
+	// most recent records. ToChannel handles it like DropNewest because it
+	// can not receive from its channel.
+	DropOldest
+)
+
+// ToChannel returns a RecordFunc that sends records to ch so applications
+// can consume them programmatically, for example to derive business
+// metrics. When ch is full the record is handled according to policy.
+func ToChannel(ch chan<- Record, policy DropPolicy) RecordFunc {
+	return func(rec Record) {
+		select {
+		case ch <- rec:
+		default:
+			if policy == Block {
+				stats.queueBlocked.Add(1)
+				ch <- rec
+				return
+			}
+			stats.queueDropped.Add(1)
+		}
+	}
+}
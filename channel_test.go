@@ -0,0 +1,35 @@
+package httplog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestToChannel(t *testing.T) {
+	records := make(chan httplog.Record, 1)
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.ToChannel(records, httplog.DropNewest))
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/first", nil))
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/second", nil))
+
+	if got := len(records); got != 1 {
+		t.Fatalf("expected one queued record got %d", got)
+	}
+	if rec := <-records; rec.Path != "/first" || rec.Status != http.StatusNotFound {
+		t.Errorf("unexpected record %+v", rec)
+	}
+}
+
+func TestToChannel_block(t *testing.T) {
+	records := make(chan httplog.Record)
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.ToChannel(records, httplog.Block))
+
+	go logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec := <-records; rec.Path != "/" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+}
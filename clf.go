@@ -0,0 +1,114 @@
+package httplog
+
+import (
+	"io"
+	"strconv"
+	"sync"
+)
+
+// clfTime is the time layout of the Common Log Format.
+const clfTime = "[02/Jan/2006:15:04:05 -0700]"
+
+// CommonLog returns a RecordFunc writing records to w in the Common Log
+// Format of the Apache and nginx access logs:
+//
+//	203.0.113.7 - - [10/Oct/2000:13:55:36 -0700] "GET /index.html HTTP/1.1" 200 2326
+//
+// The line uses the Path and Query of the record, so redaction options
+// apply, and the number of body bytes the handler wrote. Records of other
+// types than TypeRequest are skipped. Writes to w are serialized.
+//
+// A service moving consumers from such a file to JSON can pass both sinks to
+// Wrap, each record is written in both formats:
+//
+//	httplog.Wrap(h, httplog.CommonLog(accessLog), httplog.JSONWriter(os.Stdout, nil))
+func CommonLog(w io.Writer) RecordFunc {
+	return clfWriter(w, false)
+}
+
+// CombinedLog is like CommonLog but writes the Combined Log Format, which
+// adds the Referer and User-Agent headers.
+func CombinedLog(w io.Writer) RecordFunc {
+	return clfWriter(w, true)
+}
+
+func clfWriter(w io.Writer, combined bool) RecordFunc {
+	var mu sync.Mutex
+	return func(rec Record) {
+		if rec.Type != "" && rec.Type != TypeRequest {
+			return
+		}
+		line := appendCLF(nil, rec, combined)
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := w.Write(line); err != nil {
+			stats.writeErrors.Add(1)
+		}
+	}
+}
+
+func appendCLF(b []byte, rec Record, combined bool) []byte {
+	b = appendCLFField(b, rec.ClientIP)
+	b = append(b, " - - "...)
+	b = rec.Time.AppendFormat(b, clfTime)
+	b = append(b, ` "`...)
+	b = appendCLFEscaped(b, rec.Method)
+	b = append(b, ' ')
+	b = appendCLFEscaped(b, rec.Path)
+	if rec.Query != "" {
+		b = append(b, '?')
+		b = appendCLFEscaped(b, rec.Query)
+	}
+	proto := "HTTP/1.1"
+	if rec.Request != nil && rec.Request.Proto != "" {
+		proto = rec.Request.Proto
+	}
+	b = append(b, ' ')
+	b = appendCLFEscaped(b, proto)
+	b = append(b, `" `...)
+	b = strconv.AppendInt(b, int64(rec.Status), 10)
+	b = append(b, ' ')
+	if rec.writer != nil && rec.writer.written > 0 {
+		b = strconv.AppendInt(b, rec.writer.written, 10)
+	} else {
+		b = append(b, '-')
+	}
+	if combined {
+		var referer string
+		if rec.Request != nil {
+			referer = rec.Request.Referer()
+		}
+		b = append(b, ` "`...)
+		b = appendCLFEscaped(b, referer)
+		b = append(b, `" "`...)
+		b = appendCLFEscaped(b, rec.UserAgent)
+		b = append(b, '"')
+	}
+	return append(b, '\n')
+}
+
+// appendCLFField appends s, or "-" when it is empty, as an unquoted field.
+func appendCLFField(b []byte, s string) []byte {
+	if s == "" {
+		return append(b, '-')
+	}
+	return appendCLFEscaped(b, s)
+}
+
+// appendCLFEscaped appends s escaping quotes, backslashes, and control and
+// non ASCII bytes the way Apache does, so a line can not be split or
+// forged by a client.
+func appendCLFEscaped(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c < 0x20 || c >= 0x7f:
+			b = append(b, '\\', 'x', hex[c>>4], hex[c&0xf])
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
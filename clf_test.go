@@ -0,0 +1,80 @@
+package httplog_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestCommonLog(t *testing.T) {
+	restore := httplog.SetClock(func() time.Time {
+		return time.Date(2000, time.October, 10, 13, 55, 36, 0, time.FixedZone("", -7*60*60))
+	})
+	defer restore()
+
+	for _, tt := range []struct {
+		Name string
+		Sink func(w *bytes.Buffer) httplog.RecordFunc
+		Want string
+	}{
+		{
+			Name: "common",
+			Sink: func(w *bytes.Buffer) httplog.RecordFunc { return httplog.CommonLog(w) },
+			Want: `203.0.113.7 - - [10/Oct/2000:13:55:36 -0700] "GET /index.html?lang=en HTTP/1.1" 200 5` + "\n",
+		},
+		{
+			Name: "combined",
+			Sink: func(w *bytes.Buffer) httplog.RecordFunc { return httplog.CombinedLog(w) },
+			Want: `203.0.113.7 - - [10/Oct/2000:13:55:36 -0700] "GET /index.html?lang=en HTTP/1.1" 200 5 "https://example.com/" "curl/8.0 \"quoted\""` + "\n",
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("hello"))
+			}), tt.Sink(&buf))
+
+			r := httptest.NewRequest(http.MethodGet, "/index.html?lang=en", nil)
+			r.RemoteAddr = "203.0.113.7:4321"
+			r.Header.Set("Referer", "https://example.com/")
+			r.Header.Set("User-Agent", `curl/8.0 "quoted"`)
+			logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got := buf.String(); got != tt.Want {
+				t.Errorf("expected %q got %q", tt.Want, got)
+			}
+		})
+	}
+}
+
+func TestCommonLog_escaping(t *testing.T) {
+	var buf bytes.Buffer
+	httplog.CommonLog(&buf)(httplog.Record{Method: http.MethodGet, Path: "/a\nb", Status: http.StatusNotFound})
+
+	got := buf.String()
+	if strings.Count(got, "\n") != 1 || !strings.Contains(got, `"GET /a\x0ab HTTP/1.1" 404 -`) {
+		t.Errorf("expected an escaped single line got %q", got)
+	}
+	if !regexp.MustCompile(`^- - - \[`).MatchString(got) {
+		t.Errorf("expected a missing client to be written as - got %q", got)
+	}
+}
+
+func TestCommonLog_dualFormat(t *testing.T) {
+	var legacy, structured bytes.Buffer
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.CommonLog(&legacy), httplog.JSONWriter(&structured, nil))
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	if !strings.Contains(legacy.String(), `"GET /missing HTTP/1.1" 404`) {
+		t.Errorf("unexpected common log line %q", legacy.String())
+	}
+	if !strings.Contains(structured.String(), `"path": "/missing"`) {
+		t.Errorf("unexpected JSON line %q", structured.String())
+	}
+}
@@ -0,0 +1,83 @@
+package httplog
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// SetClientID records the OAuth client_id or other API client identifier of
+// the request, typically once an authentication middleware has introspected
+// the access token. It is logged as client_id. SetClientID does nothing when
+// ctx does not belong to a request handled by Wrap.
+func SetClientID(ctx context.Context, clientID string) {
+	info, ok := requestInfoFrom(ctx)
+	if !ok {
+		return
+	}
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	info.clientID = clientID
+}
+
+func (info *requestInfo) getClientID() string {
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	return info.clientID
+}
+
+// WithClientIDHeader logs the value of header, for example "X-Client-Id", as
+// the client_id of the request unless the handler sets one with SetClientID.
+func WithClientIDHeader(header string) Option {
+	return optionFunc(func(c *config) {
+		c.inspectors = append(c.inspectors, func(req *http.Request) func(rec *Record) {
+			if clientID := req.Header.Get(header); clientID != "" {
+				SetClientID(req.Context(), clientID)
+			}
+			return nil
+		})
+	})
+}
+
+// WithAPIKeyHash adds api_key_hash, a truncated HMAC-SHA256 of the API key
+// sent in header keyed with secret, to each record so traffic and errors
+// can be reported per client without logging the key itself. Without secret
+// the hashes cannot be brute-forced offline, so keep it out of the logs and
+// use the same secret across instances to compare their hashes. When header
+// is "Authorization" the key is taken from a Bearer credential; of a Basic
+// credential only the user name is hashed so passwords never are.
+func WithAPIKeyHash(header string, secret []byte) Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			key := rec.Request.Header.Get(header)
+			if http.CanonicalHeaderKey(header) == "Authorization" {
+				key = authorizationKey(rec.Request, key)
+			}
+			if key == "" {
+				return
+			}
+			mac := hmac.New(sha256.New, secret)
+			mac.Write([]byte(key))
+			rec.Attrs = append(rec.Attrs, slog.String("api_key_hash", hex.EncodeToString(mac.Sum(nil)[:8])))
+		})
+	})
+}
+
+// authorizationKey returns the part of an Authorization header value that
+// identifies the client: the token of a Bearer credential, the user name of
+// a Basic credential and the credential of other schemes.
+func authorizationKey(req *http.Request, value string) string {
+	scheme, credential, ok := strings.Cut(value, " ")
+	if !ok {
+		return value
+	}
+	if strings.EqualFold(scheme, "Basic") {
+		user, _, _ := req.BasicAuth()
+		return user
+	}
+	return strings.TrimSpace(credential)
+}
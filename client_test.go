@@ -0,0 +1,76 @@
+package httplog_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestSetClientID(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httplog.SetClientID(r.Context(), "introspected-client")
+	}), httplog.WithClientIDHeader("X-Client-Id"), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Client-Id", "header-client")
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	if got := recordFields(rec)["client_id"]; got != "introspected-client" {
+		t.Errorf("expected client_id from the context got %v", got)
+	}
+}
+
+func TestWithClientIDHeader(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithClientIDHeader("X-Client-Id"), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Client-Id", "header-client")
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	if got := recordFields(rec)["client_id"]; got != "header-client" {
+		t.Errorf("expected client_id from the header got %v", got)
+	}
+}
+
+func TestWithAPIKeyHash(t *testing.T) {
+	secret := []byte("log secret")
+	hash := func(key string) string {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(key))
+		return hex.EncodeToString(mac.Sum(nil)[:8])
+	}
+
+	for _, tt := range []struct {
+		Header, Value, Key string
+	}{
+		{Header: "X-Api-Key", Value: "secret-key", Key: "secret-key"},
+		{Header: "Authorization", Value: "Bearer secret-key", Key: "secret-key"},
+		{Header: "Authorization", Value: "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:password")), Key: "alice"},
+	} {
+		want := hash(tt.Key)
+		var rec httplog.Record
+		logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithAPIKeyHash(tt.Header, secret), httplog.RecordFunc(func(r httplog.Record) {
+			rec = r
+		}))
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(tt.Header, tt.Value)
+		logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+		if got := recordFields(rec)["api_key_hash"]; got != want {
+			t.Errorf("expected api_key_hash %q for %s got %v", want, tt.Header, got)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package httplog
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// clock holds the time source and request ID generator, which tests may
+// replace with SetClock and SetIDGenerator. They are atomic because the time
+// is read on every write of a response.
+var clock struct {
+	now   atomic.Pointer[func() time.Time]
+	newID atomic.Pointer[func() string]
+}
+
+// SetClock makes httplog read the time from now instead of time.Now, and
+// returns a function restoring the previous clock. The clock is used for
+// request timestamps and durations, write timing, the windows of
+// aggregators such as Rollup and SLOTracker, the rotation of WithHashedIP
+// and the file names of ParquetWriter, so golden file tests of the logs can
+// be deterministic:
+//
+//	defer httplog.SetClock(func() time.Time { return fixed })()
+//
+// Periodic reporters such as RuntimeStats still tick in real time but stamp
+// their records with the clock. A nil now restores time.Now.
+func SetClock(now func() time.Time) (restore func()) {
+	var p *func() time.Time
+	if now != nil {
+		p = &now
+	}
+	prev := clock.now.Swap(p)
+	return func() { clock.now.Store(prev) }
+}
+
+// SetIDGenerator makes Wrap use newID for the IDs of requests without an
+// X-Request-Id header instead of random IDs, and returns a function
+// restoring the previous generator. A nil newID restores random IDs.
+func SetIDGenerator(newID func() string) (restore func()) {
+	var p *func() string
+	if newID != nil {
+		p = &newID
+	}
+	prev := clock.newID.Swap(p)
+	return func() { clock.newID.Store(prev) }
+}
+
+func timeNow() time.Time {
+	if now := clock.now.Load(); now != nil {
+		return (*now)()
+	}
+	return time.Now()
+}
+
+func timeSince(t time.Time) time.Duration { return timeNow().Sub(t) }
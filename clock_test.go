@@ -0,0 +1,90 @@
+package httplog_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestSetClock(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	defer httplog.SetClock(func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	})()
+	var n int
+	defer httplog.SetIDGenerator(func() string {
+		n++
+		return "req-" + strconv.Itoa(n)
+	})()
+
+	var buf bytes.Buffer
+	var times []time.Time
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.JSONWriter(&buf, nil), httplog.RecordFunc(func(rec httplog.Record) {
+		times = append(times, rec.Time)
+	}))
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/golden", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		logMux.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	want := `{"type": "HTTP_REQUEST", "schema": "httplog/v1", "method": "GET", "host": "example.com", "path": "/golden", "duration": "3ms", "status": 404, "request_id": "req-1", "client_ip": "192.0.2.1"}
+{"type": "HTTP_REQUEST", "schema": "httplog/v1", "method": "GET", "host": "example.com", "path": "/golden", "duration": "3ms", "status": 404, "request_id": "req-2", "client_ip": "192.0.2.1"}
+`
+	if buf.String() != want {
+		t.Errorf("expected deterministic output\n%s\ngot\n%s", want, buf.String())
+	}
+	if len(times) != 2 || times[0].Year() != 2024 || times[1].Sub(times[0]) != 4*time.Millisecond {
+		t.Errorf("expected request times from the clock got %v", times)
+	}
+}
+
+func TestSetClock_syncFallback(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		now = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	)
+	defer httplog.SetClock(func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	})()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var fellBack atomic.Bool
+	async := httplog.Async(func(rec httplog.Record) {
+		if rec.Type == httplog.TypePipeline {
+			fellBack.Store(true)
+		}
+		if rec.Status == 1 {
+			close(started)
+			<-release
+		}
+	}, 1, httplog.WithSyncFallback(time.Hour), httplog.WithBatchSize(1))
+	defer async.Close()
+	defer close(release)
+
+	async.Log(httplog.Record{Status: 1})
+	<-started
+	async.Log(httplog.Record{Status: 2})
+	async.Log(httplog.Record{Status: 3})
+	if async.Log(httplog.Record{Status: 4}); fellBack.Load() {
+		t.Fatal("expected no fallback before the clock passes the threshold")
+	}
+	mu.Lock()
+	now = now.Add(2 * time.Hour)
+	mu.Unlock()
+	async.Log(httplog.Record{Status: 5})
+	if !fellBack.Load() {
+		t.Errorf("expected the clock to drive the fallback")
+	}
+}
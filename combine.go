@@ -0,0 +1,142 @@
+package httplog
+
+import (
+	"math/rand"
+	"net"
+	"strings"
+)
+
+// Tee returns a RecordFunc that passes each record to every fn in order.
+func Tee(fns ...RecordFunc) RecordFunc {
+	return func(rec Record) {
+		for _, fn := range fns {
+			fn(rec)
+		}
+	}
+}
+
+// Filter returns a RecordFunc that passes a record to fn only when keep
+// returns true for it. Dropped records are counted in Stats.SinkSkipped.
+func Filter(keep func(rec Record) bool, fn RecordFunc) RecordFunc {
+	return func(rec Record) {
+		if !keep(rec) {
+			stats.sinkSkipped.Add(1)
+			return
+		}
+		fn(rec)
+	}
+}
+
+// MapRecord returns a RecordFunc that passes the result of transform to fn.
+// transform may modify the record it is given; Attrs should be copied
+// before they are modified in place because other functions passed to Tee
+// may share them.
+func MapRecord(transform func(rec Record) Record, fn RecordFunc) RecordFunc {
+	return func(rec Record) {
+		fn(transform(rec))
+	}
+}
+
+// Map is an Option that replaces each record with the result of transform
+// before it is emitted, so fields can be added, removed, or rewritten in one
+// place for every sink, for example to strip query strings in production:
+//
+//	httplog.Map(func(rec httplog.Record) httplog.Record {
+//		rec.Query = ""
+//		return rec
+//	})
+//
+// transform runs after sanitizing and truncation, in the order Map options
+// are passed to Wrap, so hooks, filters and sinks all see its result. Unlike
+// MapRecord it does not need to copy Attrs before modifying them.
+func Map(transform func(rec Record) Record) Option {
+	return optionFunc(func(c *config) {
+		c.finalizers = append(c.finalizers, func(rec *Record) {
+			*rec = transform(*rec)
+		})
+	})
+}
+
+// StatusRoutes holds the sinks RouteByStatus sends each status class to. A
+// nil sink discards the records of its class.
+type StatusRoutes struct {
+	// Success receives records with a status below 400.
+	Success RecordFunc
+	// ClientError receives records with a 4xx status.
+	ClientError RecordFunc
+	// ServerError receives records with a status of 500 or more.
+	ServerError RecordFunc
+}
+
+// RouteByStatus returns a RecordFunc that passes each record to the sink
+// for its status class. Unlike JSON, which writes server errors to both of
+// its loggers, each record goes to exactly one sink:
+//
+//	httplog.RouteByStatus(httplog.StatusRoutes{
+//		Success:     httplog.JSONWriter(os.Stdout, nil),
+//		ClientError: httplog.JSONWriter(os.Stdout, nil),
+//		ServerError: alerting,
+//	})
+func RouteByStatus(routes StatusRoutes) RecordFunc {
+	return func(rec Record) {
+		fn := routes.Success
+		switch {
+		case rec.Status >= 500:
+			fn = routes.ServerError
+		case rec.Status >= 400:
+			fn = routes.ClientError
+		}
+		if fn != nil {
+			fn(rec)
+		}
+	}
+}
+
+// RouteByHost returns a RecordFunc that passes each record to the sink for
+// its host, like the per virtual host access logs of nginx and Apache. Hosts
+// are matched ignoring case and port. Records of other hosts go to fallback,
+// which may be nil to discard them.
+//
+//	httplog.RouteByHost(map[string]httplog.RecordFunc{
+//		"api.example.com": httplog.JSONWriter(apiLog, nil),
+//		"www.example.com": httplog.JSONWriter(wwwLog, nil),
+//	}, httplog.JSONWriter(os.Stdout, nil))
+func RouteByHost(routes map[string]RecordFunc, fallback RecordFunc) RecordFunc {
+	normalized := make(map[string]RecordFunc, len(routes))
+	for host, fn := range routes {
+		normalized[normalizeHost(host)] = fn
+	}
+	return func(rec Record) {
+		fn, ok := normalized[normalizeHost(rec.Host)]
+		if !ok {
+			fn = fallback
+		}
+		if fn != nil {
+			fn(rec)
+		}
+	}
+}
+
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// Sample returns a RecordFunc that passes a random fraction, given by rate,
+// of records to fn. Dropped records are counted in Stats.SinkSkipped.
+func Sample(rate float64, fn RecordFunc) RecordFunc {
+	return func(rec Record) {
+		if !sampled(rate) {
+			stats.sinkSkipped.Add(1)
+			return
+		}
+		fn(rec)
+	}
+}
+
+// sampled randomly returns true for the given fraction of calls.
+func sampled(rate float64) bool {
+	return rate >= 1 || rand.Float64() < rate
+}
@@ -0,0 +1,146 @@
+package httplog_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestTee(t *testing.T) {
+	var first, second []int
+	fn := httplog.Tee(
+		func(rec httplog.Record) { first = append(first, rec.Status) },
+		func(rec httplog.Record) { second = append(second, rec.Status) },
+	)
+
+	fn(httplog.Record{Status: http.StatusOK})
+
+	if len(first) != 1 || len(second) != 1 {
+		t.Errorf("expected both functions to be called got %v and %v", first, second)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	var statuses []int
+	fn := httplog.Filter(func(rec httplog.Record) bool {
+		return rec.Status >= 400
+	}, func(rec httplog.Record) {
+		statuses = append(statuses, rec.Status)
+	})
+
+	before := httplog.ReadStats()
+	fn(httplog.Record{Status: http.StatusOK})
+	fn(httplog.Record{Status: http.StatusNotFound})
+
+	if len(statuses) != 1 || statuses[0] != http.StatusNotFound {
+		t.Errorf("expected only the 404 record got %v", statuses)
+	}
+	after := httplog.ReadStats()
+	if n := after.SinkSkipped - before.SinkSkipped; n != 1 {
+		t.Errorf("expected the skipped record to be counted got %d", n)
+	}
+	if after.Filtered != before.Filtered {
+		t.Errorf("expected records skipped by a sink not to count as filtered")
+	}
+}
+
+func TestRouteByStatus(t *testing.T) {
+	var success, clientError, serverError []int
+	fn := httplog.RouteByStatus(httplog.StatusRoutes{
+		Success:     func(rec httplog.Record) { success = append(success, rec.Status) },
+		ClientError: func(rec httplog.Record) { clientError = append(clientError, rec.Status) },
+		ServerError: func(rec httplog.Record) { serverError = append(serverError, rec.Status) },
+	})
+
+	for _, status := range []int{http.StatusOK, http.StatusFound, http.StatusNotFound, 499, http.StatusBadGateway} {
+		fn(httplog.Record{Status: status})
+	}
+
+	if len(success) != 2 || len(clientError) != 2 || len(serverError) != 1 {
+		t.Errorf("unexpected routing %v %v %v", success, clientError, serverError)
+	}
+
+	httplog.RouteByStatus(httplog.StatusRoutes{})(httplog.Record{Status: http.StatusOK})
+}
+
+func TestRouteByHost(t *testing.T) {
+	var api, fallback []string
+	fn := httplog.RouteByHost(map[string]httplog.RecordFunc{
+		"API.example.com": func(rec httplog.Record) { api = append(api, rec.Host) },
+	}, func(rec httplog.Record) { fallback = append(fallback, rec.Host) })
+
+	for _, host := range []string{"api.example.com", "api.example.com:8443", "www.example.com", ""} {
+		fn(httplog.Record{Host: host})
+	}
+
+	if len(api) != 2 || len(fallback) != 2 {
+		t.Errorf("unexpected routing %v %v", api, fallback)
+	}
+}
+
+func TestMapRecord(t *testing.T) {
+	var rec httplog.Record
+	fn := httplog.MapRecord(func(rec httplog.Record) httplog.Record {
+		rec.Query = ""
+		return rec
+	}, func(r httplog.Record) {
+		rec = r
+	})
+
+	fn(httplog.Record{Path: "/", Query: "token=secret"})
+
+	if rec.Path != "/" || rec.Query != "" {
+		t.Errorf("expected the transformed record got %+v", rec)
+	}
+}
+
+func TestMap(t *testing.T) {
+	var records []httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(),
+		httplog.Map(func(rec httplog.Record) httplog.Record {
+			rec.Query = ""
+			rec.Attrs = append(rec.Attrs, slog.String("env", "prod"))
+			return rec
+		}),
+		httplog.WithMinLevel(slog.LevelError),
+		httplog.Map(func(rec httplog.Record) httplog.Record {
+			rec.Status = http.StatusInternalServerError
+			return rec
+		}),
+		httplog.RecordFunc(func(rec httplog.Record) {
+			records = append(records, rec)
+		}),
+	)
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?token=secret", nil))
+
+	if len(records) != 1 {
+		t.Fatalf("expected filters to see the mapped record got %d records", len(records))
+	}
+	rec := records[0]
+	if rec.Query != "" || len(rec.Attrs) == 0 || rec.Attrs[len(rec.Attrs)-1].String() != "env=prod" {
+		t.Errorf("expected the transformed record got %+v", rec)
+	}
+}
+
+func TestSample(t *testing.T) {
+	var all, none int
+	sampleAll := httplog.Sample(1, func(httplog.Record) { all++ })
+	sampleNone := httplog.Sample(0, func(httplog.Record) { none++ })
+
+	before := httplog.ReadStats()
+	for i := 0; i < 100; i++ {
+		sampleAll(httplog.Record{})
+		sampleNone(httplog.Record{})
+	}
+
+	if all != 100 || none != 0 {
+		t.Errorf("expected 100 and 0 records got %d and %d", all, none)
+	}
+	if n := httplog.ReadStats().SinkSkipped - before.SinkSkipped; n != 100 {
+		t.Errorf("expected the skipped records to be counted got %d", n)
+	}
+}
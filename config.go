@@ -0,0 +1,226 @@
+package httplog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Config declares a logging policy. It is usually loaded from a file with
+// LoadConfig so the policy can change without recompiling the service.
+type Config struct {
+	// Format is the record encoding, "json" (the default), "text",
+	// "common" or "combined". See Format.
+	Format Format `json:"format" yaml:"format"`
+	// Level is the minimum level of logged records, see WithMinLevel.
+	Level *Level `json:"level" yaml:"level"`
+	// DurationFormat is one of "string" (the default), "ms", "ns", or "s".
+	DurationFormat string `json:"duration_format" yaml:"duration_format"`
+	// Sinks lists where records are written. When it is empty records are
+	// written to stdout, and those with a status of 500 or more also to
+	// stderr.
+	Sinks []SinkConfig `json:"sinks" yaml:"sinks"`
+	// SampleRate is the fraction of requests logged. Requests with a status
+	// of 500 or more are always logged. When it is unset every request is
+	// logged.
+	SampleRate *float64 `json:"sample_rate" yaml:"sample_rate"`
+	// PreflightSampleRate is passed to WithPreflightSampling when set.
+	PreflightSampleRate *float64 `json:"preflight_sample_rate" yaml:"preflight_sample_rate"`
+	// TraceSampling enables WithTraceSampling.
+	TraceSampling bool `json:"trace_sampling" yaml:"trace_sampling"`
+
+	Redact RedactConfig `json:"redact" yaml:"redact"`
+	Skip   SkipConfig   `json:"skip" yaml:"skip"`
+}
+
+// SinkConfig declares a destination for records.
+type SinkConfig struct {
+	// Type is "stdout", "stderr", or "file".
+	Type string `json:"type" yaml:"type"`
+	// Path is the file records are appended to when Type is "file".
+	Path string `json:"path" yaml:"path"`
+	// MinStatus limits the sink to records with at least this status.
+	MinStatus int `json:"min_status" yaml:"min_status"`
+	// Format overrides the Format of the Config for this sink, for example
+	// to keep writing a legacy "common" log file while other sinks move to
+	// JSON.
+	Format *Format `json:"format" yaml:"format"`
+}
+
+// RedactConfig declares how personal data is removed from records.
+type RedactConfig struct {
+	// ClientIP is "mask" (see WithMaskedIP), or "hash" (see WithHashedIP).
+	ClientIP string `json:"client_ip" yaml:"client_ip"`
+	// HashRotation is how often the key used to hash client IPs changes,
+	// for example "24h". The default is 24 hours.
+	HashRotation string `json:"hash_rotation" yaml:"hash_rotation"`
+	// Query removes query strings from records.
+	Query bool `json:"query" yaml:"query"`
+}
+
+// SkipConfig declares requests that are never logged.
+type SkipConfig struct {
+	// Paths are request paths to skip. A path ending in "*" skips every path
+	// with that prefix.
+	Paths []string `json:"paths" yaml:"paths"`
+	// Methods are request methods to skip, such as "HEAD".
+	Methods []string `json:"methods" yaml:"methods"`
+}
+
+// LoadConfig reads a Config from the YAML (.yaml or .yml) or JSON file at
+// path and returns a middleware wrapping handlers with the configured
+// policy. Files opened by file sinks stay open for the life of the process.
+func LoadConfig(path string) (func(http.Handler) http.Handler, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("httplog: failed to parse %s: %w", path, err)
+		}
+	default:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("httplog: failed to parse %s: %w", path, err)
+		}
+	}
+	options, err := cfg.Options()
+	if err != nil {
+		return nil, fmt.Errorf("httplog: invalid configuration in %s: %w", path, err)
+	}
+	return func(h http.Handler) http.Handler {
+		return Wrap(h, options...)
+	}, nil
+}
+
+// Options returns the Wrap options implementing the configuration.
+func (cfg Config) Options() ([]Option, error) {
+	var formatOptions []FormatOption
+	switch cfg.DurationFormat {
+	case "", "string":
+	case "ms":
+		formatOptions = append(formatOptions, WithDurationFormat(DurationMilliseconds))
+	case "ns":
+		formatOptions = append(formatOptions, WithDurationFormat(DurationNanoseconds))
+	case "s":
+		formatOptions = append(formatOptions, WithDurationFormat(DurationSeconds))
+	default:
+		return nil, fmt.Errorf("unknown duration format %q", cfg.DurationFormat)
+	}
+
+	var options []Option
+	switch cfg.Redact.ClientIP {
+	case "":
+	case "mask":
+		options = append(options, WithMaskedIP())
+	case "hash":
+		rotation := 24 * time.Hour
+		if cfg.Redact.HashRotation != "" {
+			d, err := time.ParseDuration(cfg.Redact.HashRotation)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid hash rotation %q", cfg.Redact.HashRotation)
+			}
+			rotation = d
+		}
+		options = append(options, WithHashedIP(rotation))
+	default:
+		return nil, fmt.Errorf("unknown client IP redaction %q", cfg.Redact.ClientIP)
+	}
+	if cfg.Redact.Query {
+		options = append(options, optionFunc(func(c *config) {
+			c.finalizers = append(c.finalizers, func(rec *Record) {
+				rec.Query = ""
+			})
+		}))
+	}
+	if len(cfg.Skip.Paths) > 0 || len(cfg.Skip.Methods) > 0 {
+		skip := cfg.Skip
+		options = append(options, withFilter(func(rec *Record) bool {
+			return !skip.matches(rec.Request)
+		}))
+	}
+	if rate := cfg.SampleRate; rate != nil {
+		options = append(options, withFilter(func(rec *Record) bool {
+			return rec.Status >= 500 || sampled(*rate)
+		}))
+	}
+	if cfg.PreflightSampleRate != nil {
+		options = append(options, WithPreflightSampling(*cfg.PreflightSampleRate))
+	}
+	if cfg.TraceSampling {
+		options = append(options, WithTraceSampling())
+	}
+	if cfg.Level != nil {
+		options = append(options, WithMinLevel(*cfg.Level))
+	}
+
+	if len(cfg.Sinks) == 0 {
+		if cfg.Format == FormatJSON {
+			options = append(options, JSONWriter(os.Stdout, os.Stderr, formatOptions...))
+		} else {
+			options = append(options, cfg.Format.Sink(os.Stdout, formatOptions...))
+		}
+	}
+	for _, sink := range cfg.Sinks {
+		var w io.Writer
+		switch sink.Type {
+		case "stdout":
+			w = os.Stdout
+		case "stderr":
+			w = os.Stderr
+		case "file":
+			if sink.Path == "" {
+				return nil, fmt.Errorf("file sink requires a path")
+			}
+			f, err := os.OpenFile(sink.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+			if err != nil {
+				return nil, err
+			}
+			w = f
+		default:
+			return nil, fmt.Errorf("unknown sink type %q", sink.Type)
+		}
+		format := cfg.Format
+		if sink.Format != nil {
+			format = *sink.Format
+		}
+		fn := format.Sink(w, formatOptions...)
+		if minStatus := sink.MinStatus; minStatus > 0 {
+			fn = Filter(func(rec Record) bool { return rec.Status >= minStatus }, fn)
+		}
+		options = append(options, fn)
+	}
+	return options, nil
+}
+
+func (skip SkipConfig) matches(req *http.Request) bool {
+	for _, method := range skip.Methods {
+		if strings.EqualFold(method, req.Method) {
+			return true
+		}
+	}
+	for _, path := range skip.Paths {
+		if prefix, ok := strings.CutSuffix(path, "*"); ok {
+			if strings.HasPrefix(req.URL.Path, prefix) {
+				return true
+			}
+		} else if path == req.URL.Path {
+			return true
+		}
+	}
+	return false
+}
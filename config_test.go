@@ -0,0 +1,196 @@
+package httplog_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "access.log")
+	errPath := filepath.Join(dir, "error.log")
+
+	for _, tt := range []struct {
+		Name, File, Content string
+	}{
+		{
+			Name: "yaml",
+			File: "httplog.yaml",
+			Content: `# access log policy
+duration_format: ms
+sinks:
+  - type: file
+    path: ` + logPath + `
+  - type: file
+    path: ` + errPath + `
+    min_status: 500
+redact:
+  client_ip: mask
+  query: true
+skip:
+  paths: ["/healthz", "/debug/*"]
+  methods: [HEAD]
+`,
+		},
+		{
+			Name: "json",
+			File: "httplog.json",
+			Content: `{
+  "duration_format": "ms",
+  "sinks": [
+    {"type": "file", "path": "` + logPath + `"},
+    {"type": "file", "path": "` + errPath + `", "min_status": 500}
+  ],
+  "redact": {"client_ip": "mask", "query": true},
+  "skip": {"paths": ["/healthz", "/debug/*"], "methods": ["HEAD"]}
+}`,
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			_ = os.Remove(logPath)
+			_ = os.Remove(errPath)
+			configPath := filepath.Join(dir, tt.File)
+			if err := os.WriteFile(configPath, []byte(tt.Content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			middleware, err := httplog.LoadConfig(configPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/fail" {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+			for _, req := range []*http.Request{
+				httptest.NewRequest(http.MethodGet, "/users?token=secret", nil),
+				httptest.NewRequest(http.MethodGet, "/fail", nil),
+				httptest.NewRequest(http.MethodGet, "/healthz", nil),
+				httptest.NewRequest(http.MethodGet, "/debug/pprof", nil),
+				httptest.NewRequest(http.MethodHead, "/users", nil),
+			} {
+				req.RemoteAddr = "198.51.100.77:5555"
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}
+
+			lines := readJSONLines(t, logPath)
+			if len(lines) != 2 {
+				t.Fatalf("expected 2 logged requests got %d", len(lines))
+			}
+			if _, ok := lines[0]["query"]; ok {
+				t.Errorf("expected the query to be redacted")
+			}
+			if lines[0]["client_ip"] != "198.51.100.0" {
+				t.Errorf("expected a masked client IP got %v", lines[0]["client_ip"])
+			}
+			if _, ok := lines[0]["duration"].(float64); !ok {
+				t.Errorf("expected a numeric duration got %v", lines[0]["duration"])
+			}
+			if errLines := readJSONLines(t, errPath); len(errLines) != 1 || errLines[0]["path"] != "/fail" {
+				t.Errorf("expected only the failed request in the error log got %v", errLines)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_invalid(t *testing.T) {
+	for _, content := range []string{
+		`{"format": "xml"}`,
+		`{"sinks": [{"type": "kafka"}]}`,
+		`{"unknown": true}`,
+		`{"redact": {"client_ip": "hash", "hash_rotation": "forever"}}`,
+	} {
+		path := filepath.Join(t.TempDir(), "httplog.json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := httplog.LoadConfig(path); err == nil {
+			t.Errorf("expected an error for %s", content)
+		}
+	}
+}
+
+func readJSONLines(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	var lines []map[string]any
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid JSON line %q: %s", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestLoadConfig_formatAndLevel(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "access.log")
+	configPath := filepath.Join(dir, "httplog.yml")
+	if err := os.WriteFile(configPath, []byte("format: text\nlevel: error\nsinks:\n  - type: file\n    path: "+logPath+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	middleware, err := httplog.LoadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); strings.Count(got, "\n") != 1 || !strings.Contains(got, `msg="request error"`) {
+		t.Errorf("expected a single text error record got %q", got)
+	}
+}
+
+func TestLoadConfig_sinkFormat(t *testing.T) {
+	dir := t.TempDir()
+	legacyPath := filepath.Join(dir, "access.log")
+	jsonPath := filepath.Join(dir, "access.json")
+	configPath := filepath.Join(dir, "httplog.yaml")
+	content := "sinks:\n  - type: file\n    path: " + legacyPath + "\n    format: combined\n  - type: file\n    path: " + jsonPath + "\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	middleware, err := httplog.LoadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	middleware(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	data, err := os.ReadFile(legacyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); !strings.Contains(got, `"GET / HTTP/1.1" 404`) {
+		t.Errorf("expected a combined log line got %q", got)
+	}
+	if lines := readJSONLines(t, jsonPath); len(lines) != 1 || lines[0]["status"] != float64(http.StatusNotFound) {
+		t.Errorf("expected a JSON line got %v", lines)
+	}
+}
@@ -0,0 +1,103 @@
+package httplog
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// connInfo is the connection state ConnContext stores in the connection's
+// base context.
+type connInfo struct {
+	opened   time.Time
+	requests atomic.Int64
+
+	// idleSince and idle, in Unix nanoseconds and nanoseconds, are kept
+	// by the ConnState hook of Server: when the connection last became idle
+	// and how long it then stayed idle before its current request.
+	idleSince, idle atomic.Int64
+}
+
+// ConnContext is an http.Server ConnContext function that tracks
+// connections for WithConnectionFields:
+//
+//	server := &http.Server{Handler: handler, ConnContext: httplog.ConnContext}
+//
+// Servers with their own ConnContext function can call it from theirs.
+func ConnContext(ctx context.Context, _ net.Conn) context.Context {
+	return context.WithValue(ctx, connInfoKey, &connInfo{opened: timeNow()})
+}
+
+// connTracker finds the connInfo of a connection for the ConnState hook of
+// Server. Connections are removed once they are closed or hijacked.
+type connTracker struct {
+	conns sync.Map // net.Conn to *connInfo
+}
+
+func (t *connTracker) connContext(ctx context.Context, conn net.Conn) context.Context {
+	info := &connInfo{opened: timeNow()}
+	t.conns.Store(conn, info)
+	return context.WithValue(ctx, connInfoKey, info)
+}
+
+func (t *connTracker) connState(conn net.Conn, state http.ConnState) {
+	v, ok := t.conns.Load(conn)
+	if !ok {
+		return
+	}
+	info := v.(*connInfo)
+	switch state {
+	case http.StateIdle:
+		info.idleSince.Store(timeNow().UnixNano())
+	case http.StateActive:
+		if since := info.idleSince.Swap(0); since != 0 {
+			info.idle.Store(timeNow().UnixNano() - since)
+		}
+	case http.StateHijacked, http.StateClosed:
+		t.conns.Delete(conn)
+	}
+}
+
+// WithConnectionFields adds local_addr, the server address the request
+// arrived on, and remote_port to each record. With ConnContext installed on
+// the server it also adds conn_requests, the number of requests the
+// connection has served including this one, conn_reused, and conn_age, so
+// clients that do not keep connections alive stand out. Servers set up with
+// Server also add conn_idle to requests on reused connections, the time the
+// connection was idle before the request.
+func WithConnectionFields() Option {
+	return optionFunc(func(c *config) {
+		c.inspectors = append(c.inspectors, func(req *http.Request) func(rec *Record) {
+			var requests int64
+			var age, idle time.Duration
+			conn, ok := req.Context().Value(connInfoKey).(*connInfo)
+			if ok {
+				requests = conn.requests.Add(1)
+				age = timeSince(conn.opened)
+				idle = time.Duration(conn.idle.Load())
+			}
+			return func(rec *Record) {
+				if addr, ok := req.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+					rec.Attrs = append(rec.Attrs, slog.String("local_addr", addr.String()))
+				}
+				if _, port, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+					rec.Attrs = append(rec.Attrs, slog.String("remote_port", port))
+				}
+				if ok {
+					rec.Attrs = append(rec.Attrs,
+						slog.Int64("conn_requests", requests),
+						slog.Bool("conn_reused", requests > 1),
+						slog.Duration("conn_age", age),
+					)
+					if requests > 1 && idle > 0 {
+						rec.Attrs = append(rec.Attrs, slog.Duration("conn_idle", idle))
+					}
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,59 @@
+package httplog_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithConnectionFields(t *testing.T) {
+	records := make(chan httplog.Record, 2)
+	server := httptest.NewUnstartedServer(httplog.Wrap(http.NotFoundHandler(), httplog.WithConnectionFields(), httplog.RecordFunc(func(rec httplog.Record) {
+		records <- rec
+	})))
+	server.Config.ConnContext = httplog.ConnContext
+	server.Start()
+	defer server.Close()
+
+	client := server.Client()
+	for i := 0; i < 2; i++ {
+		res, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+	}
+
+	for i, want := range []bool{false, true} {
+		fields := recordFields(<-records)
+		if fields["local_addr"] != server.Listener.Addr().String() {
+			t.Errorf("expected local_addr %s got %v", server.Listener.Addr(), fields["local_addr"])
+		}
+		if port, _ := fields["remote_port"].(string); port == "" {
+			t.Errorf("expected a remote_port got %v", fields)
+		}
+		if fields["conn_requests"] != int64(i+1) || fields["conn_reused"] != want {
+			t.Errorf("request %d: unexpected connection fields %v", i, fields)
+		}
+	}
+}
+
+func TestWithConnectionFields_withoutConnContext(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithConnectionFields(), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	fields := recordFields(rec)
+	if fields["remote_port"] != "1234" {
+		t.Errorf("expected the remote port of the request got %v", fields["remote_port"])
+	}
+	if _, ok := fields["conn_reused"]; ok {
+		t.Errorf("expected no connection reuse fields without ConnContext got %v", fields)
+	}
+}
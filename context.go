@@ -0,0 +1,180 @@
+package httplog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// RequestIDHeader is the request header used to propagate a request ID.
+// When it is absent Wrap generates a new ID.
+const RequestIDHeader = "X-Request-Id"
+
+type contextKey int
+
+const (
+	requestInfoKey contextKey = iota
+	connInfoKey
+)
+
+// requestInfo is the request scoped state Wrap stores in the request context.
+type requestInfo struct {
+	ID     string
+	Method string
+	Path   string
+	Route  string
+	// ClientIP is the client address, anonymized if configured.
+	ClientIP string
+	Trace    traceParent
+	Logger   *slog.Logger
+
+	errorStatus func(err error) int
+
+	// mu guards the fields below, which handlers and other middleware set
+	// through functions such as SetClientID.
+	mu       sync.Mutex
+	clientID string
+	err      error
+	attrs    []slog.Attr
+	costs    map[string]float64
+	// record is the last record passed to a Func, for recordFor.
+	record Record
+}
+
+// setRecord keeps rec, the record passed to a Func, for recordOf.
+func (info *requestInfo) setRecord(rec Record) {
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	info.record = rec
+}
+
+// recordOf returns the record Wrap passed to a Func together with req.
+func recordOf(req *http.Request) (Record, bool) {
+	info, ok := requestInfoFrom(req.Context())
+	if !ok {
+		return Record{}, false
+	}
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	if info.record.Request != req {
+		return Record{}, false
+	}
+	return info.record, true
+}
+
+// addAttrs adds fields to the record of the request.
+func (info *requestInfo) addAttrs(attrs ...slog.Attr) {
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	info.attrs = append(info.attrs, attrs...)
+}
+
+// setAttr adds a field to the record of the request, replacing an earlier
+// field with the same key.
+func (info *requestInfo) setAttr(attr slog.Attr) {
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	for i, a := range info.attrs {
+		if a.Key == attr.Key {
+			info.attrs[i] = attr
+			return
+		}
+	}
+	info.attrs = append(info.attrs, attr)
+}
+
+func (info *requestInfo) getAttrs() []slog.Attr {
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	return info.attrs
+}
+
+func newRequestInfo(req *http.Request) *requestInfo {
+	info := &requestInfo{
+		ID:       req.Header.Get(RequestIDHeader),
+		Method:   req.Method,
+		Path:     req.URL.Path,
+		ClientIP: clientIP(req),
+	}
+	if outer, ok := requestInfoFrom(req.Context()); ok {
+		// A nested Wrap keeps the ID generated by the outer one.
+		info.ID = outer.ID
+	}
+	if info.ID == "" {
+		info.ID = newRequestID()
+	}
+	if tp, ok := parseTraceParent(req.Header.Get(TraceParentHeader)); ok {
+		info.Trace = tp
+	}
+	return info
+}
+
+func newRequestID() string {
+	if newID := clock.newID.Load(); newID != nil {
+		return (*newID)()
+	}
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// route returns the normalized path of the request when WithNormalizedPath is
+// used and the raw path otherwise.
+func (info *requestInfo) route() string {
+	if info.Route != "" {
+		return info.Route
+	}
+	return info.Path
+}
+
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+func withRequestInfo(ctx context.Context, info *requestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey, info)
+}
+
+func requestInfoFrom(ctx context.Context) (*requestInfo, bool) {
+	info, ok := ctx.Value(requestInfoKey).(*requestInfo)
+	return info, ok
+}
+
+// RequestID returns the ID of the request handled by Wrap. It returns an
+// empty string when ctx does not belong to such a request.
+func RequestID(ctx context.Context) string {
+	info, ok := requestInfoFrom(ctx)
+	if !ok {
+		return ""
+	}
+	return info.ID
+}
+
+// TraceID returns the W3C trace ID propagated with the request handled by
+// Wrap. It returns an empty string when the request carried no valid
+// traceparent header.
+func TraceID(ctx context.Context) string {
+	info, ok := requestInfoFrom(ctx)
+	if !ok {
+		return ""
+	}
+	return info.Trace.TraceID
+}
+
+// FromContext returns the request scoped logger stored by WithContextLogger.
+// It returns slog.Default when ctx has no such logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	info, ok := requestInfoFrom(ctx)
+	if !ok || info.Logger == nil {
+		return slog.Default()
+	}
+	return info.Logger
+}
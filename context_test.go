@@ -0,0 +1,98 @@
+package httplog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithContextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httplog.FromContext(r.Context()).Info("hello")
+	}), httplog.WithContextLogger(logger), httplog.Func(func(*http.Request, time.Duration, int) {}))
+
+	r := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	r.RemoteAddr = "192.0.2.7:4321"
+	r.Header.Set(httplog.RequestIDHeader, "some-id")
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		"msg":        "hello",
+		"request_id": "some-id",
+		"route":      "/users/42",
+		"client_ip":  "192.0.2.7",
+	} {
+		if got := line[key]; got != want {
+			t.Errorf("expected %s to be %q got %v", key, want, got)
+		}
+	}
+}
+
+func TestFromContext_default(t *testing.T) {
+	var got *slog.Logger
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = httplog.FromContext(r.Context())
+	}), httplog.Func(func(*http.Request, time.Duration, int) {}))
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got != slog.Default() {
+		t.Errorf("expected the default logger")
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var first, second string
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if first == "" {
+			first = httplog.RequestID(r.Context())
+		} else {
+			second = httplog.RequestID(r.Context())
+		}
+	}), httplog.Func(func(*http.Request, time.Duration, int) {}))
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if first == "" || second == "" {
+		t.Fatalf("expected generated request IDs got %q and %q", first, second)
+	}
+	if first == second {
+		t.Errorf("expected distinct request IDs got %q twice", first)
+	}
+}
+
+func TestTraceID_invalid(t *testing.T) {
+	for _, header := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		var got string
+		logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = httplog.TraceID(r.Context())
+		}), httplog.Func(func(*http.Request, time.Duration, int) {}))
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(httplog.TraceParentHeader, header)
+		logMux.ServeHTTP(httptest.NewRecorder(), r)
+		if got != "" {
+			t.Errorf("expected no trace ID for %q got %q", header, got)
+		}
+	}
+}
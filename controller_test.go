@@ -0,0 +1,287 @@
+package httplog_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWrap_responseController(t *testing.T) {
+	records := make(chan httplog.Record, 1)
+	deadline := time.Now().Add(time.Minute).Truncate(time.Second)
+	server := httptest.NewServer(httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		if err := rc.SetReadDeadline(deadline); err != nil {
+			t.Errorf("SetReadDeadline: %s", err)
+		}
+		if err := rc.SetWriteDeadline(deadline); err != nil {
+			t.Errorf("SetWriteDeadline: %s", err)
+		}
+		if err := rc.EnableFullDuplex(); err != nil {
+			t.Errorf("EnableFullDuplex: %s", err)
+		}
+		w.WriteHeader(http.StatusOK)
+		if err := rc.Flush(); err != nil {
+			t.Errorf("Flush: %s", err)
+		}
+	}), httplog.WithDeadlineFields(), httplog.RecordFunc(func(rec httplog.Record) {
+		records <- rec
+	})))
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	_ = res.Body.Close()
+
+	rec := <-records
+	fields := map[string]any{}
+	for _, a := range rec.Attrs {
+		fields[a.Key] = a.Value.Any()
+	}
+	if got, ok := fields["read_deadline"].(time.Time); !ok || !got.Equal(deadline) {
+		t.Errorf("expected read_deadline %s got %v", deadline, fields["read_deadline"])
+	}
+	if got, ok := fields["write_deadline"].(time.Time); !ok || !got.Equal(deadline) {
+		t.Errorf("expected write_deadline %s got %v", deadline, fields["write_deadline"])
+	}
+	if fields["full_duplex"] != true {
+		t.Errorf("expected full_duplex to be true got %v", fields["full_duplex"])
+	}
+}
+
+func TestWrap_hijack(t *testing.T) {
+	server := httptest.NewServer(httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("expected the wrapped response writer to implement http.Hijacker")
+			return
+		}
+		conn, buf, err := hijacker.Hijack()
+		if err != nil {
+			t.Errorf("Hijack: %s", err)
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_, _ = buf.WriteString("HTTP/1.1 204 No Content\r\nConnection: close\r\n\r\n")
+		_ = buf.Flush()
+	}), httplog.Func(func(*http.Request, time.Duration, int) {})))
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("expected status %d got %d", http.StatusNoContent, res.StatusCode)
+	}
+}
+
+func TestWrap_responseControllerNotSupported(t *testing.T) {
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Now()); err == nil {
+			t.Error("expected an error from a response writer without deadline support")
+		}
+	}), httplog.Func(func(*http.Request, time.Duration, int) {}))
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+type unwrapWriter struct {
+	http.ResponseWriter
+}
+
+func (w unwrapWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func TestWrap_nested(t *testing.T) {
+	var outer, inner httplog.Record
+	var seen http.ResponseWriter
+	h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = w
+		w.WriteHeader(http.StatusTeapot)
+	}), httplog.RecordFunc(func(rec httplog.Record) { inner = rec }))
+	middleware := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(unwrapWriter{w}, r)
+	})
+	httplog.Wrap(middleware, httplog.RecordFunc(func(rec httplog.Record) {
+		outer = rec
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if outer.Status != http.StatusTeapot || inner.Status != http.StatusTeapot {
+		t.Errorf("expected both records to have status 418 got %d and %d", outer.Status, inner.Status)
+	}
+	if outer.RequestID == "" || outer.RequestID != inner.RequestID {
+		t.Errorf("expected a shared request id got %q and %q", outer.RequestID, inner.RequestID)
+	}
+	if _, ok := seen.(unwrapWriter); !ok {
+		t.Errorf("expected the handler to get the middleware writer got %T", seen)
+	}
+}
+
+func TestWrap_nestedOuterWritesStatus(t *testing.T) {
+	var outer httplog.Record
+	h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), httplog.RecordFunc(func(httplog.Record) {}))
+	middleware := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+		w.WriteHeader(http.StatusTeapot)
+	})
+	res := httptest.NewRecorder()
+	httplog.Wrap(middleware, httplog.RecordFunc(func(rec httplog.Record) {
+		outer = rec
+	})).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if res.Code != http.StatusTeapot {
+		t.Fatalf("expected the client to get 418 got %d", res.Code)
+	}
+	if outer.Status != http.StatusTeapot {
+		t.Errorf("expected the outer record to have status 418 got %d", outer.Status)
+	}
+	for _, a := range outer.Attrs {
+		if a.Key == "warning" {
+			t.Errorf("expected no warning got %s", a.Value)
+		}
+	}
+}
+
+func TestWithResponseTiming(t *testing.T) {
+	var rec httplog.Record
+	h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+		_, _ = w.Write([]byte("a"))
+		time.Sleep(time.Millisecond)
+		_ = http.NewResponseController(w).Flush()
+		time.Sleep(time.Millisecond)
+	}), httplog.WithResponseTiming(), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	fields := recordFields(rec)
+	ttfb, _ := fields["time_to_first_byte"].(time.Duration)
+	ttlb, _ := fields["time_to_last_byte"].(time.Duration)
+	if ttfb < time.Millisecond || ttlb < ttfb+time.Millisecond || rec.Duration < ttlb+time.Millisecond {
+		t.Errorf("unexpected timings ttfb=%s ttlb=%s duration=%s", ttfb, ttlb, rec.Duration)
+	}
+	if _, ok := fields["write_duration"].(time.Duration); !ok {
+		t.Errorf("expected write_duration got %v", fields)
+	}
+}
+
+func TestWrap_flushOnly(t *testing.T) {
+	var rec httplog.Record
+	hook := &recordingHook{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = http.NewResponseController(w).Flush()
+		cancel() // the client goes away while the stream is open
+	}), httplog.WithHook(hook), httplog.WithResponseTiming(), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx))
+
+	if rec.Status != http.StatusOK || res.Code != http.StatusOK {
+		t.Errorf("expected the flushed response to be logged as 200 got %d", rec.Status)
+	}
+	if _, ok := recordFields(rec)["time_to_first_byte"]; !ok {
+		t.Errorf("expected first byte timing for a flush got %v", rec.Attrs)
+	}
+	if len(hook.calls) < 2 || hook.calls[1] != "write" {
+		t.Errorf("expected the response write hook to run on flush got %v", hook.calls)
+	}
+}
+
+func TestWithResponseTiming_noBody(t *testing.T) {
+	var rec httplog.Record
+	h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), httplog.WithResponseTiming(), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if _, ok := recordFields(rec)["time_to_first_byte"]; ok {
+		t.Errorf("expected no timings without writes got %v", rec.Attrs)
+	}
+}
+
+func TestWrap_interimResponses(t *testing.T) {
+	records := make(chan httplog.Record, 1)
+	server := httptest.NewServer(httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+		_, _ = w.Write([]byte("hello"))
+	}), httplog.WithInterimFields(), httplog.RecordFunc(func(rec httplog.Record) {
+		records <- rec
+	})))
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	_ = res.Body.Close()
+
+	rec := <-records
+	if rec.Status != http.StatusOK || res.StatusCode != http.StatusOK {
+		t.Errorf("expected the final status 200 to be logged got %d", rec.Status)
+	}
+	fields := recordFields(rec)
+	if fields["early_hints"] != true {
+		t.Errorf("expected early_hints got %v", fields)
+	}
+	if statuses, _ := fields["interim_statuses"].([]int); len(statuses) != 1 || statuses[0] != http.StatusEarlyHints {
+		t.Errorf("expected interim_statuses [103] got %v", fields["interim_statuses"])
+	}
+}
+
+func TestWrap_superfluousWriteHeader(t *testing.T) {
+	var rec httplog.Record
+	h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(http.StatusInternalServerError)
+	}), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Status != http.StatusCreated || w.Code != http.StatusCreated {
+		t.Errorf("expected the first status to be logged got %d", rec.Status)
+	}
+	if got := recordFields(rec)["warning"]; got != "superfluous_write_header" {
+		t.Errorf("expected a superfluous_write_header warning got %v", got)
+	}
+}
+
+func TestWrap_writeAfterHijack(t *testing.T) {
+	records := make(chan httplog.Record, 1)
+	server := httptest.NewServer(httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 204 No Content\r\nConnection: close\r\n\r\n")
+		_ = rw.Flush()
+		_, _ = w.Write([]byte("oops"))
+	}), httplog.RecordFunc(func(rec httplog.Record) {
+		records <- rec
+	})))
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	rec := <-records
+	if got := recordFields(rec)["warning"]; got != "write_after_hijack" {
+		t.Errorf("expected a write_after_hijack warning got %v", got)
+	}
+}
@@ -0,0 +1,124 @@
+package httplog
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+)
+
+// AddCost adds amount to the named cost of the request, such as the number
+// of database queries or compute units it used. The costs are logged in a
+// cost group, for example cost.db_queries=3, for usage based billing from
+// the access logs. AddCost does nothing when ctx does not belong to a
+// request handled by Wrap.
+func AddCost(ctx context.Context, name string, amount float64) {
+	info, ok := requestInfoFrom(ctx)
+	if !ok {
+		return
+	}
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	if info.costs == nil {
+		info.costs = make(map[string]float64)
+	}
+	info.costs[name] += amount
+}
+
+func (info *requestInfo) getCosts() map[string]float64 {
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	costs := make(map[string]float64, len(info.costs))
+	for name, amount := range info.costs {
+		costs[name] = amount
+	}
+	return costs
+}
+
+func costAttr(costs map[string]float64) slog.Attr {
+	names := make([]string, 0, len(costs))
+	for name := range costs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	attrs := make([]slog.Attr, len(names))
+	for i, name := range names {
+		attrs[i] = slog.Float64(name, costs[name])
+	}
+	return slog.Attr{Key: "cost", Value: slog.GroupValue(attrs...)}
+}
+
+// CostTotals sums the costs reported with AddCost per customer: the tenant
+// set by WithTenant when present, then the client_id set by SetClientID or
+// WithClientIDHeader, and the client IP otherwise. It is created with
+// NewCostTotals. A CostTotals is an Option so it can be passed to Wrap
+// directly. Costs that are not numbers are ignored.
+type CostTotals struct {
+	mu     sync.Mutex
+	totals map[string]map[string]float64
+}
+
+// NewCostTotals returns an empty CostTotals.
+func NewCostTotals() *CostTotals {
+	return &CostTotals{totals: make(map[string]map[string]float64)}
+}
+
+// Log adds the costs of rec to the totals of its customer.
+func (t *CostTotals) Log(rec Record) {
+	var costs []slog.Attr
+	customer, tenant := rec.ClientIP, false
+	for _, a := range rec.Attrs {
+		switch a.Key {
+		case "cost":
+			if a.Value.Kind() == slog.KindGroup {
+				costs = a.Value.Group()
+			}
+		case "tenant":
+			customer, tenant = a.Value.String(), true
+		case "client_id":
+			if !tenant {
+				customer = a.Value.String()
+			}
+		}
+	}
+	if len(costs) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	totals, ok := t.totals[customer]
+	if !ok {
+		totals = make(map[string]float64)
+		t.totals[customer] = totals
+	}
+	for _, a := range costs {
+		if amount, ok := costAmount(a.Value); ok {
+			totals[a.Key] += amount
+		}
+	}
+}
+
+// costAmount returns the amount of a cost attr when it is a number.
+func costAmount(v slog.Value) (float64, bool) {
+	switch v.Kind() {
+	case slog.KindFloat64:
+		return v.Float64(), true
+	case slog.KindInt64:
+		return float64(v.Int64()), true
+	case slog.KindUint64:
+		return float64(v.Uint64()), true
+	}
+	return 0, false
+}
+
+func (t *CostTotals) apply(c *config) { RecordFunc(t.Log).apply(c) }
+
+// Totals returns the costs per customer and name since the last call and
+// starts summing again, so it can be called from a periodic billing export.
+func (t *CostTotals) Totals() map[string]map[string]float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	totals := t.totals
+	t.totals = make(map[string]map[string]float64)
+	return totals
+}
@@ -0,0 +1,73 @@
+package httplog_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestAddCost(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httplog.AddCost(r.Context(), "db_queries", 2)
+		httplog.AddCost(r.Context(), "compute_units", 0.5)
+		httplog.AddCost(r.Context(), "db_queries", 1)
+	}), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	cost, ok := groupFields(rec, "cost")
+	if !ok || cost["db_queries"] != float64(3) || cost["compute_units"] != 0.5 {
+		t.Errorf("unexpected cost group %v", cost)
+	}
+}
+
+func TestAddCost_withoutRequest(t *testing.T) {
+	httplog.AddCost(context.Background(), "db_queries", 1)
+}
+
+func TestCostTotals(t *testing.T) {
+	totals := httplog.NewCostTotals()
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/free" {
+			httplog.AddCost(r.Context(), "compute_units", 1.5)
+		}
+	}), httplog.WithTenant(httplog.TenantFromHeader("X-Tenant")), totals, httplog.RecordFunc(func(httplog.Record) {}))
+
+	for _, tt := range []struct{ path, tenant string }{
+		{"/a", "acme"},
+		{"/b", "acme"},
+		{"/free", "acme"},
+		{"/a", "globex"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		r.Header.Set("X-Tenant", tt.tenant)
+		logMux.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	got := totals.Totals()
+	if got["acme"]["compute_units"] != 3 || got["globex"]["compute_units"] != 1.5 || len(got) != 2 {
+		t.Errorf("unexpected totals %v", got)
+	}
+	if again := totals.Totals(); len(again) != 0 {
+		t.Errorf("expected the totals to be reset got %v", again)
+	}
+}
+
+func TestCostTotals_otherKinds(t *testing.T) {
+	totals := httplog.NewCostTotals()
+	totals.Log(httplog.Record{ClientIP: "192.0.2.1", Attrs: []slog.Attr{slog.String("cost", "high")}})
+	totals.Log(httplog.Record{ClientIP: "192.0.2.1", Attrs: []slog.Attr{slog.Group("cost",
+		slog.Int("db_queries", 2),
+		slog.Uint64("rows", 10),
+		slog.String("tier", "gold"),
+	)}})
+
+	got := totals.Totals()["192.0.2.1"]
+	if got["db_queries"] != 2 || got["rows"] != 10 || len(got) != 2 {
+		t.Errorf("expected only numeric costs to be summed got %v", got)
+	}
+}
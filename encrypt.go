@@ -0,0 +1,68 @@
+package httplog
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+)
+
+// WithEncryptedFields encrypts the values of the named fields with aead
+// before records are emitted, so logs at rest are pseudonymous while an
+// operator holding the key can still recover the values with DecryptField.
+// Fields are the keys of top level attributes or one of "client_ip",
+// "user_agent", "query", or "path". Encrypted values are only ever replaced
+// as strings; non string attributes are formatted first.
+//
+// A typical aead is AES-GCM created with aes.NewCipher and cipher.NewGCM.
+func WithEncryptedFields(aead cipher.AEAD, fields ...string) Option {
+	return optionFunc(func(c *config) {
+		c.finalizers = append(c.finalizers, func(rec *Record) {
+			for _, field := range fields {
+				switch field {
+				case "client_ip":
+					rec.ClientIP = encryptField(aead, field, rec.ClientIP)
+				case "user_agent":
+					rec.UserAgent = encryptField(aead, field, rec.UserAgent)
+				case "query":
+					rec.Query = encryptField(aead, field, rec.Query)
+				case "path":
+					rec.Path = encryptField(aead, field, rec.Path)
+				default:
+					for i, a := range rec.Attrs {
+						if a.Key == field && a.Value.Kind() != slog.KindGroup {
+							rec.Attrs[i].Value = slog.StringValue(encryptField(aead, field, a.Value.String()))
+						}
+					}
+				}
+			}
+		})
+	})
+}
+
+func encryptField(aead cipher.AEAD, field, value string) string {
+	if value == "" {
+		return ""
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+	_, _ = rand.Read(nonce)
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(value), []byte(field)))
+}
+
+// DecryptField reverses the encryption applied by WithEncryptedFields to the
+// value logged for field.
+func DecryptField(aead cipher.AEAD, field, value string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("httplog: encrypted value too short")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(field))
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,45 @@
+package httplog_test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithEncryptedFields(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithServiceInfo("billing", "", ""), httplog.WithEncryptedFields(aead, "client_ip", "service"), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "198.51.100.77:5555"
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	if rec.ClientIP == "198.51.100.77" {
+		t.Fatal("expected the client IP to be encrypted")
+	}
+	if got, err := httplog.DecryptField(aead, "client_ip", rec.ClientIP); err != nil || got != "198.51.100.77" {
+		t.Errorf("expected to decrypt the client IP got %q, %v", got, err)
+	}
+	service, _ := recordFields(rec)["service"].(string)
+	if got, err := httplog.DecryptField(aead, "service", service); err != nil || got != "billing" {
+		t.Errorf("expected to decrypt the service got %q, %v", got, err)
+	}
+	if _, err := httplog.DecryptField(aead, "service", rec.ClientIP); err == nil {
+		t.Error("expected an error decrypting a value logged for another field")
+	}
+}
@@ -0,0 +1,74 @@
+package httplog
+
+import (
+	"log/slog"
+	"os"
+)
+
+// WithServiceInfo adds service, version, and environment fields to every
+// record so logs from many services can be aggregated and correlated with
+// releases. Empty values are omitted.
+func WithServiceInfo(name, version, env string) Option {
+	var attrs []slog.Attr
+	for _, a := range []slog.Attr{
+		slog.String("service", name),
+		slog.String("version", version),
+		slog.String("environment", env),
+	} {
+		if a.Value.String() != "" {
+			attrs = append(attrs, a)
+		}
+	}
+	return withStaticAttrs(attrs)
+}
+
+// WithHostInfo adds the hostname and pid of the process, and instance_id
+// unless it is empty, to every record so logs from replicas of a horizontally
+// scaled service can be told apart.
+func WithHostInfo(instanceID string) Option {
+	var attrs []slog.Attr
+	if hostname, err := os.Hostname(); err == nil {
+		attrs = append(attrs, slog.String("hostname", hostname))
+	}
+	attrs = append(attrs, slog.Int("pid", os.Getpid()))
+	if instanceID != "" {
+		attrs = append(attrs, slog.String("instance_id", instanceID))
+	}
+	return withStaticAttrs(attrs)
+}
+
+// WithKubernetesInfo adds a "k8s" group with pod_name, namespace, and
+// node_name read from the POD_NAME, NAMESPACE (or POD_NAMESPACE), and
+// NODE_NAME environment variables, conventionally set with the downward
+// API. Unset variables are omitted.
+func WithKubernetesInfo() Option {
+	var attrs []slog.Attr
+	namespace := os.Getenv("NAMESPACE")
+	if namespace == "" {
+		namespace = os.Getenv("POD_NAMESPACE")
+	}
+	for _, a := range []slog.Attr{
+		slog.String("pod_name", os.Getenv("POD_NAME")),
+		slog.String("namespace", namespace),
+		slog.String("node_name", os.Getenv("NODE_NAME")),
+	} {
+		if a.Value.String() != "" {
+			attrs = append(attrs, a)
+		}
+	}
+	if len(attrs) == 0 {
+		return withStaticAttrs(nil)
+	}
+	return withStaticAttrs([]slog.Attr{{Key: "k8s", Value: slog.GroupValue(attrs...)}})
+}
+
+func withStaticAttrs(attrs []slog.Attr) Option {
+	return optionFunc(func(c *config) {
+		if len(attrs) == 0 {
+			return
+		}
+		c.extractors = append(c.extractors, func(rec *Record) {
+			rec.Attrs = append(rec.Attrs, attrs...)
+		})
+	})
+}
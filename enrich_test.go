@@ -0,0 +1,75 @@
+package httplog_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithServiceInfo(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithServiceInfo("billing", "v1.4.2", ""), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	fields := recordFields(rec)
+	if fields["service"] != "billing" || fields["version"] != "v1.4.2" {
+		t.Errorf("expected service and version fields got %v", fields)
+	}
+	if _, ok := fields["environment"]; ok {
+		t.Errorf("expected empty environment to be omitted")
+	}
+}
+
+func TestWithHostInfo(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithHostInfo("i-0abc"), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	hostname, _ := os.Hostname()
+	fields := recordFields(rec)
+	if fields["hostname"] != hostname {
+		t.Errorf("expected hostname %q got %v", hostname, fields["hostname"])
+	}
+	if fields["pid"] != int64(os.Getpid()) {
+		t.Errorf("expected pid %d got %v", os.Getpid(), fields["pid"])
+	}
+	if fields["instance_id"] != "i-0abc" {
+		t.Errorf("expected instance_id got %v", fields["instance_id"])
+	}
+}
+
+func TestWithKubernetesInfo(t *testing.T) {
+	t.Setenv("POD_NAME", "api-7d9f")
+	t.Setenv("NAMESPACE", "")
+	t.Setenv("POD_NAMESPACE", "payments")
+	t.Setenv("NODE_NAME", "")
+
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithKubernetesInfo(), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	group, ok := recordFields(rec)["k8s"].([]slog.Attr)
+	if !ok {
+		t.Fatalf("expected a k8s group got %v", rec.Attrs)
+	}
+	got := map[string]string{}
+	for _, a := range group {
+		got[a.Key] = a.Value.String()
+	}
+	if len(got) != 2 || got["pod_name"] != "api-7d9f" || got["namespace"] != "payments" {
+		t.Errorf("unexpected k8s fields %v", got)
+	}
+}
@@ -0,0 +1,124 @@
+package httplog
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+// serverErrorPatterns are the prefixes of the messages net/http and its
+// HTTP/2 server log, with the event they are reported as. The remote
+// address follows a prefix ending in "from ".
+var serverErrorPatterns = []struct {
+	prefix, event string
+	level         slog.Level
+}{
+	{"http: TLS handshake error from ", "tls_handshake_error", slog.LevelWarn},
+	{"http: panic serving ", "panic", slog.LevelError},
+	{"http: Accept error: ", "accept_error", slog.LevelError},
+	{"http: superfluous response.WriteHeader call from ", "superfluous_write_header", slog.LevelWarn},
+	{"http: response.WriteHeader on hijacked connection from ", "write_header_after_hijack", slog.LevelWarn},
+	{"http: response.Write on hijacked connection from ", "write_after_hijack", slog.LevelWarn},
+	{"http: URL query contains semicolon", "query_semicolon", slog.LevelWarn},
+	{"http2: server: error reading preface from client ", "http2_preface_error", slog.LevelWarn},
+}
+
+// ErrorLog returns a logger for http.Server.ErrorLog that passes the errors
+// net/http logs itself to fn as records of TypeServer, so they end up in the
+// same sink as the requests. The attrs of a record are event, such as
+// tls_handshake_error or panic, remote_addr when the line names the
+// client, error and error_log, the full line. Unrecognized lines have the
+// event server_error. Records are at level warn, or error for panics and
+// accept errors. The records have no Request so sinks that are a Func do not
+// receive them; use a RecordFunc.
+func ErrorLog(fn RecordFunc) *log.Logger {
+	return log.New(errorLogWriter(fn), "", 0)
+}
+
+type errorLogWriter RecordFunc
+
+func (w errorLogWriter) Write(p []byte) (int, error) {
+	w(parseServerError(string(bytes.TrimRight(p, "\n"))))
+	return len(p), nil
+}
+
+func parseServerError(line string) Record {
+	rec := Record{Type: TypeServer, Time: timeNow(), minLevel: slog.LevelWarn}
+	event, rest := "server_error", ""
+	for _, p := range serverErrorPatterns {
+		if r, ok := strings.CutPrefix(line, p.prefix); ok {
+			event, rest, rec.minLevel = p.event, r, p.level
+			break
+		}
+	}
+	rec.Attrs = append(rec.Attrs, slog.String("event", event))
+	switch event {
+	case "tls_handshake_error", "panic", "http2_preface_error":
+		// "<addr>: <error>"; the panic message is followed by a stack
+		// which stays in error_log.
+		addr, err, ok := strings.Cut(rest, ": ")
+		if !ok {
+			break
+		}
+		if event == "panic" {
+			err, _, _ = strings.Cut(err, "\n")
+		}
+		rec.Attrs = append(rec.Attrs, slog.String("remote_addr", addr), slog.String("error", err))
+	case "accept_error":
+		err, _, _ := strings.Cut(rest, "; retrying in ")
+		rec.Attrs = append(rec.Attrs, slog.String("error", err))
+	case "superfluous_write_header", "write_header_after_hijack", "write_after_hijack":
+		// "<remote addr or caller> (<file>:<line>)"
+		caller, _, _ := strings.Cut(rest, " ")
+		rec.Attrs = append(rec.Attrs, slog.String("caller", caller))
+	}
+	rec.Attrs = append(rec.Attrs, slog.String("error_log", line))
+	return rec
+}
+
+// serverErrorAddr returns the remote_addr of a record of ErrorLog split into
+// host and port.
+func serverErrorAddr(rec Record) (addr, host, port string) {
+	for _, a := range rec.Attrs {
+		if a.Key == "remote_addr" {
+			addr = a.Value.String()
+			break
+		}
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", "", ""
+	}
+	return addr, host, port
+}
+
+// replaceServerErrorAddr replaces addr in the remote_addr and error_log of a
+// record of ErrorLog with host and port, or "-" when host is empty, and
+// returns the new address.
+func replaceServerErrorAddr(rec *Record, addr, host, port string) string {
+	replacement := "-"
+	if host != "" {
+		replacement = net.JoinHostPort(host, port)
+	}
+	for i, a := range rec.Attrs {
+		switch a.Key {
+		case "remote_addr":
+			rec.Attrs[i].Value = slog.StringValue(replacement)
+		case "error_log":
+			rec.Attrs[i].Value = slog.StringValue(strings.ReplaceAll(a.Value.String(), addr, replacement))
+		}
+	}
+	return replacement
+}
+
+// anonymizeServerError replaces the client address in a line net/http logs
+// with its anonymized host.
+func anonymizeServerError(line string, anonymize func(ip string) string) string {
+	addr, host, port := serverErrorAddr(parseServerError(strings.TrimRight(line, "\n")))
+	if host == "" {
+		return line
+	}
+	return strings.ReplaceAll(line, addr, net.JoinHostPort(anonymize(host), port))
+}
@@ -0,0 +1,96 @@
+package httplog_test
+
+import (
+	"bufio"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestErrorLog(t *testing.T) {
+	for _, tt := range []struct {
+		line   string
+		level  slog.Level
+		fields map[string]any
+	}{
+		{
+			line:  "http: TLS handshake error from 192.0.2.1:1234: EOF",
+			level: slog.LevelWarn,
+			fields: map[string]any{
+				"event":       "tls_handshake_error",
+				"remote_addr": "192.0.2.1:1234",
+				"error":       "EOF",
+			},
+		},
+		{
+			line:  "http: panic serving 192.0.2.1:1234: boom\ngoroutine 1 [running]:\nmain.main()",
+			level: slog.LevelError,
+			fields: map[string]any{
+				"event":       "panic",
+				"remote_addr": "192.0.2.1:1234",
+				"error":       "boom",
+			},
+		},
+		{
+			line:   "http: Accept error: accept tcp [::]:80: too many open files; retrying in 5ms",
+			level:  slog.LevelError,
+			fields: map[string]any{"event": "accept_error", "error": "accept tcp [::]:80: too many open files"},
+		},
+		{
+			line:   "http: superfluous response.WriteHeader call from main.handler (main.go:12)",
+			level:  slog.LevelWarn,
+			fields: map[string]any{"event": "superfluous_write_header", "caller": "main.handler"},
+		},
+		{
+			line:   "something else",
+			level:  slog.LevelWarn,
+			fields: map[string]any{"event": "server_error", "error_log": "something else"},
+		},
+	} {
+		var rec httplog.Record
+		logger := httplog.ErrorLog(func(r httplog.Record) { rec = r })
+		logger.Print(tt.line)
+
+		if rec.Type != httplog.TypeServer || rec.Level() != tt.level {
+			t.Errorf("%q: expected a %s record at %s got %s at %s", tt.line, httplog.TypeServer, tt.level, rec.Type, rec.Level())
+		}
+		fields := recordFields(rec)
+		if fields["error_log"] != tt.line {
+			t.Errorf("expected the line %q got %v", tt.line, fields["error_log"])
+		}
+		for key, want := range tt.fields {
+			if got := fields[key]; got != want {
+				t.Errorf("%q: expected %s to be %v got %v", tt.line, key, want, got)
+			}
+		}
+	}
+}
+
+func TestServer_errorLog(t *testing.T) {
+	records := make(chan httplog.Record, 1)
+	srv := httplog.Server(&http.Server{Handler: http.NotFoundHandler()}, httplog.RecordFunc(func(rec httplog.Record) {
+		records <- rec
+	}))
+	server := httptest.NewUnstartedServer(srv.Handler)
+	server.Config = srv
+	server.StartTLS()
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A plain HTTP request to the TLS server fails the handshake.
+	_, _ = conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	_, _ = bufio.NewReader(conn).ReadString('\n')
+	_ = conn.Close()
+
+	rec := <-records
+	if fields := recordFields(rec); rec.Type != httplog.TypeServer || fields["event"] != "tls_handshake_error" {
+		t.Errorf("expected a tls_handshake_error record got %s %v", rec.Type, fields)
+	}
+}
@@ -0,0 +1,137 @@
+package httplog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+)
+
+// SetError records an error the handler ran into, for example before it
+// responds with a 500. It is logged as error, its message, and error_chain,
+// the errors found by following Unwrap, including both forms used by
+// errors.Join and fmt.Errorf with several %w verbs, as a list of ErrorInfo
+// so errors can be aggregated by type. A later call replaces the error.
+// SetError does nothing when ctx does not belong to a request handled by
+// Wrap.
+func SetError(ctx context.Context, err error) {
+	info, ok := requestInfoFrom(ctx)
+	if !ok {
+		return
+	}
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	info.err = err
+}
+
+func (info *requestInfo) getError() error {
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	return info.err
+}
+
+// ErrorInfo is an error of the error_chain logged for SetError.
+type ErrorInfo struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// maxErrorChain bounds the error chain in case of a cycle.
+const maxErrorChain = 32
+
+func errorAttrs(err error) []slog.Attr {
+	return []slog.Attr{
+		slog.String("error", err.Error()),
+		slog.Any("error_chain", appendErrorChain(nil, err)),
+	}
+}
+
+// appendErrorChain appends err and the errors it wraps depth first.
+func appendErrorChain(chain []ErrorInfo, err error) []ErrorInfo {
+	if err == nil || len(chain) >= maxErrorChain {
+		return chain
+	}
+	chain = append(chain, ErrorInfo{Type: fmt.Sprintf("%T", err), Message: err.Error()})
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		chain = appendErrorChain(chain, e.Unwrap())
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			chain = appendErrorChain(chain, inner)
+		}
+	}
+	return chain
+}
+
+// WrapErr is like Wrap for handlers that return an error instead of writing
+// error responses themselves. A returned error is recorded as if passed to
+// SetError and, unless the handler already wrote a status, answered with the
+// status WithErrorStatus maps it to, ErrorStatus by default, and its status
+// text as the body. A status that is not a final status, a three digit code
+// of at least 200, is answered with 500 Internal Server Error.
+func WrapErr(fn func(w http.ResponseWriter, r *http.Request) error, options ...Option) http.HandlerFunc {
+	return Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := fn(w, r)
+		if err == nil {
+			return
+		}
+		SetError(r.Context(), err)
+		record := findLogRecord(w)
+		if record == nil || record.status != 0 {
+			return
+		}
+		status := ErrorStatus(err)
+		if info, ok := requestInfoFrom(r.Context()); ok && info.errorStatus != nil {
+			status = info.errorStatus(err)
+		}
+		if status < 200 || status > 999 {
+			// WriteHeader panics for codes above 999 and 1xx codes are
+			// interim, leaving an implicit 200 for the failed request.
+			status = http.StatusInternalServerError
+		}
+		if status == StatusClientClosedRequest {
+			// The client is gone so there is no one to respond to.
+			record.status = status
+			return
+		}
+		http.Error(w, http.StatusText(status), status)
+	}), options...)
+}
+
+// WithErrorStatus sets the function WrapErr uses to pick the status of the
+// response for a returned error.
+func WithErrorStatus(status func(err error) int) Option {
+	return optionFunc(func(c *config) {
+		c.errorStatus = status
+	})
+}
+
+// ErrorStatus returns the status WrapErr responds with by default. It uses the
+// StatusCode method of the first error in the chain that has one and
+// otherwise maps well known errors:
+//
+//	fs.ErrNotExist            404 Not Found
+//	fs.ErrPermission          403 Forbidden
+//	context.DeadlineExceeded  503 Service Unavailable
+//	context.Canceled          499 (StatusClientClosedRequest)
+//
+// Any other error is a 500 Internal Server Error.
+func ErrorStatus(err error) int {
+	var coder interface{ StatusCode() int }
+	switch {
+	case errors.As(err, &coder):
+		return coder.StatusCode()
+	case errors.Is(err, fs.ErrNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return http.StatusForbidden
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
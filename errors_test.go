@@ -0,0 +1,102 @@
+package httplog_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestSetError(t *testing.T) {
+	var rec httplog.Record
+	h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pathErr := &fs.PathError{Op: "open", Path: "config.yaml", Err: fs.ErrNotExist}
+		err := fmt.Errorf("loading config: %w", errors.Join(pathErr, context.DeadlineExceeded))
+		httplog.SetError(r.Context(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	fields := recordFields(rec)
+	if fields["error"] != "loading config: open config.yaml: file does not exist\\x0Acontext deadline exceeded" {
+		t.Errorf("unexpected error %q", fields["error"])
+	}
+	chain, _ := fields["error_chain"].([]httplog.ErrorInfo)
+	var types []string
+	for _, e := range chain {
+		types = append(types, e.Type)
+	}
+	want := []string{"*fmt.wrapError", "*errors.joinError", "*fs.PathError", "*errors.errorString", "context.deadlineExceededError"}
+	if fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Errorf("expected chain %v got %v", want, types)
+	}
+}
+
+func TestSetError_withoutRequest(t *testing.T) {
+	httplog.SetError(context.Background(), errors.New("ignored"))
+}
+
+type statusError int
+
+func (e statusError) Error() string   { return http.StatusText(int(e)) }
+func (e statusError) StatusCode() int { return int(e) }
+
+func TestWrapErr(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		err     error
+		options []httplog.Option
+		status  int
+	}{
+		{name: "nil", status: http.StatusOK},
+		{name: "default", err: errors.New("boom"), status: http.StatusInternalServerError},
+		{name: "status code", err: fmt.Errorf("lookup: %w", statusError(http.StatusConflict)), status: http.StatusConflict},
+		{name: "not exist", err: &fs.PathError{Op: "open", Path: "x", Err: fs.ErrNotExist}, status: http.StatusNotFound},
+		{name: "mapper", err: errors.New("boom"), options: []httplog.Option{httplog.WithErrorStatus(func(error) int {
+			return http.StatusBadGateway
+		})}, status: http.StatusBadGateway},
+		{name: "invalid status code", err: statusError(0), status: http.StatusInternalServerError},
+		{name: "interim status code", err: statusError(http.StatusEarlyHints), status: http.StatusInternalServerError},
+		{name: "invalid mapper status", err: errors.New("boom"), options: []httplog.Option{httplog.WithErrorStatus(func(error) int {
+			return 1000
+		})}, status: http.StatusInternalServerError},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var rec httplog.Record
+			h := httplog.WrapErr(func(w http.ResponseWriter, r *http.Request) error {
+				return tt.err
+			}, append(tt.options, httplog.RecordFunc(func(r httplog.Record) { rec = r }))...)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if w.Code != tt.status || rec.Status != tt.status {
+				t.Errorf("expected status %d got response %d and record %d", tt.status, w.Code, rec.Status)
+			}
+			if got, want := recordFields(rec)["error"], tt.err; (want == nil) != (got == nil) || want != nil && got != want.Error() {
+				t.Errorf("expected error %v got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestWrapErr_afterWrite(t *testing.T) {
+	var rec httplog.Record
+	h := httplog.WrapErr(func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusAccepted)
+		return errors.New("late")
+	}, httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusAccepted || rec.Status != http.StatusAccepted {
+		t.Errorf("expected the written status to be kept got %d", rec.Status)
+	}
+	if recordFields(rec)["error"] != "late" {
+		t.Errorf("expected the error to be logged got %v", rec.Attrs)
+	}
+}
@@ -0,0 +1,257 @@
+package httplog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// WithConditionalFields adds if_none_match and if_modified_since, reporting
+// whether the request carried those headers, and not_modified, reporting
+// whether the response status was 304, to each record. Together they show
+// how effective HTTP caching is for an endpoint.
+func WithConditionalFields() Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			rec.Attrs = append(rec.Attrs,
+				slog.Bool("if_none_match", rec.Request.Header.Get("If-None-Match") != ""),
+				slog.Bool("if_modified_since", rec.Request.Header.Get("If-Modified-Since") != ""),
+				slog.Bool("not_modified", rec.Status == http.StatusNotModified),
+			)
+		})
+	})
+}
+
+// Outcome returns the class of a status: "success" below 300, "redirect",
+// "client_error" or "server_error".
+func Outcome(status int) string {
+	switch {
+	case status >= 500:
+		return "server_error"
+	case status >= 400:
+		return "client_error"
+	case status >= 300:
+		return "redirect"
+	default:
+		return "success"
+	}
+}
+
+// WithOutcome adds outcome, as returned by Outcome, and status_text, such as
+// "Not Found", to each record so queries can group by class without ranges
+// of status codes.
+func WithOutcome() Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			text := http.StatusText(rec.Status)
+			if rec.Status == StatusClientClosedRequest {
+				text = "Client Closed Request"
+			}
+			rec.Attrs = append(rec.Attrs,
+				slog.String("outcome", Outcome(rec.Status)),
+				slog.String("status_text", text),
+			)
+		})
+	})
+}
+
+// WithRangeFields adds range, the Range header of the request, and
+// content_range, the Content-Range header of the response, to records of
+// 206 Partial Content and 416 Range Not Satisfiable responses, for debugging
+// streaming and large downloads.
+func WithRangeFields() Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			if rec.Status != http.StatusPartialContent && rec.Status != http.StatusRequestedRangeNotSatisfiable {
+				return
+			}
+			if v := rec.Request.Header.Get("Range"); v != "" {
+				rec.Attrs = append(rec.Attrs, slog.String("range", v))
+			}
+			if v := rec.ResponseHeader.Get("Content-Range"); v != "" {
+				rec.Attrs = append(rec.Attrs, slog.String("content_range", v))
+			}
+		})
+	})
+}
+
+// cacheStatusHeaders are the response headers WithCacheStatus reads, in
+// order of preference.
+var cacheStatusHeaders = []string{"Cf-Cache-Status", "X-Cache-Status", "X-Cache"}
+
+// WithCacheStatus adds cache_status, read from the CF-Cache-Status,
+// X-Cache-Status or X-Cache response header set by a caching layer, to each
+// record that has one. Values are reduced to their first word in upper case
+// so "Hit from cloudfront" becomes "HIT". A positive Age header is added as
+// age and, without any of the other headers, implies a HIT.
+func WithCacheStatus() Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			status := ""
+			for _, name := range cacheStatusHeaders {
+				if v := strings.Fields(rec.ResponseHeader.Get(name)); len(v) > 0 {
+					status = strings.ToUpper(strings.TrimSuffix(v[0], ","))
+					break
+				}
+			}
+			age, err := strconv.Atoi(rec.ResponseHeader.Get("Age"))
+			if err != nil || age <= 0 {
+				age = 0
+			} else if status == "" {
+				status = "HIT"
+			}
+			if status != "" {
+				rec.Attrs = append(rec.Attrs, slog.String("cache_status", status))
+			}
+			if age > 0 {
+				rec.Attrs = append(rec.Attrs, slog.Int("age", age))
+			}
+		})
+	})
+}
+
+// WithRedirectLocation adds location, the Location header, to records of 3xx
+// responses so redirect chains can be followed in the logs. Redirects to a
+// host other than the one requested also get redirect_external=true, which
+// helps find open redirects.
+func WithRedirectLocation() Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			if rec.Status < 300 || rec.Status > 399 {
+				return
+			}
+			location := rec.ResponseHeader.Get("Location")
+			if location == "" {
+				return
+			}
+			rec.Attrs = append(rec.Attrs, slog.String("location", location))
+			if u, err := url.Parse(location); err == nil && u.Host != "" && normalizeHost(u.Host) != normalizeHost(rec.Request.Host) {
+				rec.Attrs = append(rec.Attrs, slog.Bool("redirect_external", true))
+			}
+		})
+	})
+}
+
+// WithNegotiationFields adds accept and accept_encoding from the request and
+// content_type and content_encoding from the response, when present, to
+// each record, for diagnosing content negotiation such as clients that ask
+// for gzip but do not get it.
+func WithNegotiationFields() Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			for _, f := range [...]struct {
+				key   string
+				value string
+			}{
+				{"accept", rec.Request.Header.Get("Accept")},
+				{"accept_encoding", rec.Request.Header.Get("Accept-Encoding")},
+				{"content_type", rec.ResponseHeader.Get("Content-Type")},
+				{"content_encoding", rec.ResponseHeader.Get("Content-Encoding")},
+			} {
+				if f.value != "" {
+					rec.Attrs = append(rec.Attrs, slog.String(f.key, f.value))
+				}
+			}
+		})
+	})
+}
+
+// IdempotencyKeyHeader is the header WithIdempotencyKey reads by default.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// WithIdempotencyKey adds idempotency_key, the value of header, to records
+// of POST, PUT, PATCH and DELETE requests carrying it, so retries of the
+// same operation can be traced. IdempotencyKeyHeader is used when header is
+// empty.
+func WithIdempotencyKey(header string) Option {
+	if header == "" {
+		header = IdempotencyKeyHeader
+	}
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			switch rec.Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			default:
+				return
+			}
+			if v := rec.Request.Header.Get(header); v != "" {
+				rec.Attrs = append(rec.Attrs, slog.String("idempotency_key", v))
+			}
+		})
+	})
+}
+
+// edgeHeaders maps the request headers added by CDNs and load balancers to
+// the fields WithEdgeHeaders logs them as.
+var edgeHeaders = []struct{ header, key string }{
+	{"Cf-Ray", "cf_ray"},
+	{"Fly-Request-Id", "fly_request_id"},
+	{"X-Amzn-Trace-Id", "amzn_trace_id"},
+	{"Fastly-Ff", "fastly_ff"},
+	{"X-Cloud-Trace-Context", "cloud_trace_context"},
+	{"X-Vercel-Id", "vercel_id"},
+	{"X-Azure-Ref", "azure_ref"},
+}
+
+// WithEdgeHeaders adds the correlation IDs set by CDNs and load balancers,
+// such as CF-Ray as cf_ray, Fly-Request-Id as fly_request_id,
+// X-Amzn-Trace-Id as amzn_trace_id and Fastly-FF as fastly_ff, to records
+// of requests that carry them so origin logs can be joined with edge logs.
+func WithEdgeHeaders() Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			for _, h := range edgeHeaders {
+				if v := rec.Request.Header.Get(h.header); v != "" {
+					rec.Attrs = append(rec.Attrs, slog.String(h.key, v))
+				}
+			}
+		})
+	})
+}
+
+// isPreflight reports whether req is a CORS preflight request. Wrap adds
+// preflight=true to the records of such requests.
+func isPreflight(req *http.Request) bool {
+	return req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// WithPreflightSampling logs only the given fraction of CORS preflight
+// requests. A rate of 0 suppresses them entirely and a rate of 1 logs all of
+// them. Preflights often dominate the logs of browser facing APIs.
+func WithPreflightSampling(rate float64) Option {
+	return optionFunc(func(c *config) {
+		c.filters = append(c.filters, func(rec *Record) bool {
+			if !isPreflight(rec.Request) {
+				return true
+			}
+			return sampled(rate)
+		})
+	})
+}
+
+// WithFingerprint adds a fingerprint field to each record: a stable hash of
+// the method, the normalized path (see NormalizePath), and the values of the
+// given request headers. Requests with the same fingerprint, such as
+// retries or bot traffic, can be grouped without logging the raw headers.
+func WithFingerprint(headers ...string) Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			h := sha256.New()
+			h.Write([]byte(rec.Request.Method))
+			h.Write([]byte{0})
+			h.Write([]byte(NormalizePath(rec.Request.URL.Path)))
+			for _, name := range headers {
+				h.Write([]byte{0})
+				for _, value := range rec.Request.Header.Values(name) {
+					h.Write([]byte(value))
+					h.Write([]byte{'\n'})
+				}
+			}
+			rec.Attrs = append(rec.Attrs, slog.String("fingerprint", hex.EncodeToString(h.Sum(nil)[:8])))
+		})
+	})
+}
@@ -0,0 +1,296 @@
+package httplog_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func recordFields(rec httplog.Record) map[string]any {
+	fields := make(map[string]any, len(rec.Attrs))
+	for _, a := range rec.Attrs {
+		fields[a.Key] = a.Value.Any()
+	}
+	return fields
+}
+
+func groupFields(rec httplog.Record, key string) (map[string]any, bool) {
+	for _, a := range rec.Attrs {
+		if a.Key == key && a.Value.Kind() == slog.KindGroup {
+			return recordFields(httplog.Record{Attrs: a.Value.Group()}), true
+		}
+	}
+	return nil, false
+}
+
+func TestWithConditionalFields(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}), httplog.WithConditionalFields(), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-None-Match", `"abc"`)
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	fields := recordFields(rec)
+	for key, want := range map[string]bool{
+		"if_none_match":     true,
+		"if_modified_since": false,
+		"not_modified":      true,
+	} {
+		if fields[key] != want {
+			t.Errorf("expected %s to be %t got %v", key, want, fields[key])
+		}
+	}
+}
+
+func TestWithOutcome(t *testing.T) {
+	for _, tt := range []struct {
+		Status  int
+		Outcome string
+		Text    string
+	}{
+		{Status: http.StatusOK, Outcome: "success", Text: "OK"},
+		{Status: http.StatusMovedPermanently, Outcome: "redirect", Text: "Moved Permanently"},
+		{Status: http.StatusNotFound, Outcome: "client_error", Text: "Not Found"},
+		{Status: httplog.StatusClientClosedRequest, Outcome: "client_error", Text: "Client Closed Request"},
+		{Status: http.StatusServiceUnavailable, Outcome: "server_error", Text: "Service Unavailable"},
+	} {
+		var rec httplog.Record
+		h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.Status)
+		}), httplog.WithOutcome(), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		fields := recordFields(rec)
+		if fields["outcome"] != tt.Outcome || fields["status_text"] != tt.Text {
+			t.Errorf("expected %s and %q for %d got %v", tt.Outcome, tt.Text, tt.Status, fields)
+		}
+	}
+}
+
+func TestWithRangeFields(t *testing.T) {
+	content := strings.NewReader("0123456789")
+	var rec httplog.Record
+	h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "digits.txt", time.Time{}, content)
+	}), httplog.WithRangeFields(), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+
+	r := httptest.NewRequest(http.MethodGet, "/digits.txt", nil)
+	r.Header.Set("Range", "bytes=2-5")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	fields := recordFields(rec)
+	if rec.Status != http.StatusPartialContent || fields["range"] != "bytes=2-5" || fields["content_range"] != "bytes 2-5/10" {
+		t.Errorf("unexpected record status %d fields %v", rec.Status, fields)
+	}
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/digits.txt", nil))
+	if _, ok := recordFields(rec)["range"]; ok || rec.Status != http.StatusOK {
+		t.Errorf("expected no range fields for a full response got %v", rec.Attrs)
+	}
+}
+
+func TestWithCacheStatus(t *testing.T) {
+	for _, tt := range []struct {
+		Name   string
+		Header http.Header
+		Status any
+		Age    any
+	}{
+		{Name: "none", Header: http.Header{}},
+		{Name: "cloudflare", Header: http.Header{"Cf-Cache-Status": {"DYNAMIC"}}, Status: "DYNAMIC"},
+		{Name: "x-cache", Header: http.Header{"X-Cache": {"Hit from cloudfront"}}, Status: "HIT"},
+		{Name: "preference", Header: http.Header{"X-Cache": {"MISS"}, "X-Cache-Status": {"STALE"}}, Status: "STALE"},
+		{Name: "age", Header: http.Header{"Age": {"120"}}, Status: "HIT", Age: int64(120)},
+		{Name: "age with status", Header: http.Header{"Age": {"3"}, "X-Cache": {"REVALIDATED"}}, Status: "REVALIDATED", Age: int64(3)},
+		{Name: "zero age", Header: http.Header{"Age": {"0"}}},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var rec httplog.Record
+			h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for name, values := range tt.Header {
+					w.Header()[name] = values
+				}
+			}), httplog.WithCacheStatus(), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			fields := recordFields(rec)
+			if fields["cache_status"] != tt.Status || fields["age"] != tt.Age {
+				t.Errorf("expected cache_status %v and age %v got %v", tt.Status, tt.Age, fields)
+			}
+		})
+	}
+}
+
+func TestWithRedirectLocation(t *testing.T) {
+	for _, tt := range []struct {
+		Name     string
+		Location string
+		Status   int
+		External any
+	}{
+		{Name: "relative", Location: "/login", Status: http.StatusFound},
+		{Name: "same host", Location: "https://example.com:443/login", Status: http.StatusMovedPermanently},
+		{Name: "external", Location: "https://evil.example/", Status: http.StatusSeeOther, External: true},
+		{Name: "protocol relative", Location: "//evil.example/", Status: http.StatusFound, External: true},
+		{Name: "not a redirect", Location: "/items/1", Status: http.StatusCreated},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var rec httplog.Record
+			h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Location", tt.Location)
+				w.WriteHeader(tt.Status)
+			}), httplog.WithRedirectLocation(), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			fields := recordFields(rec)
+			var wantLocation any
+			if tt.Status < 400 && tt.Status >= 300 {
+				wantLocation = tt.Location
+			}
+			if fields["location"] != wantLocation || fields["redirect_external"] != tt.External {
+				t.Errorf("unexpected fields %v", fields)
+			}
+		})
+	}
+}
+
+func TestWithNegotiationFields(t *testing.T) {
+	var rec httplog.Record
+	h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte("<p>hello</p>"))
+	}), httplog.WithNegotiationFields(), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "application/json")
+	r.Header.Set("Accept-Encoding", "gzip, br")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	fields := recordFields(rec)
+	for key, want := range map[string]any{
+		"accept":           "application/json",
+		"accept_encoding":  "gzip, br",
+		"content_type":     "text/html; charset=utf-8",
+		"content_encoding": nil,
+	} {
+		if got := fields[key]; got != want {
+			t.Errorf("expected %s to be %v got %v", key, want, got)
+		}
+	}
+}
+
+func TestWithIdempotencyKey(t *testing.T) {
+	for _, tt := range []struct {
+		Name   string
+		Header string
+		Method string
+		Want   any
+	}{
+		{Name: "post", Method: http.MethodPost, Want: "key-1"},
+		{Name: "get", Method: http.MethodGet},
+		{Name: "custom header", Header: "X-Request-Key", Method: http.MethodPatch, Want: "key-1"},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var rec httplog.Record
+			h := httplog.Wrap(http.NotFoundHandler(), httplog.WithIdempotencyKey(tt.Header), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+			r := httptest.NewRequest(tt.Method, "/", nil)
+			header := tt.Header
+			if header == "" {
+				header = httplog.IdempotencyKeyHeader
+			}
+			r.Header.Set(header, "key-1")
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got := recordFields(rec)["idempotency_key"]; got != tt.Want {
+				t.Errorf("expected idempotency_key %v got %v", tt.Want, got)
+			}
+		})
+	}
+}
+
+func TestWithEdgeHeaders(t *testing.T) {
+	var rec httplog.Record
+	h := httplog.Wrap(http.NotFoundHandler(), httplog.WithEdgeHeaders(), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("CF-Ray", "8c1b2a3d4e5f6789-AMS")
+	r.Header.Set("X-Amzn-Trace-Id", "Root=1-67891233-abcdef012345678912345678")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	fields := recordFields(rec)
+	if fields["cf_ray"] != "8c1b2a3d4e5f6789-AMS" || fields["amzn_trace_id"] != "Root=1-67891233-abcdef012345678912345678" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+	if _, ok := fields["fly_request_id"]; ok {
+		t.Errorf("expected missing headers to be omitted")
+	}
+}
+
+func TestWrap_preflight(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	if got := recordFields(rec)["preflight"]; got != true {
+		t.Errorf("expected preflight to be true got %v", got)
+	}
+}
+
+func TestWithPreflightSampling(t *testing.T) {
+	var methods []string
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithPreflightSampling(0), httplog.RecordFunc(func(r httplog.Record) {
+		methods = append(methods, r.Method)
+	}))
+
+	preflight := httptest.NewRequest(http.MethodOptions, "/", nil)
+	preflight.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	logMux.ServeHTTP(httptest.NewRecorder(), preflight)
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodOptions, "/", nil))
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+
+	if len(methods) != 2 || methods[0] != http.MethodOptions || methods[1] != http.MethodPost {
+		t.Errorf("expected only the preflight request to be suppressed got %v", methods)
+	}
+}
+
+func TestWithFingerprint(t *testing.T) {
+	var fingerprints []any
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithFingerprint("User-Agent"), httplog.RecordFunc(func(r httplog.Record) {
+		fingerprints = append(fingerprints, recordFields(r)["fingerprint"])
+	}))
+
+	for _, tt := range []struct {
+		Path, UserAgent string
+	}{
+		{Path: "/users/1", UserAgent: "bot"},
+		{Path: "/users/2", UserAgent: "bot"},
+		{Path: "/users/2", UserAgent: "browser"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, tt.Path, nil)
+		r.Header.Set("User-Agent", tt.UserAgent)
+		logMux.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	if fp, ok := fingerprints[0].(string); !ok || len(fp) != 16 {
+		t.Fatalf("expected a fingerprint got %v", fingerprints[0])
+	}
+	if fingerprints[0] != fingerprints[1] {
+		t.Errorf("expected requests to the same route to share a fingerprint")
+	}
+	if fingerprints[1] == fingerprints[2] {
+		t.Errorf("expected a different header value to change the fingerprint")
+	}
+}
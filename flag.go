@@ -0,0 +1,151 @@
+package httplog
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Level is a slog.Level that implements flag.Value, so a service can
+// register it directly:
+//
+//	var level httplog.Level
+//	flag.Var(&level, "http-log-level", "minimum level of access log records")
+type Level slog.Level
+
+// Level implements slog.Leveler.
+func (l Level) Level() slog.Level { return slog.Level(l) }
+
+// String implements flag.Value.
+func (l Level) String() string { return slog.Level(l).String() }
+
+// Set implements flag.Value. It accepts the names understood by
+// slog.Level.UnmarshalText, such as "info", "ERROR", or "warn+1".
+func (l *Level) Set(s string) error { return l.UnmarshalText([]byte(s)) }
+
+// MarshalText implements encoding.TextMarshaler.
+func (l Level) MarshalText() ([]byte, error) { return slog.Level(l).MarshalText() }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (l *Level) UnmarshalText(text []byte) error {
+	return (*slog.Level)(l).UnmarshalText(text)
+}
+
+// WithMinLevel drops records whose Level is below level. For example
+// slog.LevelError only logs requests with a status of 500 or more.
+func WithMinLevel(level slog.Leveler) Option {
+	return withFilter(func(rec *Record) bool {
+		return rec.Level() >= level.Level()
+	})
+}
+
+// DebugOnly applies options but only computes the fields they add while
+// level is at most slog.LevelDebug, so expensive fields such as the headers
+// of WithRequestHeaders, the request bodies WithGraphQL parses, or the stack
+// of WithRecover cost nothing on the info path. level is read for every
+// request, so a slog.LevelVar turns the fields on and off at run time:
+//
+//	var level slog.LevelVar
+//	httplog.Wrap(h, httplog.DebugOnly(&level, httplog.WithRequestHeaders("Accept"), httplog.WithRecover()))
+//
+// Options that do not add fields, such as sinks and filters, are applied as
+// usual; WithRecover still recovers panics but only logs the stack at debug.
+func DebugOnly(level slog.Leveler, options ...Option) Option {
+	enabled := func() bool { return level.Level() <= slog.LevelDebug }
+	return optionFunc(func(c *config) {
+		inspectors, extractors, finalizers := len(c.inspectors), len(c.extractors), len(c.finalizers)
+		recoverPanics := c.recoverPanics
+		for _, o := range options {
+			o.apply(c)
+		}
+		for i, inspect := range c.inspectors[inspectors:] {
+			inspect := inspect
+			c.inspectors[inspectors+i] = func(req *http.Request) func(rec *Record) {
+				if !enabled() {
+					return nil
+				}
+				return inspect(req)
+			}
+		}
+		for _, list := range [][]func(rec *Record){c.extractors[extractors:], c.finalizers[finalizers:]} {
+			for i, extract := range list {
+				extract := extract
+				list[i] = func(rec *Record) {
+					if enabled() {
+						extract(rec)
+					}
+				}
+			}
+		}
+		if !recoverPanics && c.recoverPanics {
+			c.stackEnabled = enabled
+		}
+	})
+}
+
+// Format selects an access log encoding. It implements flag.Value so a
+// service can register it directly:
+//
+//	var format httplog.Format
+//	flag.Var(&format, "http-log-format", "access log format: json, text, common or combined")
+type Format int
+
+const (
+	// FormatJSON writes the JSON lines produced by JSON.
+	FormatJSON Format = iota
+	// FormatText writes records with Structured and a slog.TextHandler.
+	FormatText
+	// FormatCommon writes the Common Log Format lines of CommonLog.
+	FormatCommon
+	// FormatCombined writes the Combined Log Format lines of CombinedLog.
+	FormatCombined
+)
+
+var formatNames = [...]string{
+	FormatJSON:     "json",
+	FormatText:     "text",
+	FormatCommon:   "common",
+	FormatCombined: "combined",
+}
+
+// String implements flag.Value.
+func (f Format) String() string {
+	if f < 0 || int(f) >= len(formatNames) {
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+	return formatNames[f]
+}
+
+// Set implements flag.Value.
+func (f *Format) Set(s string) error { return f.UnmarshalText([]byte(s)) }
+
+// MarshalText implements encoding.TextMarshaler.
+func (f Format) MarshalText() ([]byte, error) { return []byte(f.String()), nil }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (f *Format) UnmarshalText(text []byte) error {
+	for i, name := range formatNames {
+		if strings.EqualFold(string(text), name) {
+			*f = Format(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("httplog: unknown format %q", text)
+}
+
+// Sink returns a RecordFunc writing records to w in the format. The Common
+// and Combined Log Formats ignore options.
+func (f Format) Sink(w io.Writer, options ...FormatOption) RecordFunc {
+	switch f {
+	case FormatText:
+		return Structured(slog.New(slog.NewTextHandler(w, nil)), options...)
+	case FormatCommon:
+		return CommonLog(w)
+	case FormatCombined:
+		return CombinedLog(w)
+	default:
+		return JSONWriter(w, nil, options...)
+	}
+}
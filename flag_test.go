@@ -0,0 +1,124 @@
+package httplog_test
+
+import (
+	"bytes"
+	"flag"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestLevel_flag(t *testing.T) {
+	var level httplog.Level
+	var format httplog.Format
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Var(&level, "http-log-level", "")
+	flags.Var(&format, "http-log-format", "")
+
+	if err := flags.Parse([]string{"-http-log-level=error", "-http-log-format=TEXT"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if level.Level() != slog.LevelError {
+		t.Errorf("expected level error got %s", level)
+	}
+	if format != httplog.FormatText {
+		t.Errorf("expected format text got %s", format)
+	}
+	if err := flags.Set("http-log-format", "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if err := flags.Set("http-log-level", "loud"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestFormat_Sink(t *testing.T) {
+	for _, tt := range []struct {
+		Format httplog.Format
+		Want   string
+	}{
+		{Format: httplog.FormatJSON, Want: `"path": "/greeting"`},
+		{Format: httplog.FormatText, Want: `msg=request method=GET host=example.com path=/greeting`},
+		{Format: httplog.FormatCombined, Want: `"GET /greeting HTTP/1.1" 404 19 "" ""`},
+	} {
+		t.Run(tt.Format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			logMux := httplog.Wrap(http.NotFoundHandler(), tt.Format.Sink(&buf))
+
+			logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/greeting", nil))
+
+			if got := buf.String(); !strings.Contains(got, tt.Want) {
+				t.Errorf("expected %q to contain %q", got, tt.Want)
+			}
+		})
+	}
+}
+
+func TestWithMinLevel(t *testing.T) {
+	var statuses []int
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}), httplog.WithMinLevel(httplog.Level(slog.LevelError)), httplog.RecordFunc(func(rec httplog.Record) {
+		statuses = append(statuses, rec.Status)
+	}))
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	if len(statuses) != 1 || statuses[0] != http.StatusInternalServerError {
+		t.Errorf("expected only the failed request got %v", statuses)
+	}
+}
+
+func TestDebugOnly(t *testing.T) {
+	var level slog.LevelVar
+	var records []httplog.Record
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/panic" {
+			panic("boom")
+		}
+	}), httplog.DebugOnly(&level, httplog.WithRequestHeaders("Accept"), httplog.WithRecover()), httplog.RecordFunc(func(rec httplog.Record) {
+		records = append(records, rec)
+	}))
+
+	serve := func(path string) {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Accept", "text/html")
+		logMux.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	serve("/")
+	serve("/panic")
+	level.Set(slog.LevelDebug)
+	serve("/")
+	serve("/panic")
+
+	if len(records) != 4 {
+		t.Fatalf("expected 4 records got %d", len(records))
+	}
+	for i, want := range []struct {
+		Headers, Panic, Stack bool
+	}{
+		{Headers: false},
+		{Headers: false, Panic: true, Stack: false},
+		{Headers: true},
+		{Headers: true, Panic: true, Stack: true},
+	} {
+		got := map[string]bool{}
+		for _, a := range records[i].Attrs {
+			got[a.Key] = true
+		}
+		if got["request_headers"] != want.Headers || got["panic"] != want.Panic || got["stack"] != want.Stack {
+			t.Errorf("record %d: expected %+v got attrs %v", i, want, records[i].Attrs)
+		}
+	}
+	if records[1].Status != http.StatusInternalServerError {
+		t.Errorf("expected the panic to be recovered at info got status %d", records[1].Status)
+	}
+}
@@ -0,0 +1,304 @@
+package httplog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// DurationFormat selects how a sink encodes the request duration.
+type DurationFormat int
+
+const (
+	// DurationString encodes durations using time.Duration.String, for example "1.2ms".
+	DurationString DurationFormat = iota
+	// DurationMilliseconds encodes durations as a floating point number of milliseconds.
+	DurationMilliseconds
+	// DurationNanoseconds encodes durations as an integer number of nanoseconds.
+	DurationNanoseconds
+	// DurationSeconds encodes durations as a floating point number of seconds.
+	DurationSeconds
+)
+
+func (f DurationFormat) appendJSON(b []byte, d time.Duration) []byte {
+	switch f {
+	case DurationMilliseconds:
+		return strconv.AppendFloat(b, float64(d)/float64(time.Millisecond), 'f', -1, 64)
+	case DurationNanoseconds:
+		return strconv.AppendInt(b, d.Nanoseconds(), 10)
+	case DurationSeconds:
+		return strconv.AppendFloat(b, d.Seconds(), 'f', -1, 64)
+	default:
+		return appendJSONString(b, d.String())
+	}
+}
+
+// SchemaVersion is written as the "schema" field of JSON output. It changes
+// when fields are removed or change type, not when fields are added.
+// httplog.schema.json describes the fields of this version.
+const SchemaVersion = "httplog/v1"
+
+// FormatOption configures how a sink, such as JSON, encodes requests.
+type FormatOption func(*format)
+
+type format struct {
+	duration DurationFormat
+
+	// The fields below are only used by Structured.
+	message, errorMessage       string
+	static                      []slog.Attr
+	requestGroup, responseGroup string
+	profile                     *Profile
+}
+
+func newFormat(options []FormatOption) format {
+	f := format{message: "request", errorMessage: "request error"}
+	for _, o := range options {
+		o(&f)
+	}
+	return f
+}
+
+// WithDurationFormat sets the encoding used for the request duration.
+func WithDurationFormat(f DurationFormat) FormatOption {
+	return func(o *format) {
+		o.duration = f
+	}
+}
+
+// EncodeJSON returns the JSON object JSON and JSONWriter write for rec,
+// without the trailing newline. The output is valid JSON for any record:
+// strings are escaped as JSON requires, invalid UTF-8 is replaced with
+// U+FFFD, and non-finite floats are encoded as null.
+func EncodeJSON(rec Record, options ...FormatOption) []byte {
+	return newFormat(options).appendJSON(nil, rec)
+}
+
+func (f format) appendJSON(b []byte, rec Record) []byte {
+	if rec.Type != "" && rec.Type != TypeRequest {
+		b = append(b, `{"type": `...)
+		b = appendJSONString(b, rec.Type)
+		b = append(b, `, "schema": "`+SchemaVersion+`"`...)
+		for _, a := range rec.Attrs {
+			b = f.appendJSONAttr(b, a, ", ")
+		}
+		return append(b, '}')
+	}
+	b = append(b, `{"type": "HTTP_REQUEST", "schema": "`+SchemaVersion+`", "method": `...)
+	b = appendJSONString(b, rec.Method)
+	if rec.Host != "" {
+		b = append(b, `, "host": `...)
+		b = appendJSONString(b, rec.Host)
+	}
+	b = append(b, `, "path": `...)
+	b = appendJSONString(b, rec.Path)
+	if rec.Route != "" {
+		b = append(b, `, "route": `...)
+		b = appendJSONString(b, rec.Route)
+	}
+	if rec.Query != "" {
+		b = append(b, `, "query": `...)
+		b = appendJSONString(b, rec.Query)
+	}
+	b = append(b, `, "duration": `...)
+	b = f.duration.appendJSON(b, rec.Duration)
+	b = append(b, `, "status": `...)
+	b = strconv.AppendInt(b, int64(rec.Status), 10)
+	if rec.RequestID != "" {
+		b = append(b, `, "request_id": `...)
+		b = appendJSONString(b, rec.RequestID)
+	}
+	if rec.TraceID != "" {
+		b = append(b, `, "trace_id": `...)
+		b = appendJSONString(b, rec.TraceID)
+	}
+	if rec.ClientIP != "" {
+		b = append(b, `, "client_ip": `...)
+		b = appendJSONString(b, rec.ClientIP)
+	}
+	if rec.UserAgent != "" {
+		b = append(b, `, "user_agent": `...)
+		b = appendJSONString(b, rec.UserAgent)
+	}
+	for _, a := range rec.Attrs {
+		b = f.appendJSONAttr(b, a, ", ")
+	}
+	return append(b, '}')
+}
+
+// appendJSONAttrs encodes attrs as a JSON object.
+func (f format) appendJSONAttrs(b []byte, attrs []slog.Attr) []byte {
+	b = append(b, '{')
+	sep := ""
+	for _, a := range attrs {
+		before := len(b)
+		b = f.appendJSONAttr(b, a, sep)
+		if len(b) > before {
+			sep = ", "
+		}
+	}
+	return append(b, '}')
+}
+
+func (f format) appendJSONAttr(b []byte, a slog.Attr, sep string) []byte {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return b
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return b
+		}
+		if a.Key == "" {
+			for _, ga := range attrs {
+				before := len(b)
+				b = f.appendJSONAttr(b, ga, sep)
+				if len(b) > before {
+					sep = ", "
+				}
+			}
+			return b
+		}
+		b = append(b, sep...)
+		b = appendJSONString(b, a.Key)
+		b = append(b, ": {"...)
+		sep = ""
+		for _, ga := range attrs {
+			before := len(b)
+			b = f.appendJSONAttr(b, ga, sep)
+			if len(b) > before {
+				sep = ", "
+			}
+		}
+		return append(b, '}')
+	}
+	b = append(b, sep...)
+	b = appendJSONString(b, a.Key)
+	b = append(b, ": "...)
+	return f.appendJSONValue(b, a.Value)
+}
+
+func (f format) appendJSONValue(b []byte, v slog.Value) []byte {
+	switch v.Kind() {
+	case slog.KindString:
+		return appendJSONString(b, v.String())
+	case slog.KindInt64:
+		return strconv.AppendInt(b, v.Int64(), 10)
+	case slog.KindUint64:
+		return strconv.AppendUint(b, v.Uint64(), 10)
+	case slog.KindFloat64:
+		return appendJSONFloat(b, v.Float64())
+	case slog.KindBool:
+		return strconv.AppendBool(b, v.Bool())
+	case slog.KindDuration:
+		return f.duration.appendJSON(b, v.Duration())
+	case slog.KindTime:
+		return appendJSONString(b, v.Time().Format(time.RFC3339Nano))
+	default:
+		if err, ok := v.Any().(error); ok {
+			return appendJSONString(b, err.Error())
+		}
+		if p, err := json.Marshal(v.Any()); err == nil {
+			return append(b, p...)
+		}
+		return appendJSONString(b, fmt.Sprint(v.Any()))
+	}
+}
+
+// structuredValue returns v, an attr value of kind any such as a slice of
+// StackFrame, as the maps, slices, json.Number, string and bool values of
+// its JSON encoding, so binary sinks encode it with the same structure as
+// the JSON sinks. It reports false when v cannot be encoded as JSON.
+func structuredValue(v any) (any, bool) {
+	p, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(p))
+	decoder.UseNumber()
+	var structured any
+	if err := decoder.Decode(&structured); err != nil {
+		return nil, false
+	}
+	return structured, true
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends s as a JSON string. Unlike strconv.AppendQuote,
+// whose \x and \U escapes are not JSON, it only uses the escapes JSON
+// allows. U+2028 and U+2029 are escaped too since they end lines in
+// JavaScript.
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
+
+// appendJSONFloat appends v as a JSON number, or null for NaN and the
+// infinities which JSON cannot represent.
+func appendJSONFloat(b []byte, v float64) []byte {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return append(b, "null"...)
+	}
+	return strconv.AppendFloat(b, v, 'g', -1, 64)
+}
@@ -0,0 +1,149 @@
+package httplog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithDurationFormat(t *testing.T) {
+	for _, tt := range []struct {
+		Name   string
+		Format httplog.DurationFormat
+		Want   string
+	}{
+		{Name: "string", Format: httplog.DurationString, Want: `"duration": "1.5ms"`},
+		{Name: "milliseconds", Format: httplog.DurationMilliseconds, Want: `"duration": 1.5,`},
+		{Name: "nanoseconds", Format: httplog.DurationNanoseconds, Want: `"duration": 1500000,`},
+		{Name: "seconds", Format: httplog.DurationSeconds, Want: `"duration": 0.0015,`},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			fn := httplog.JSON(log.New(&out, "", 0), log.New(&errOut, "", 0), httplog.WithDurationFormat(tt.Format))
+
+			fn(httptest.NewRequest(http.MethodGet, "/", nil), 1500*time.Microsecond, http.StatusOK)
+
+			if got := out.String(); !strings.Contains(got, tt.Want) {
+				t.Errorf("expected %q to contain %q", got, tt.Want)
+			}
+			if errOut.Len() != 0 {
+				t.Errorf("expected no error output got %q", errOut.String())
+			}
+		})
+	}
+}
+
+func TestJSON_attrs(t *testing.T) {
+	var out bytes.Buffer
+	fn := httplog.JSONWriter(&out, nil)
+
+	fn(httplog.Record{
+		Method: http.MethodGet,
+		Path:   "/",
+		Status: http.StatusOK,
+		Attrs: []slog.Attr{
+			slog.Group("baggage", slog.String("team", "payments"), slog.Int("attempt", 2)),
+			slog.Group("empty"),
+			slog.Bool("ok", true),
+		},
+	})
+
+	var line map[string]any
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("expected valid JSON got %q: %s", out.String(), err)
+	}
+	baggage, ok := line["baggage"].(map[string]any)
+	if !ok || baggage["team"] != "payments" || baggage["attempt"] != float64(2) {
+		t.Errorf("unexpected baggage field %v", line["baggage"])
+	}
+	if _, ok := line["empty"]; ok {
+		t.Errorf("expected empty groups to be omitted")
+	}
+	if line["ok"] != true {
+		t.Errorf("expected ok to be true got %v", line["ok"])
+	}
+}
+
+func TestJSONWriter(t *testing.T) {
+	var out, errOut bytes.Buffer
+	fn := httplog.JSONWriter(&out, &errOut)
+
+	fn(httplog.Record{Method: http.MethodGet, Path: "/", Status: http.StatusOK})
+	fn(httplog.Record{Method: http.MethodGet, Path: "/", Status: http.StatusBadGateway})
+
+	if got := strings.Count(out.String(), "\n"); got != 2 {
+		t.Errorf("expected 2 lines on out got %d: %q", got, out.String())
+	}
+	if !strings.Contains(errOut.String(), `"status": 502`) || strings.Count(errOut.String(), "\n") != 1 {
+		t.Errorf("expected only the 502 line on errOut got %q", errOut.String())
+	}
+
+	fn = httplog.JSONWriter(&out, nil)
+	fn(httplog.Record{Method: http.MethodGet, Path: "/", Status: http.StatusBadGateway})
+}
+
+func TestEncodeJSON(t *testing.T) {
+	got := httplog.EncodeJSON(httplog.Record{
+		Method:    http.MethodGet,
+		Path:      "/a\"b\\c\x00\x7f\u2028",
+		Status:    http.StatusOK,
+		UserAgent: "bad\xffutf8\a",
+		Attrs: []slog.Attr{
+			slog.Float64("ratio", math.NaN()),
+			slog.String("key\n", "\U0001F600\u00ad"),
+		},
+	})
+	want := `{"type": "HTTP_REQUEST", "schema": "httplog/v1", "method": "GET", "path": "/a\"b\\c\u0000` + "\x7f" + `\u2028", "duration": "0s", "status": 200, "user_agent": "bad\ufffdutf8\u0007", "ratio": null, "key\n": "` + "\U0001F600\u00ad" + `"}`
+	if string(got) != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+	if !json.Valid(got) {
+		t.Errorf("expected valid JSON got %s", got)
+	}
+}
+
+func FuzzEncodeJSON(f *testing.F) {
+	f.Add("/users/1", "Mozilla/5.0", "q=1", "value")
+	f.Add("/\"quoted\"", "\x00\x1f\x7f", "a=%22", "\\")
+	f.Add("/\xff\xfe", "\u2028\u2029", "\U0010FFFF", "\xed\xa0\x80")
+	f.Fuzz(func(t *testing.T, path, userAgent, query, value string) {
+		rec := httplog.Record{
+			Method:    http.MethodGet,
+			Host:      value,
+			Path:      path,
+			Route:     path,
+			Query:     query,
+			UserAgent: userAgent,
+			Status:    http.StatusOK,
+			Duration:  time.Millisecond,
+			Attrs: []slog.Attr{
+				slog.String(value, value),
+				slog.Group("group", slog.String(path, userAgent)),
+				slog.Any("list", []string{value}),
+			},
+		}
+		for _, format := range []httplog.DurationFormat{httplog.DurationString, httplog.DurationMilliseconds} {
+			line := httplog.EncodeJSON(rec, httplog.WithDurationFormat(format))
+			var decoded map[string]any
+			if err := json.Unmarshal(line, &decoded); err != nil {
+				t.Fatalf("invalid JSON %q: %s", line, err)
+			}
+			if bytes.ContainsAny(line, "\n\r") {
+				t.Fatalf("expected a single line got %q", line)
+			}
+			if utf8.ValidString(path) && decoded["path"] != path {
+				t.Fatalf("expected path %q got %q", path, decoded["path"])
+			}
+		}
+	})
+}
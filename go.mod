@@ -1,3 +1,5 @@
 module github.com/crhntr/httplog
 
 go 1.21
+
+require go.yaml.in/yaml/v3 v3.0.4
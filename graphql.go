@@ -0,0 +1,120 @@
+package httplog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// WithGraphQL adds the operation name and type of GraphQL requests posted to
+// one of paths (by default "/graphql") to each record as a "graphql" group.
+// At most DefaultPeekLimit bytes of the body are read to find them.
+func WithGraphQL(paths ...string) Option {
+	if len(paths) == 0 {
+		paths = []string{"/graphql"}
+	}
+	return optionFunc(func(c *config) {
+		c.inspectors = append(c.inspectors, func(req *http.Request) func(rec *Record) {
+			if req.Method != http.MethodPost || !containsString(paths, req.URL.Path) {
+				return nil
+			}
+			name, operation := graphQLOperation(peekBody(req, DefaultPeekLimit))
+			if name == "" && operation == "" {
+				return nil
+			}
+			return func(rec *Record) {
+				var attrs []slog.Attr
+				if name != "" {
+					attrs = append(attrs, slog.String("operation_name", name))
+				}
+				if operation != "" {
+					attrs = append(attrs, slog.String("operation_type", operation))
+				}
+				rec.Attrs = append(rec.Attrs, slog.Attr{Key: "graphql", Value: slog.GroupValue(attrs...)})
+			}
+		})
+	})
+}
+
+// graphQLOperation returns the operationName and the type of the first
+// operation in the query of a GraphQL request body. It reads the body as a
+// stream of JSON tokens so a truncated body still yields any fields before
+// the cut.
+func graphQLOperation(body []byte) (name, operation string) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return "", ""
+	}
+	var query string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		var dst any = new(json.RawMessage)
+		switch tok {
+		case "operationName":
+			dst = &name
+		case "query":
+			dst = &query
+		}
+		if err := dec.Decode(dst); err != nil {
+			break
+		}
+	}
+	if query == "" {
+		return name, ""
+	}
+	operation, queryName := graphQLOperationType(query)
+	if name == "" {
+		name = queryName
+	}
+	return name, operation
+}
+
+// graphQLOperationType returns the type and name of the first operation
+// defined in query.
+func graphQLOperationType(query string) (operation, name string) {
+	for {
+		query = strings.TrimLeft(query, " \t\r\n,\ufeff")
+		if !strings.HasPrefix(query, "#") {
+			break
+		}
+		if i := strings.IndexAny(query, "\r\n"); i >= 0 {
+			query = query[i:]
+		} else {
+			return "", ""
+		}
+	}
+	if strings.HasPrefix(query, "{") {
+		return "query", ""
+	}
+	for _, op := range []string{"query", "mutation", "subscription"} {
+		rest, ok := strings.CutPrefix(query, op)
+		if !ok || (rest != "" && isGraphQLNameChar(rest[0])) {
+			continue
+		}
+		rest = strings.TrimLeft(rest, " \t\r\n,")
+		end := 0
+		for end < len(rest) && isGraphQLNameChar(rest[end]) {
+			end++
+		}
+		return op, rest[:end]
+	}
+	return "", ""
+}
+
+func isGraphQLNameChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
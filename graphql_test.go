@@ -0,0 +1,87 @@
+package httplog_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithGraphQL(t *testing.T) {
+	for _, tt := range []struct {
+		Name          string
+		Body          string
+		WantName      string
+		WantOperation string
+	}{
+		{
+			Name:          "operation name",
+			Body:          `{"query": "query GetUser($id: ID!) { user(id: $id) { name } }", "operationName": "GetUser", "variables": {"id": "1"}}`,
+			WantName:      "GetUser",
+			WantOperation: "query",
+		},
+		{
+			Name:          "name from query",
+			Body:          `{"query": "# comment\nmutation AddUser { addUser { id } }"}`,
+			WantName:      "AddUser",
+			WantOperation: "mutation",
+		},
+		{
+			Name:          "anonymous shorthand",
+			Body:          `{"operationName": null, "query": "{ me { id } }"}`,
+			WantOperation: "query",
+		},
+		{
+			Name:     "truncated",
+			Body:     `{"operationName": "Big", "query": "query Big { ` + strings.Repeat("a ", httplog.DefaultPeekLimit) + `}"}`,
+			WantName: "Big",
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var rec httplog.Record
+			logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(body) != tt.Body {
+					t.Errorf("expected the handler to read the whole body")
+				}
+			}), httplog.WithGraphQL(), httplog.RecordFunc(func(r httplog.Record) {
+				rec = r
+			}))
+
+			logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tt.Body)))
+
+			if len(rec.Attrs) != 1 || rec.Attrs[0].Key != "graphql" {
+				t.Fatalf("expected a graphql group got %v", rec.Attrs)
+			}
+			fields := map[string]string{}
+			for _, a := range rec.Attrs[0].Value.Group() {
+				fields[a.Key] = a.Value.String()
+			}
+			if fields["operation_name"] != tt.WantName {
+				t.Errorf("expected operation_name %q got %q", tt.WantName, fields["operation_name"])
+			}
+			if fields["operation_type"] != tt.WantOperation {
+				t.Errorf("expected operation_type %q got %q", tt.WantOperation, fields["operation_type"])
+			}
+		})
+	}
+}
+
+func TestWithGraphQL_otherPath(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithGraphQL(), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api", strings.NewReader(`{"query": "{ me }"}`)))
+
+	if len(rec.Attrs) != 0 {
+		t.Errorf("expected no fields got %v", rec.Attrs)
+	}
+}
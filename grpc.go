@@ -0,0 +1,79 @@
+package httplog
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// grpcCodes maps gRPC status codes to their canonical names.
+var grpcCodes = [...]string{
+	"OK",
+	"Canceled",
+	"Unknown",
+	"InvalidArgument",
+	"DeadlineExceeded",
+	"NotFound",
+	"AlreadyExists",
+	"PermissionDenied",
+	"ResourceExhausted",
+	"FailedPrecondition",
+	"Aborted",
+	"OutOfRange",
+	"Unimplemented",
+	"Internal",
+	"Unavailable",
+	"DataLoss",
+	"Unauthenticated",
+}
+
+// WithGRPCStatus adds the gRPC status of gRPC and gRPC-Web responses to each
+// record as grpc_code (the numeric code) and grpc_status (its name, for
+// example "NotFound"). gRPC responses almost always have HTTP status 200 so
+// without these fields failed calls look successful.
+//
+// The status is read from the Grpc-Status trailer or, for trailers-only
+// responses, the header. Trailers encoded in a gRPC-Web response body are
+// not parsed.
+func WithGRPCStatus() Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			if !isGRPC(rec.Request.Header.Get("Content-Type")) {
+				return
+			}
+			value := rec.ResponseHeader.Get("Grpc-Status")
+			if value == "" {
+				value = rec.ResponseHeader.Get(http.TrailerPrefix + "Grpc-Status")
+			}
+			if value == "" {
+				return
+			}
+			code, err := strconv.Atoi(value)
+			if err != nil {
+				return
+			}
+			rec.Attrs = append(rec.Attrs, slog.Int("grpc_code", code), slog.String("grpc_status", grpcCodeName(code)))
+		})
+	})
+}
+
+// isGRPC reports whether contentType is one of the gRPC or gRPC-Web media
+// types, such as application/grpc+proto or application/grpc-web-text.
+func isGRPC(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	for _, prefix := range []string{"application/grpc-web-text", "application/grpc-web", "application/grpc"} {
+		if rest, ok := strings.CutPrefix(mediaType, prefix); ok {
+			return rest == "" || rest[0] == '+'
+		}
+	}
+	return false
+}
+
+func grpcCodeName(code int) string {
+	if code < 0 || code >= len(grpcCodes) {
+		return "Code(" + strconv.Itoa(code) + ")"
+	}
+	return grpcCodes[code]
+}
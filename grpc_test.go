@@ -0,0 +1,90 @@
+package httplog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithGRPCStatus(t *testing.T) {
+	for _, tt := range []struct {
+		Name        string
+		ContentType string
+		Handler     http.HandlerFunc
+		WantCode    int64
+		WantStatus  string
+	}{
+		{
+			Name:        "declared trailer",
+			ContentType: "application/grpc",
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Trailer", "Grpc-Status")
+				w.WriteHeader(http.StatusOK)
+				w.Header().Set("Grpc-Status", "5")
+			},
+			WantCode:   5,
+			WantStatus: "NotFound",
+		},
+		{
+			Name:        "trailer prefix",
+			ContentType: "application/grpc+proto",
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Header().Set(http.TrailerPrefix+"Grpc-Status", "13")
+			},
+			WantCode:   13,
+			WantStatus: "Internal",
+		},
+		{
+			Name:        "grpc-web trailers only",
+			ContentType: "application/grpc-web-text",
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Grpc-Status", "0")
+				w.WriteHeader(http.StatusOK)
+			},
+			WantCode:   0,
+			WantStatus: "OK",
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var rec httplog.Record
+			logMux := httplog.Wrap(tt.Handler, httplog.WithGRPCStatus(), httplog.RecordFunc(func(r httplog.Record) {
+				rec = r
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, "/pkg.Service/Method", nil)
+			r.Header.Set("Content-Type", tt.ContentType)
+			logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+			fields := map[string]any{}
+			for _, a := range rec.Attrs {
+				fields[a.Key] = a.Value.Any()
+			}
+			if got := fields["grpc_code"]; got != tt.WantCode {
+				t.Errorf("expected grpc_code %d got %v", tt.WantCode, got)
+			}
+			if got := fields["grpc_status"]; got != tt.WantStatus {
+				t.Errorf("expected grpc_status %q got %v", tt.WantStatus, got)
+			}
+		})
+	}
+}
+
+func TestWithGRPCStatus_notGRPC(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Grpc-Status", "5")
+	}), httplog.WithGRPCStatus(), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Content-Type", "application/grpcfoo")
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	if len(rec.Attrs) != 0 {
+		t.Errorf("expected no attributes got %v", rec.Attrs)
+	}
+}
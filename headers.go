@@ -0,0 +1,53 @@
+package httplog
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// WithRequestHeaders adds a request_headers group with the values of the
+// named request headers that are present. Keys are the header names in
+// lower case with dashes replaced by underscores, so User-Agent is logged as
+// user_agent, and repeated headers are joined with ", ". Credentials, in
+// headers such as Authorization, Cookie or X-Api-Key, are logged as
+// "REDACTED".
+func WithRequestHeaders(names ...string) Option {
+	return withHeaders("request_headers", names, func(rec *Record) http.Header { return rec.Request.Header })
+}
+
+// WithResponseHeaders is like WithRequestHeaders for response headers, such
+// as Content-Type, Cache-Control or X-RateLimit-Remaining, logged in a
+// response_headers group.
+func WithResponseHeaders(names ...string) Option {
+	return withHeaders("response_headers", names, func(rec *Record) http.Header { return rec.ResponseHeader })
+}
+
+func withHeaders(group string, names []string, header func(rec *Record) http.Header) Option {
+	type allowed struct{ name, key string }
+	headers := make([]allowed, len(names))
+	for i, name := range names {
+		name = http.CanonicalHeaderKey(name)
+		headers[i] = allowed{name: name, key: strings.ReplaceAll(strings.ToLower(name), "-", "_")}
+	}
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			h := header(rec)
+			var attrs []slog.Attr
+			for _, a := range headers {
+				values := h.Values(a.name)
+				if len(values) == 0 {
+					continue
+				}
+				value := strings.Join(values, ", ")
+				if sensitiveName(a.name) {
+					value = "REDACTED"
+				}
+				attrs = append(attrs, slog.String(a.key, value))
+			}
+			if len(attrs) > 0 {
+				rec.Attrs = append(rec.Attrs, slog.Attr{Key: group, Value: slog.GroupValue(attrs...)})
+			}
+		})
+	})
+}
@@ -0,0 +1,57 @@
+package httplog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithResponseHeaders(t *testing.T) {
+	var rec httplog.Record
+	h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Cache-Control", "no-cache")
+		w.Header().Add("Cache-Control", "no-store")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("X-Internal", "hidden")
+	}), httplog.WithResponseHeaders("content-type", "Cache-Control", "X-RateLimit-Remaining", "Set-Cookie"), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	headers, ok := groupFields(rec, "response_headers")
+	if !ok {
+		t.Fatalf("expected a response_headers group got %v", rec.Attrs)
+	}
+	for key, want := range map[string]any{
+		"content_type":          "application/json",
+		"cache_control":         "no-cache, no-store",
+		"set_cookie":            "REDACTED",
+		"x_ratelimit_remaining": nil,
+		"x_internal":            nil,
+	} {
+		if got := headers[key]; got != want {
+			t.Errorf("expected %s to be %v got %v", key, want, got)
+		}
+	}
+}
+
+func TestWithRequestHeaders(t *testing.T) {
+	var rec httplog.Record
+	h := httplog.Wrap(http.NotFoundHandler(), httplog.WithRequestHeaders("Accept", "Authorization", "X-Missing"), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/html")
+	r.Header.Set("Authorization", "Bearer secret")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	headers, _ := groupFields(rec, "request_headers")
+	if headers["accept"] != "text/html" || headers["authorization"] != "REDACTED" || len(headers) != 2 {
+		t.Errorf("unexpected request headers %v", headers)
+	}
+
+	h = httplog.Wrap(http.NotFoundHandler(), httplog.WithRequestHeaders("X-Missing"), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if _, ok := groupFields(rec, "request_headers"); ok {
+		t.Errorf("expected no group without matching headers")
+	}
+}
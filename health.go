@@ -0,0 +1,49 @@
+package httplog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HealthChecker is implemented by sinks that can report whether they are
+// able to deliver records, such as network sinks.
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+}
+
+// HealthCheckFunc adapts a function to a HealthChecker.
+type HealthCheckFunc func(ctx context.Context) error
+
+// Ping calls fn.
+func (fn HealthCheckFunc) Ping(ctx context.Context) error { return fn(ctx) }
+
+// HealthTimeout bounds how long HealthHandler waits for each checker.
+const HealthTimeout = 5 * time.Second
+
+// HealthHandler returns a handler that pings every checker and responds with
+// 200 OK when all succeed and 503 Service Unavailable, listing the errors,
+// otherwise. Mount it as, or alongside, a readiness probe when access logs
+// are mandatory for a deployment.
+func HealthHandler(checkers ...HealthChecker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), HealthTimeout)
+		defer cancel()
+		var errs []error
+		for _, checker := range checkers {
+			if err := checker.Ping(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if len(errs) > 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = io.WriteString(w, errors.Join(errs...).Error()+"\n")
+			return
+		}
+		_, _ = io.WriteString(w, "ok\n")
+	})
+}
@@ -0,0 +1,49 @@
+package httplog_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestHealthHandler(t *testing.T) {
+	async := httplog.Async(func(httplog.Record) {}, 1)
+	healthy := httplog.HealthCheckFunc(func(context.Context) error { return nil })
+	handler := httplog.HealthHandler(healthy, async)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d got %d", http.StatusOK, w.Code)
+	}
+
+	async.Close()
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "closed") {
+		t.Errorf("expected the error in the body got %q", body)
+	}
+}
+
+func TestHealthHandler_errors(t *testing.T) {
+	handler := httplog.HealthHandler(
+		httplog.HealthCheckFunc(func(context.Context) error { return errors.New("collector unreachable") }),
+		httplog.HealthCheckFunc(func(context.Context) error { return errors.New("disk full") }),
+	)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if body := w.Body.String(); !strings.Contains(body, "collector unreachable") || !strings.Contains(body, "disk full") {
+		t.Errorf("expected both errors in the body got %q", body)
+	}
+}
@@ -0,0 +1,100 @@
+package httplog
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// WithHeartbeat passes a record of type TypeInFlight to the functions of
+// Wrap for requests still in flight after the given time, and then every
+// interval until they complete, so dashboards keep seeing the traffic of
+// long-poll and streaming endpoints during long holds. The attrs are the
+// request_id, method, path, route, client_ip and trace_id of the request,
+// elapsed, the time since it started, and heartbeat, the number of the
+// heartbeat starting at 1. The record of a request that had heartbeats gets
+// a heartbeats field with their count.
+//
+// Heartbeats are emitted from their own goroutine and are not sampled or
+// filtered, but their fields are sanitized, truncated and transformed by Map
+// and redaction options like those of the request records.
+func WithHeartbeat(after, interval time.Duration) Option {
+	return optionFunc(func(c *config) {
+		c.inspectors = append(c.inspectors, func(req *http.Request) func(rec *Record) {
+			info, ok := requestInfoFrom(req.Context())
+			if !ok {
+				return nil
+			}
+			start := timeNow()
+			stop := make(chan struct{})
+			count := make(chan int, 1)
+			go func() {
+				n := 0
+				defer func() { count <- n }()
+				timer := time.NewTimer(after)
+				defer timer.Stop()
+				for {
+					select {
+					case <-stop:
+						return
+					case <-req.Context().Done():
+						return
+					case <-timer.C:
+					}
+					n++
+					c.recordFunc()(c.heartbeatRecord(req, info, start, n))
+					timer.Reset(interval)
+				}
+			}()
+			return func(rec *Record) {
+				close(stop)
+				if n := <-count; n > 0 {
+					rec.Attrs = append(rec.Attrs, slog.Int("heartbeats", n))
+				}
+			}
+		})
+	})
+}
+
+// heartbeatRecord builds the heartbeat after the fields went through the
+// same sanitizing, truncation and finalizers as the record of the request,
+// so redaction options apply to heartbeats too.
+func (c *config) heartbeatRecord(req *http.Request, info *requestInfo, start time.Time, n int) Record {
+	rec := Record{
+		Request:   req,
+		Time:      start,
+		Method:    info.Method,
+		Path:      info.Path,
+		Route:     info.Route,
+		ClientIP:  info.ClientIP,
+		RequestID: info.ID,
+		TraceID:   info.Trace.TraceID,
+	}
+	sanitizeRecord(&rec)
+	c.limits.truncate(&rec)
+	for _, finalize := range c.finalizers {
+		finalize(&rec)
+	}
+	route := rec.Route
+	if route == "" {
+		route = rec.Path
+	}
+	attrs := []slog.Attr{
+		slog.String("request_id", rec.RequestID),
+		slog.String("method", rec.Method),
+		slog.String("path", rec.Path),
+		slog.String("route", route),
+	}
+	if rec.ClientIP != "" {
+		attrs = append(attrs, slog.String("client_ip", rec.ClientIP))
+	}
+	if rec.TraceID != "" {
+		attrs = append(attrs, slog.String("trace_id", rec.TraceID))
+	}
+	now := timeNow()
+	attrs = append(attrs,
+		slog.Duration("elapsed", now.Sub(start)),
+		slog.Int("heartbeat", n),
+	)
+	return Record{Type: TypeInFlight, Time: now, Attrs: append(attrs, rec.Attrs...)}
+}
@@ -0,0 +1,119 @@
+package httplog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithHeartbeat(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		heartbeats []httplog.Record
+		requests   []httplog.Record
+	)
+	beat := make(chan struct{}, 10)
+	release := make(chan struct{})
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/poll" {
+			<-release
+		}
+	}), httplog.WithHeartbeat(5*time.Millisecond, 5*time.Millisecond), httplog.RecordFunc(func(rec httplog.Record) {
+		mu.Lock()
+		defer mu.Unlock()
+		if rec.Type == httplog.TypeInFlight {
+			heartbeats = append(heartbeats, rec)
+			select {
+			case beat <- struct{}{}:
+			default:
+			}
+			return
+		}
+		requests = append(requests, rec)
+	}))
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/quick", nil))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/poll", nil))
+	}()
+	<-beat
+	<-beat
+	close(release)
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(heartbeats) < 2 {
+		t.Fatalf("expected at least 2 heartbeats got %d", len(heartbeats))
+	}
+	attrs := map[string]any{}
+	for _, a := range heartbeats[1].Attrs {
+		attrs[a.Key] = a.Value.Any()
+	}
+	if attrs["path"] != "/poll" || attrs["heartbeat"] != int64(2) || attrs["request_id"] == "" {
+		t.Errorf("unexpected heartbeat attrs %v", heartbeats[1].Attrs)
+	}
+	if elapsed, _ := attrs["elapsed"].(time.Duration); elapsed < 10*time.Millisecond {
+		t.Errorf("expected the second heartbeat after at least 10ms got %v", attrs["elapsed"])
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 request records got %d", len(requests))
+	}
+	for _, a := range requests[0].Attrs {
+		if a.Key == "heartbeats" {
+			t.Errorf("expected no heartbeats for the quick request")
+		}
+	}
+	var count int64
+	for _, a := range requests[1].Attrs {
+		if a.Key == "heartbeats" {
+			count = a.Value.Int64()
+		}
+	}
+	if count != int64(len(heartbeats)) {
+		t.Errorf("expected the request record to count %d heartbeats got %d", len(heartbeats), count)
+	}
+}
+
+func TestWithHeartbeat_sanitized(t *testing.T) {
+	beats := make(chan httplog.Record, 10)
+	release := make(chan struct{})
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}), httplog.WithHeartbeat(time.Millisecond, time.Hour), httplog.WithMaskedIP(), httplog.WithLimits(httplog.Limits{Path: 8}),
+		httplog.RecordFunc(func(rec httplog.Record) {
+			if rec.Type == httplog.TypeInFlight {
+				beats <- rec
+			}
+		}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.URL.Path = "/a\n{\"type\": \"HTTP_REQUEST\"}"
+	r.RemoteAddr = "198.51.100.77:5555"
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logMux.ServeHTTP(httptest.NewRecorder(), r)
+	}()
+	rec := <-beats
+	close(release)
+	<-done
+
+	line := string(httplog.EncodeJSON(rec))
+	for _, a := range rec.Attrs {
+		if path := a.Value.String(); a.Key == "path" && (strings.Contains(path, "\n") || len(path) > 8) {
+			t.Errorf("expected a sanitized and truncated path got %q", path)
+		}
+	}
+	if !strings.Contains(line, `"client_ip": "198.51.100.0"`) || !strings.Contains(line, `"truncated": true`) {
+		t.Errorf("expected the masked client IP and the truncated flag got %s", line)
+	}
+}
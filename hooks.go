@@ -0,0 +1,103 @@
+package httplog
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// RequestStartHook is implemented by hooks that run before the wrapped
+// handler. The returned request is passed on to later hooks and the
+// handler, so a hook can derive a new context, for example to start a span.
+type RequestStartHook interface {
+	OnRequestStart(req *http.Request) *http.Request
+}
+
+// ResponseWriteHook is implemented by hooks that run once when the handler
+// first writes the response, just before header is sent, so the hook may
+// still add response headers.
+type ResponseWriteHook interface {
+	OnResponseWrite(req *http.Request, status int, header http.Header)
+}
+
+// CompleteHook is implemented by hooks that run after the handler returns.
+// OnComplete receives every record, including those later dropped by
+// sampling or filtering options.
+type CompleteHook interface {
+	OnComplete(rec Record)
+}
+
+// PanicHook is implemented by hooks that are notified of panics recovered by
+// WithRecover, for example to page someone or report to an error tracker.
+// OnPanic runs before CompleteHook and is called for every panic, including
+// those whose record is later dropped, but not for http.ErrAbortHandler.
+type PanicHook interface {
+	OnPanic(rec Record, value any, stack []StackFrame)
+}
+
+// WithHook registers hook with Wrap. The hook must implement at least one of
+// RequestStartHook, ResponseWriteHook, CompleteHook, or PanicHook; each hook
+// method is called from the goroutine serving the request. Hooks are called
+// in the order they were registered.
+func WithHook(hook any) Option {
+	start, isStart := hook.(RequestStartHook)
+	write, isWrite := hook.(ResponseWriteHook)
+	complete, isComplete := hook.(CompleteHook)
+	panicHook, isPanic := hook.(PanicHook)
+	if !isStart && !isWrite && !isComplete && !isPanic {
+		panic(fmt.Sprintf("httplog: %T does not implement any hook interface", hook))
+	}
+	return optionFunc(func(c *config) {
+		if isStart {
+			c.startHooks = append(c.startHooks, start)
+		}
+		if isWrite {
+			c.writeHooks = append(c.writeHooks, write)
+		}
+		if isComplete {
+			c.completeHooks = append(c.completeHooks, complete)
+		}
+		if isPanic {
+			c.panicHooks = append(c.panicHooks, panicHook)
+		}
+	})
+}
+
+// PanicWebhook is a PanicHook that posts each panic as a JSON object to a
+// URL, such as a chat or incident webhook. Create it with NewPanicWebhook
+// and register it with WithHook.
+type PanicWebhook struct {
+	url    string
+	client *http.Client
+	format format
+}
+
+// NewPanicWebhook returns a PanicWebhook posting to url with a client that
+// gives up after 10 seconds. The body is the JSON line of the record, which
+// holds the panic and stack fields. Requests are sent from a new goroutine so
+// a slow endpoint does not delay the response; failures are counted in
+// Stats.WriteErrors.
+func NewPanicWebhook(url string, options ...FormatOption) *PanicWebhook {
+	return &PanicWebhook{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		format: newFormat(options),
+	}
+}
+
+// OnPanic implements PanicHook.
+func (h *PanicWebhook) OnPanic(rec Record, _ any, _ []StackFrame) {
+	body := h.format.appendJSON(nil, rec)
+	go func() {
+		res, err := h.client.Post(h.url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			stats.writeErrors.Add(1)
+			return
+		}
+		_ = res.Body.Close()
+		if res.StatusCode >= 300 {
+			stats.writeErrors.Add(1)
+		}
+	}()
+}
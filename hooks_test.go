@@ -0,0 +1,141 @@
+package httplog_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+type hookKey struct{}
+
+type recordingHook struct {
+	calls []string
+}
+
+func (h *recordingHook) OnRequestStart(req *http.Request) *http.Request {
+	h.calls = append(h.calls, "start")
+	return req.WithContext(context.WithValue(req.Context(), hookKey{}, "from hook"))
+}
+
+func (h *recordingHook) OnResponseWrite(req *http.Request, status int, header http.Header) {
+	h.calls = append(h.calls, "write")
+	if req.Context().Value(hookKey{}) != "from hook" {
+		h.calls = append(h.calls, "missing context")
+	}
+}
+
+func (h *recordingHook) OnComplete(rec httplog.Record) {
+	h.calls = append(h.calls, "complete")
+}
+
+func TestWithHook(t *testing.T) {
+	hook := new(recordingHook)
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hook.calls = append(hook.calls, "handler")
+		if r.Context().Value(hookKey{}) != "from hook" {
+			t.Error("expected the handler to receive the request returned by the hook")
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("done"))
+	}), httplog.WithHook(hook), httplog.WithPreflightSampling(0), httplog.RecordFunc(func(httplog.Record) {
+		hook.calls = append(hook.calls, "log")
+	}))
+
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	want := []string{"start", "handler", "write", "complete"}
+	if len(hook.calls) != len(want) {
+		t.Fatalf("expected calls %v got %v", want, hook.calls)
+	}
+	for i := range want {
+		if hook.calls[i] != want[i] {
+			t.Errorf("expected calls %v got %v", want, hook.calls)
+			break
+		}
+	}
+}
+
+func TestWithHook_responseHeader(t *testing.T) {
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}), httplog.WithHook(headerHook{}), httplog.RecordFunc(func(httplog.Record) {}))
+
+	w := httptest.NewRecorder()
+	logMux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := w.Result().Header.Get("Server-Timing"); got != "app" {
+		t.Errorf("expected the hook to set a response header got %q", got)
+	}
+}
+
+type headerHook struct{}
+
+func (headerHook) OnResponseWrite(req *http.Request, status int, header http.Header) {
+	header.Set("Server-Timing", "app")
+}
+
+func TestWithHook_invalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	httplog.WithHook(struct{}{})
+}
+
+type panicHook struct {
+	value any
+	stack []httplog.StackFrame
+	path  string
+}
+
+func (h *panicHook) OnPanic(rec httplog.Record, value any, stack []httplog.StackFrame) {
+	h.value, h.stack, h.path = value, stack, rec.Path
+}
+
+func TestWithHook_panic(t *testing.T) {
+	hook := new(panicHook)
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), httplog.WithRecover(), httplog.WithHook(hook), httplog.WithMinLevel(slog.Level(100)), httplog.RecordFunc(func(httplog.Record) {}))
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+	if hook.value != "boom" || hook.path != "/orders" || len(hook.stack) == 0 {
+		t.Errorf("expected the hook to be notified of the panic got %+v", hook)
+	}
+}
+
+func TestNewPanicWebhook(t *testing.T) {
+	bodies := make(chan map[string]any, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		bodies <- body
+	}))
+	defer webhook.Close()
+
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), httplog.WithRecover(), httplog.WithHook(httplog.NewPanicWebhook(webhook.URL)), httplog.RecordFunc(func(httplog.Record) {}))
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+	select {
+	case body := <-bodies:
+		if body["panic"] != "boom" || body["path"] != "/orders" || body["status"] != float64(http.StatusInternalServerError) {
+			t.Errorf("unexpected webhook body %v", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the webhook to be called")
+	}
+}
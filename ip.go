@@ -0,0 +1,73 @@
+package httplog
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// WithMaskedIP zeroes the host part of the client IP before it is logged:
+// the last octet of IPv4 addresses and the last 80 bits of IPv6 addresses.
+// Masked addresses still show the network a request came from, which is
+// often enough for operations while satisfying data protection rules.
+func WithMaskedIP() Option {
+	return optionFunc(func(c *config) {
+		c.anonymize = MaskIP
+	})
+}
+
+// MaskIP zeroes the last octet of an IPv4 address or the last 80 bits of an
+// IPv6 address. Strings that are not IP addresses are returned unchanged.
+func MaskIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap()
+	bits := 24
+	if addr.Is6() {
+		bits = 48
+	}
+	prefix, err := addr.WithZone("").Prefix(bits)
+	if err != nil {
+		return ip
+	}
+	return prefix.Addr().String()
+}
+
+// WithHashedIP replaces the client IP with a keyed hash before it is logged.
+// The key is random and replaced every rotation, so requests from the same
+// client can be correlated within a rotation period but the address cannot
+// be recovered, even by brute force, once the key is discarded.
+func WithHashedIP(rotation time.Duration) Option {
+	h := &ipHasher{rotation: rotation}
+	return optionFunc(func(c *config) {
+		c.anonymize = h.hash
+	})
+}
+
+type ipHasher struct {
+	rotation time.Duration
+
+	mu      sync.Mutex
+	salt    [16]byte
+	expires time.Time
+}
+
+func (h *ipHasher) hash(ip string) string {
+	h.mu.Lock()
+	if now := timeNow(); !now.Before(h.expires) {
+		_, _ = rand.Read(h.salt[:])
+		h.expires = now.Add(h.rotation)
+	}
+	salt := h.salt
+	h.mu.Unlock()
+
+	sum := sha256.New()
+	sum.Write(salt[:])
+	sum.Write([]byte(ip))
+	return hex.EncodeToString(sum.Sum(nil)[:8])
+}
@@ -0,0 +1,63 @@
+package httplog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestMaskIP(t *testing.T) {
+	for _, tt := range []struct {
+		IP, Want string
+	}{
+		{IP: "192.0.2.123", Want: "192.0.2.0"},
+		{IP: "2001:db8:85a3:8d3:1319:8a2e:370:7348", Want: "2001:db8:85a3::"},
+		{IP: "::ffff:192.0.2.123", Want: "192.0.2.0"},
+		{IP: "not-an-ip", Want: "not-an-ip"},
+	} {
+		if got := httplog.MaskIP(tt.IP); got != tt.Want {
+			t.Errorf("MaskIP(%q) = %q, want %q", tt.IP, got, tt.Want)
+		}
+	}
+}
+
+func TestWithMaskedIP(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithMaskedIP(), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "198.51.100.77:5555"
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	if rec.ClientIP != "198.51.100.0" {
+		t.Errorf("expected masked client IP got %q", rec.ClientIP)
+	}
+}
+
+func TestWithHashedIP(t *testing.T) {
+	var ips []string
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithHashedIP(time.Hour), httplog.RecordFunc(func(r httplog.Record) {
+		ips = append(ips, r.ClientIP)
+	}))
+
+	for _, addr := range []string{"198.51.100.77:5555", "198.51.100.77:6666", "198.51.100.78:5555"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = addr
+		logMux.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	if ips[0] == "198.51.100.77" || len(ips[0]) != 16 {
+		t.Errorf("expected a hashed client IP got %q", ips[0])
+	}
+	if ips[0] != ips[1] {
+		t.Errorf("expected the same client to hash to the same value got %q and %q", ips[0], ips[1])
+	}
+	if ips[0] == ips[2] {
+		t.Errorf("expected different clients to hash to different values")
+	}
+}
@@ -0,0 +1,209 @@
+package httplog
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JournalSocket is the socket journald listens on for the native protocol.
+const JournalSocket = "/run/systemd/journal/socket"
+
+// JournalWriter sends records to journald using its native protocol so
+// access logs are indexed and can be queried with journalctl, for example
+//
+//	journalctl SYSLOG_IDENTIFIER=api HTTP_STATUS=503
+//
+// It is created with NewJournalWriter. A JournalWriter is an Option so it can
+// be passed to Wrap directly.
+//
+// Each entry has a MESSAGE such as "GET /items 200 1.2ms", a PRIORITY of err
+// for a status of 500 or more, warning for 400 or more and info otherwise,
+// and the fields HTTP_METHOD, HTTP_HOST, HTTP_PATH, HTTP_ROUTE, HTTP_QUERY,
+// HTTP_STATUS, DURATION_USEC, REQUEST_ID, TRACE_ID, CLIENT_IP and
+// USER_AGENT. Attrs are added with upper case names, joining groups with an
+// underscore.
+type JournalWriter struct {
+	identifier string
+
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+// NewJournalWriter connects to the journald socket, usually JournalSocket.
+// Entries are tagged with identifier as SYSLOG_IDENTIFIER when it is not
+// empty.
+func NewJournalWriter(socket, identifier string) (*JournalWriter, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &JournalWriter{identifier: identifier, conn: conn}, nil
+}
+
+// Log sends rec to journald. Entries journald does not accept, such as ones
+// larger than the socket buffer, are counted in Stats.WriteErrors.
+func (j *JournalWriter) Log(rec Record) {
+	b := j.appendEntry(nil, rec)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.conn == nil {
+		stats.writeErrors.Add(1)
+		return
+	}
+	if _, err := j.conn.Write(b); err != nil {
+		stats.writeErrors.Add(1)
+	}
+}
+
+func (j *JournalWriter) apply(c *config) { RecordFunc(j.Log).apply(c) }
+
+// Close closes the connection to journald.
+func (j *JournalWriter) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.conn == nil {
+		return nil
+	}
+	err := j.conn.Close()
+	j.conn = nil
+	return err
+}
+
+// Ping reports an error once the JournalWriter is closed.
+func (j *JournalWriter) Ping(context.Context) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.conn == nil {
+		return errors.New("httplog: journal closed")
+	}
+	return nil
+}
+
+// journalPriority maps the level of rec to a syslog priority. Records
+// without a level set by an option get one from their status, so client
+// errors are warnings.
+func journalPriority(rec Record) string {
+	level := rec.Level()
+	if rec.minLevel == 0 {
+		switch {
+		case rec.Status >= 500:
+			return "3"
+		case rec.Status >= 400:
+			return "4"
+		}
+	}
+	switch {
+	case level >= slog.LevelError:
+		return "3"
+	case level >= slog.LevelWarn:
+		return "4"
+	case level >= slog.LevelInfo:
+		return "6"
+	default:
+		return "7"
+	}
+}
+
+func (j *JournalWriter) appendEntry(b []byte, rec Record) []byte {
+	priority := journalPriority(rec)
+	if rec.Type != "" && rec.Type != TypeRequest {
+		b = appendJournalField(b, "MESSAGE", rec.Type)
+		b = appendJournalField(b, "PRIORITY", priority)
+		if j.identifier != "" {
+			b = appendJournalField(b, "SYSLOG_IDENTIFIER", j.identifier)
+		}
+		for _, a := range rec.Attrs {
+			b = appendJournalAttr(b, "", a)
+		}
+		return b
+	}
+	b = appendJournalField(b, "MESSAGE", rec.Method+" "+rec.Path+" "+strconv.Itoa(rec.Status)+" "+rec.Duration.String())
+	b = appendJournalField(b, "PRIORITY", priority)
+	if j.identifier != "" {
+		b = appendJournalField(b, "SYSLOG_IDENTIFIER", j.identifier)
+	}
+	for _, field := range [...]struct{ key, value string }{
+		{"HTTP_METHOD", rec.Method},
+		{"HTTP_HOST", rec.Host},
+		{"HTTP_PATH", rec.Path},
+		{"HTTP_ROUTE", rec.Route},
+		{"HTTP_QUERY", rec.Query},
+		{"HTTP_STATUS", strconv.Itoa(rec.Status)},
+		{"DURATION_USEC", strconv.FormatInt(rec.Duration.Microseconds(), 10)},
+		{"REQUEST_ID", rec.RequestID},
+		{"TRACE_ID", rec.TraceID},
+		{"CLIENT_IP", rec.ClientIP},
+		{"USER_AGENT", rec.UserAgent},
+	} {
+		if field.value != "" {
+			b = appendJournalField(b, field.key, field.value)
+		}
+	}
+	for _, a := range rec.Attrs {
+		b = appendJournalAttr(b, "", a)
+	}
+	return b
+}
+
+func appendJournalAttr(b []byte, prefix string, a slog.Attr) []byte {
+	a.Value = a.Value.Resolve()
+	key := prefix + journalFieldName(a.Key)
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			key += "_"
+		} else {
+			key = prefix
+		}
+		for _, ga := range a.Value.Group() {
+			b = appendJournalAttr(b, key, ga)
+		}
+		return b
+	}
+	if key == "" || key[0] >= '0' && key[0] <= '9' || key[0] == '_' {
+		return b
+	}
+	value := a.Value.String()
+	if a.Value.Kind() == slog.KindDuration {
+		value = strconv.FormatInt(a.Value.Duration().Microseconds(), 10)
+	} else if a.Value.Kind() == slog.KindTime {
+		value = a.Value.Time().Format(time.RFC3339Nano)
+	}
+	return appendJournalField(b, key, value)
+}
+
+// journalFieldName maps key to the characters journald allows in field
+// names: upper case letters, digits and underscores.
+func journalFieldName(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		default:
+			return '_'
+		}
+	}, key)
+}
+
+// appendJournalField encodes a field in the native protocol. Values with a
+// newline use the length prefixed binary form.
+func appendJournalField(b []byte, key, value string) []byte {
+	b = append(b, key...)
+	if !strings.Contains(value, "\n") {
+		b = append(b, '=')
+		b = append(b, value...)
+		return append(b, '\n')
+	}
+	b = append(b, '\n')
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(value)))
+	b = append(b, value...)
+	return append(b, '\n')
+}
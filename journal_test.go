@@ -0,0 +1,108 @@
+package httplog_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestJournalWriter(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	j, err := httplog.NewJournalWriter(socket, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	j.Log(httplog.Record{
+		Method:    http.MethodGet,
+		Path:      "/items",
+		Status:    http.StatusServiceUnavailable,
+		Duration:  1500 * time.Microsecond,
+		RequestID: "some-id",
+		Attrs: []slog.Attr{
+			slog.Group("upstream", slog.String("host", "backend:8080")),
+			slog.String("error", "first\nsecond"),
+		},
+	})
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := buf[:n]
+	for _, want := range []string{
+		"MESSAGE=GET /items 503 1.5ms\n",
+		"PRIORITY=3\n",
+		"SYSLOG_IDENTIFIER=api\n",
+		"HTTP_STATUS=503\n",
+		"DURATION_USEC=1500\n",
+		"REQUEST_ID=some-id\n",
+		"UPSTREAM_HOST=backend:8080\n",
+		"ERROR\n\x0c\x00\x00\x00\x00\x00\x00\x00first\nsecond\n",
+	} {
+		if !bytes.Contains(entry, []byte(want)) {
+			t.Errorf("expected entry to contain %q got %q", want, entry)
+		}
+	}
+	if bytes.Contains(entry, []byte("HTTP_QUERY")) {
+		t.Errorf("expected empty fields to be omitted")
+	}
+}
+
+func TestJournalWriter_priority(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	j, err := httplog.NewJournalWriter(socket, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	read := func() []byte {
+		buf := make([]byte, 4096)
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf[:n]
+	}
+
+	j.Log(httplog.Record{Method: http.MethodGet, Path: "/", Status: http.StatusNotFound})
+	if entry := read(); !bytes.Contains(entry, []byte("PRIORITY=4\n")) {
+		t.Errorf("expected a client error to be a warning got %q", entry)
+	}
+
+	httplog.ErrorLog(j.Log).Print("http: TLS handshake error from 192.0.2.1:1234: EOF")
+	if entry := read(); !bytes.Contains(entry, []byte("PRIORITY=4\n")) {
+		t.Errorf("expected the level of a server record got %q", entry)
+	}
+
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		httplog.WithMethodPolicy(map[string]httplog.MethodPolicy{http.MethodDelete: {Level: slog.LevelError}}), j)
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/items/1", nil))
+	if entry := read(); !bytes.Contains(entry, []byte("PRIORITY=3\n")) {
+		t.Errorf("expected the raised level of a request got %q", entry)
+	}
+}
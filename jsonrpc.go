@@ -0,0 +1,73 @@
+package httplog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"net/http"
+)
+
+// WithJSONRPC adds the method and id of JSON-RPC 2.0 requests to each record
+// as a "jsonrpc" group. For batch requests the group holds the batch size
+// and the methods called instead. When paths is empty every POST request
+// with a JSON body is inspected. At most DefaultPeekLimit bytes of the body
+// are read.
+func WithJSONRPC(paths ...string) Option {
+	return optionFunc(func(c *config) {
+		c.inspectors = append(c.inspectors, func(req *http.Request) func(rec *Record) {
+			if req.Method != http.MethodPost {
+				return nil
+			}
+			if len(paths) > 0 && !containsString(paths, req.URL.Path) {
+				return nil
+			}
+			if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType != "application/json" {
+				return nil
+			}
+			attrs := jsonRPCAttrs(peekBody(req, DefaultPeekLimit))
+			if len(attrs) == 0 {
+				return nil
+			}
+			return func(rec *Record) {
+				rec.Attrs = append(rec.Attrs, slog.Attr{Key: "jsonrpc", Value: slog.GroupValue(attrs...)})
+			}
+		})
+	})
+}
+
+type jsonRPCRequest struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	ID      json.RawMessage `json:"id"`
+}
+
+func jsonRPCAttrs(body []byte) []slog.Attr {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []jsonRPCRequest
+		if err := json.Unmarshal(body, &batch); err != nil || len(batch) == 0 {
+			return nil
+		}
+		methods := make([]string, 0, len(batch))
+		for _, call := range batch {
+			if call.Version != "2.0" {
+				return nil
+			}
+			methods = append(methods, call.Method)
+		}
+		return []slog.Attr{slog.Int("batch_size", len(batch)), slog.Any("methods", methods)}
+	}
+	var call jsonRPCRequest
+	if err := json.Unmarshal(body, &call); err != nil || call.Version != "2.0" || call.Method == "" {
+		return nil
+	}
+	attrs := []slog.Attr{slog.String("method", call.Method)}
+	var id string
+	if err := json.Unmarshal(call.ID, &id); err == nil {
+		attrs = append(attrs, slog.String("id", id))
+	} else if len(call.ID) > 0 && !bytes.Equal(call.ID, []byte("null")) {
+		attrs = append(attrs, slog.Any("id", json.Number(call.ID)))
+	}
+	return attrs
+}
@@ -0,0 +1,70 @@
+package httplog_test
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithJSONRPC(t *testing.T) {
+	for _, tt := range []struct {
+		Name string
+		Body string
+		Want string
+	}{
+		{
+			Name: "numeric id",
+			Body: `{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`,
+			Want: `"jsonrpc": {"method": "subtract", "id": 1}`,
+		},
+		{
+			Name: "string id",
+			Body: `{"jsonrpc": "2.0", "method": "subtract", "id": "abc"}`,
+			Want: `"jsonrpc": {"method": "subtract", "id": "abc"}`,
+		},
+		{
+			Name: "notification",
+			Body: `{"jsonrpc": "2.0", "method": "update"}`,
+			Want: `"jsonrpc": {"method": "update"}`,
+		},
+		{
+			Name: "batch",
+			Body: `[{"jsonrpc": "2.0", "method": "sum", "id": 1}, {"jsonrpc": "2.0", "method": "notify"}]`,
+			Want: `"jsonrpc": {"batch_size": 2, "methods": ["sum","notify"]}`,
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var out bytes.Buffer
+			logger := log.New(&out, "", 0)
+			logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithJSONRPC("/rpc"), httplog.JSON(logger, logger))
+
+			r := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(tt.Body))
+			r.Header.Set("Content-Type", "application/json")
+			logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got := out.String(); !strings.Contains(got, tt.Want) {
+				t.Errorf("expected %q to contain %q", got, tt.Want)
+			}
+		})
+	}
+}
+
+func TestWithJSONRPC_notJSONRPC(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithJSONRPC(), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`{"method": "subtract"}`))
+	r.Header.Set("Content-Type", "application/json")
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	if len(rec.Attrs) != 0 {
+		t.Errorf("expected no fields got %v", rec.Attrs)
+	}
+}
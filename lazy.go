@@ -0,0 +1,50 @@
+package httplog
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// Lazy returns a value that calls fn the first time a sink encodes it, so
+// costly enrichment such as a GeoIP or user lookup is skipped for records
+// that are filtered or sampled out. fn is called at most once however many
+// sinks encode the record, and string values it returns are sanitized like
+// other fields. Use it for attrs added to records:
+//
+//	rec.Attrs = append(rec.Attrs, slog.Any("geo", httplog.Lazy(lookupGeo)))
+//
+// fn may be called after the handler returned and from another goroutine,
+// for example by Async sinks, so it must not use the response writer or
+// rely on the request context not being canceled.
+func Lazy(fn func() slog.Value) slog.Value {
+	return slog.AnyValue(&lazyValue{fn: fn})
+}
+
+type lazyValue struct {
+	once  sync.Once
+	fn    func() slog.Value
+	value slog.Value
+}
+
+// LogValue implements slog.LogValuer.
+func (v *lazyValue) LogValue() slog.Value {
+	v.once.Do(func() {
+		v.value = sanitizeAttr(slog.Attr{Value: v.fn().Resolve()}).Value
+		v.fn = nil
+	})
+	return v.value
+}
+
+// WithLazyAttr adds a field named key whose value fn computes from the
+// record only when a sink encodes it, see Lazy. The record passed to fn has
+// the fields set before the option runs.
+func WithLazyAttr(key string, fn func(rec Record) slog.Value) Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			r := *rec
+			rec.Attrs = append(rec.Attrs, slog.Any(key, Lazy(func() slog.Value {
+				return fn(r)
+			})))
+		})
+	})
+}
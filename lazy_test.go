@@ -0,0 +1,48 @@
+package httplog_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithLazyAttr(t *testing.T) {
+	var calls int
+	var first, second bytes.Buffer
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}),
+		httplog.WithLazyAttr("country", func(rec httplog.Record) slog.Value {
+			calls++
+			return slog.StringValue("NZ\n" + rec.ClientIP)
+		}),
+		httplog.WithMinLevel(slog.LevelError),
+		httplog.JSONWriter(&first, nil),
+		httplog.StructuredWriter(&second),
+	)
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if calls != 0 {
+		t.Errorf("expected no lookup for a filtered record got %d", calls)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+	if calls != 1 {
+		t.Errorf("expected a single lookup for both sinks got %d", calls)
+	}
+	if got := first.String(); !strings.Contains(got, `"country": "NZ\\x0A192.0.2.1"`) {
+		t.Errorf("expected the lazy field in the JSON line got %q", got)
+	}
+	if got := second.String(); !strings.Contains(got, `"country":"NZ\\x0A192.0.2.1"`) {
+		t.Errorf("expected the lazy field in the structured line got %q", got)
+	}
+}
@@ -0,0 +1,86 @@
+package httplog
+
+import (
+	"log/slog"
+	"unicode/utf8"
+)
+
+// Limits bounds the length in bytes of logged strings. A zero limit means the
+// string is never truncated. Records with a truncated string include
+// truncated=true.
+type Limits struct {
+	Path      int
+	Query     int
+	UserAgent int
+	// Field bounds string values added by options, such as captured
+	// baggage, headers, or request body fields.
+	Field int
+}
+
+// DefaultLimits is a reasonable starting point for internet facing services.
+var DefaultLimits = Limits{
+	Path:      2048,
+	Query:     2048,
+	UserAgent: 512,
+	Field:     1024,
+}
+
+// WithLimits truncates logged strings longer than the given limits so huge
+// attack URLs or headers cannot flood the log pipeline.
+func WithLimits(limits Limits) Option {
+	return optionFunc(func(c *config) {
+		c.limits = limits
+	})
+}
+
+func (l Limits) truncate(rec *Record) {
+	var truncated bool
+	rec.Path, truncated = truncateString(rec.Path, l.Path, truncated)
+	rec.Route, truncated = truncateString(rec.Route, l.Path, truncated)
+	rec.Query, truncated = truncateString(rec.Query, l.Query, truncated)
+	rec.UserAgent, truncated = truncateString(rec.UserAgent, l.UserAgent, truncated)
+	if l.Field > 0 {
+		for i, a := range rec.Attrs {
+			rec.Attrs[i], truncated = truncateAttr(a, l.Field, truncated)
+		}
+	}
+	if truncated {
+		rec.Attrs = append(rec.Attrs, slog.Bool("truncated", true))
+	}
+}
+
+func truncateAttr(a slog.Attr, limit int, truncated bool) (slog.Attr, bool) {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		s := a.Value.String()
+		if short, t := truncateString(s, limit, truncated); len(short) < len(s) {
+			a.Value = slog.StringValue(short)
+			return a, t
+		}
+		return a, truncated
+	case slog.KindGroup:
+		group := a.Value.Group()
+		attrs := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			attrs[i], truncated = truncateAttr(ga, limit, truncated)
+		}
+		a.Value = slog.GroupValue(attrs...)
+		return a, truncated
+	default:
+		return a, truncated
+	}
+}
+
+// truncateString cuts s to at most limit bytes without splitting a UTF-8
+// encoded rune. The returned bool is true if s was cut or truncated was
+// already true.
+func truncateString(s string, limit int, truncated bool) (string, bool) {
+	if limit <= 0 || len(s) <= limit {
+		return s, truncated
+	}
+	end := limit
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end], true
+}
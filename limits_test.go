@@ -0,0 +1,61 @@
+package httplog_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithLimits(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithLimits(httplog.Limits{
+		Path:      8,
+		Query:     4,
+		UserAgent: 2,
+		Field:     3,
+	}), httplog.WithBaggage("team"), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/"+strings.Repeat("a", 100)+"?q="+strings.Repeat("b", 100), nil)
+	r.Header.Set("User-Agent", "héllo world")
+	r.Header.Set(httplog.BaggageHeader, "team=payments")
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	if rec.Path != "/aaaaaaa" {
+		t.Errorf("expected truncated path got %q", rec.Path)
+	}
+	if rec.Query != "q=bb" {
+		t.Errorf("expected truncated query got %q", rec.Query)
+	}
+	if rec.UserAgent != "h" {
+		t.Errorf("expected user agent truncated on a rune boundary got %q", rec.UserAgent)
+	}
+	fields := recordFields(rec)
+	if baggage, ok := fields["baggage"].([]slog.Attr); !ok || baggage[0].Value.String() != "pay" {
+		t.Errorf("expected truncated baggage got %v", fields["baggage"])
+	}
+	if fields["truncated"] != true {
+		t.Errorf("expected truncated to be true got %v", fields["truncated"])
+	}
+}
+
+func TestWithLimits_short(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithLimits(httplog.DefaultLimits), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users?id=1", nil))
+
+	if rec.Path != "/users" || rec.Query != "id=1" {
+		t.Errorf("expected unchanged path and query got %q and %q", rec.Path, rec.Query)
+	}
+	if _, ok := recordFields(rec)["truncated"]; ok {
+		t.Errorf("expected no truncated field")
+	}
+}
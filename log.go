@@ -1,66 +1,479 @@
 package httplog
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
 //bzzzzz
 
-func JSON(outLogger, errLogger *log.Logger) func(req *http.Request, elapsed time.Duration, status int) {
+// StatusClientClosedRequest is the non-standard status logged when the client
+// goes away before the handler writes a response. It follows the nginx
+// convention.
+const StatusClientClosedRequest = 499
+
+// JSON returns a Func writing each request as a JSON line to outLogger, and
+// to errLogger for a status of 500 or more. Called by Wrap it writes every
+// field of the Record; called directly it writes the fields it can take
+// from req.
+func JSON(outLogger, errLogger *log.Logger, options ...FormatOption) Func {
+	write := jsonLogger(outLogger, errLogger, options)
 	return func(req *http.Request, elapsed time.Duration, status int) {
-		if status >= 500 {
-			errLogger.Printf(`{"type": "HTTP_REQUEST", "method": %q, "path": %q, "duration": %q, "status": %d}`+"\n", req.Method, req.URL.Path, elapsed, status)
+		write(recordFor(req, elapsed, status))
+	}
+}
+
+func jsonLogger(outLogger, errLogger *log.Logger, options []FormatOption) RecordFunc {
+	f := newFormat(options)
+	return func(rec Record) {
+		line := string(f.appendJSON(nil, rec)) + "\n"
+		if rec.Status >= 500 {
+			if err := errLogger.Output(2, line); err != nil {
+				stats.writeErrors.Add(1)
+			}
+		}
+		if err := outLogger.Output(2, line); err != nil {
+			stats.writeErrors.Add(1)
 		}
-		outLogger.Printf(`{"type": "HTTP_REQUEST", "method": %q, "path": %q, "duration": %q, "status": %d}`+"\n", req.Method, req.URL.Path, elapsed, status)
 	}
 }
 
+// JSONWriter is like JSON but writes lines to out, and to errOut for a
+// status of 500 or more, without building log.Loggers first. errOut may be
+// nil. Writes to each writer are serialized.
+func JSONWriter(out, errOut io.Writer, options ...FormatOption) RecordFunc {
+	f := newFormat(options)
+	var outMu, errMu sync.Mutex
+	write := func(mu *sync.Mutex, w io.Writer, line []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := w.Write(line); err != nil {
+			stats.writeErrors.Add(1)
+		}
+	}
+	return func(rec Record) {
+		line := append(f.appendJSON(nil, rec), '\n')
+		if rec.Status >= 500 && errOut != nil {
+			write(&errMu, errOut, line)
+		}
+		write(&outMu, out, line)
+	}
+}
+
+// Func receives the request, duration and status of each request handled
+// by Wrap. Unlike a RecordFunc it is not called with records of other types.
 type Func func(req *http.Request, elapsed time.Duration, status int)
 
 // logRecord has a response writer and a status code
 type logRecord struct {
 	http.ResponseWriter
 	status int
+
+	readDeadline, writeDeadline time.Time
+	fullDuplex                  bool
+
+	// firstByte and lastByte are when the first write started and the last
+	// write or flush returned; writeTime is the time spent in them.
+	firstByte, lastByte time.Time
+	writeTime           time.Duration
+	written             int64
+
+	interim  []int
+	hijacked bool
+	warnings []string
+
+	// writeErr is the first error returned by a write or flush.
+	writeErr error
+
+	req        *http.Request
+	writeHooks []ResponseWriteHook
 }
 
 func (r *logRecord) Write(p []byte) (int, error) {
-	return r.ResponseWriter.Write(p)
+	if r.hijacked {
+		r.warn("write_after_hijack")
+	}
+	if r.status == 0 && !r.hijacked {
+		r.status = http.StatusOK
+		r.callWriteHooks()
+	}
+	begin := r.beginWrite()
+	n, err := r.ResponseWriter.Write(p)
+	r.endWrite(begin)
+	r.written += int64(n)
+	r.setWriteErr(err)
+	return n, err
+}
+
+func (r *logRecord) beginWrite() time.Time {
+	now := timeNow()
+	if r.firstByte.IsZero() {
+		r.firstByte = now
+	}
+	return now
+}
+
+func (r *logRecord) endWrite(begin time.Time) {
+	r.lastByte = timeNow()
+	r.writeTime += r.lastByte.Sub(begin)
 }
 
 // WriteHeader implements ResponseWriter for logRecord
 func (r *logRecord) WriteHeader(status int) {
-	r.status = status
+	if status >= 100 && status <= 199 && status != http.StatusSwitchingProtocols {
+		// Interim responses, such as 103 Early Hints, precede the final
+		// status which is the one logged.
+		r.interim = append(r.interim, status)
+		r.ResponseWriter.WriteHeader(status)
+		return
+	}
+	switch {
+	case r.hijacked:
+		r.warn("write_header_after_hijack")
+	case r.status != 0:
+		// net/http ignores the status of later calls so keep the first.
+		r.warn("superfluous_write_header")
+	default:
+		r.status = status
+		r.callWriteHooks()
+	}
 	r.ResponseWriter.WriteHeader(status)
 }
 
-func Wrap(f http.Handler, logFns ...Func) http.HandlerFunc {
-	outLogger := log.New(os.Stdout, "", 0)
-	errLogger := log.New(os.Stderr, "", 0)
+func (r *logRecord) setWriteErr(err error) {
+	if err != nil && r.writeErr == nil {
+		r.writeErr = err
+	}
+}
+
+// warn records a misuse of the ResponseWriter by the handler, which
+// net/http only reports to its own logger. The kinds are logged in the
+// warning field, separated by commas.
+func (r *logRecord) warn(warning string) {
+	for _, w := range r.warnings {
+		if w == warning {
+			return
+		}
+	}
+	r.warnings = append(r.warnings, warning)
+}
+
+func (r *logRecord) callWriteHooks() {
+	for _, hook := range r.writeHooks {
+		hook.OnResponseWrite(r.req, r.status, r.ResponseWriter.Header())
+	}
+}
+
+// findLogRecord walks the Unwrap chain of w for a logRecord of an outer
+// Wrap.
+func findLogRecord(w http.ResponseWriter) *logRecord {
+	for {
+		switch v := w.(type) {
+		case *logRecord:
+			return v
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = v.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter so http.ResponseController
+// can reach optional interfaces logRecord does not implement itself.
+func (r *logRecord) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Flush implements http.Flusher for handlers that type assert for it.
+func (r *logRecord) Flush() {
+	_ = r.FlushError()
+}
+
+// FlushError is called by http.ResponseController.
+func (r *logRecord) FlushError() error {
+	if r.status == 0 && !r.hijacked {
+		// Flushing sends the header with an implicit 200 like Write.
+		r.status = http.StatusOK
+		r.callWriteHooks()
+	}
+	begin := r.beginWrite()
+	err := http.NewResponseController(r.ResponseWriter).Flush()
+	r.endWrite(begin)
+	if !errors.Is(err, http.ErrNotSupported) {
+		r.setWriteErr(err)
+	}
+	return err
+}
+
+// Hijack implements http.Hijacker for handlers that type assert for it.
+func (r *logRecord) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil {
+		r.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// SetReadDeadline is called by http.ResponseController. It records the
+// deadline before passing it on.
+func (r *logRecord) SetReadDeadline(deadline time.Time) error {
+	err := http.NewResponseController(r.ResponseWriter).SetReadDeadline(deadline)
+	if err == nil {
+		r.readDeadline = deadline
+	}
+	return err
+}
+
+// SetWriteDeadline is called by http.ResponseController. It records the
+// deadline before passing it on.
+func (r *logRecord) SetWriteDeadline(deadline time.Time) error {
+	err := http.NewResponseController(r.ResponseWriter).SetWriteDeadline(deadline)
+	if err == nil {
+		r.writeDeadline = deadline
+	}
+	return err
+}
+
+// EnableFullDuplex is called by http.ResponseController.
+func (r *logRecord) EnableFullDuplex() error {
+	err := http.NewResponseController(r.ResponseWriter).EnableFullDuplex()
+	if err == nil {
+		r.fullDuplex = true
+	}
+	return err
+}
 
-	var fn Func
-	if len(logFns) == 0 {
-		fn = JSON(outLogger, errLogger)
-	} else if len(logFns) == 1 {
-		fn = logFns[0]
-	} else {
-		fn = func(req *http.Request, elapsed time.Duration, status int) {
-			for _, lg := range logFns {
-				lg(req, elapsed, status)
+// recordFunc returns the function records are passed to: the configured
+// funcs, or JSON lines on stdout and stderr when there are none.
+func (c *config) recordFunc() RecordFunc {
+	switch len(c.funcs) {
+	case 0:
+		outLogger := log.New(os.Stdout, "", 0)
+		errLogger := log.New(os.Stderr, "", 0)
+		return jsonLogger(outLogger, errLogger, nil)
+	case 1:
+		return c.funcs[0]
+	default:
+		return func(rec Record) {
+			for _, lg := range c.funcs {
+				lg(rec)
 			}
 		}
 	}
+}
+
+// isClientAbort reports whether err, returned by a write, means the client
+// closed the connection or canceled the request.
+func isClientAbort(r *http.Request, err error) bool {
+	return errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(r.Context().Err(), context.Canceled)
+}
+
+func Wrap(f http.Handler, options ...Option) http.HandlerFunc {
+	return newConfig(options).wrap(f)
+}
+
+// newConfig applies options to a new config.
+func newConfig(options []Option) *config {
+	c := new(config)
+	for _, o := range options {
+		o.apply(c)
+	}
+	return c
+}
+
+// wrap is Wrap for an already configured c.
+func (c *config) wrap(f http.Handler) http.HandlerFunc {
+	fn := c.recordFunc()
 	//it's a func!
 	return func(w http.ResponseWriter, r *http.Request) {
-		record := &logRecord{
-			ResponseWriter: w,
+		begin := time.Now()
+		info := newRequestInfo(r)
+		if c.normalize != nil {
+			info.Route = c.normalize(info.Path)
+		}
+		if c.anonymize != nil {
+			info.ClientIP = c.anonymize(info.ClientIP)
+		}
+		if c.logger != nil {
+			info.Logger = c.requestLogger(info, r)
+		}
+		info.errorStatus = c.errorStatus
+		r = r.WithContext(withRequestInfo(r.Context(), info))
+		for _, hook := range c.startHooks {
+			r = hook.OnRequestStart(r)
+		}
+		var extractors []func(rec *Record)
+		for _, inspect := range c.inspectors {
+			if extract := inspect(r); extract != nil {
+				extractors = append(extractors, extract)
+			}
+		}
+		var captures []func(rec Record)
+		for _, capture := range c.captures {
+			if emit := capture(r); emit != nil {
+				captures = append(captures, emit)
+			}
+		}
+		var record *logRecord
+		outer := findLogRecord(w)
+		if outer != nil {
+			// w already passes through an outer Wrap. Share its status
+			// instead of wrapping again, so both record the same status and
+			// middleware between the two keeps seeing its own writer.
+			record = outer
+			hooks := outer.writeHooks
+			outer.writeHooks = append(hooks[:len(hooks):len(hooks)], c.writeHooks...)
+			defer func() { outer.writeHooks = hooks }()
+		} else {
+			record = &logRecord{
+				ResponseWriter: w,
+				req:            r,
+				writeHooks:     c.writeHooks,
+			}
+			w = record
+		}
+
+		start := timeNow()
+		deadline, hasDeadline := r.Context().Deadline()
+		handlerStart := time.Now()
+		var recovered *recoveredPanic
+		if c.recoverPanics {
+			recovered = serveRecover(f, w, r, info, c.pprofLabels)
+			if recovered != nil && recovered.abort() {
+				defer panic(recovered.value)
+			}
+		} else if c.pprofLabels {
+			serveWithLabels(f, w, r, info)
+		} else {
+			f.ServeHTTP(w, r)
 		}
 
-		start := time.Now()
-		f.ServeHTTP(record, r)
+		handlerEnd := time.Now()
+		if recovered != nil && record.status == 0 && !recovered.abort() {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		clientCanceled := false
+		status := record.status
+		if status == 0 {
+			if errors.Is(r.Context().Err(), context.Canceled) {
+				status = StatusClientClosedRequest
+				clientCanceled = true
+			} else {
+				status = http.StatusOK
+			}
+			if outer == nil {
+				// Only the Wrap owning the writer settles the status; an
+				// outer handler may still write it after an inner Wrap.
+				record.status = status
+			}
+		}
 
-		fn(r, time.Since(start), record.status)
+		rec := Record{
+			Request:        r,
+			ResponseHeader: w.Header(),
+			Time:           start,
+			Method:         r.Method,
+			Host:           r.Host,
+			Path:           r.URL.Path,
+			Route:          info.Route,
+			Query:          r.URL.RawQuery,
+			UserAgent:      r.UserAgent(),
+			ClientIP:       info.ClientIP,
+			Status:         status,
+			Duration:       timeSince(start),
+			RequestID:      info.ID,
+			TraceID:        info.Trace.TraceID,
+			writer:         record,
+		}
+		if record.writeErr != nil {
+			// A write fails when the client has gone away, unless a handler
+			// wrote past http.TimeoutHandler or another writer that fails
+			// writes itself.
+			clientCanceled = clientCanceled || isClientAbort(r, record.writeErr)
+			rec.Attrs = append(rec.Attrs, slog.String("write_error", record.writeErr.Error()))
+		}
+		if clientCanceled {
+			rec.Attrs = append(rec.Attrs, slog.Bool("client_canceled", true))
+		}
+		if recovered != nil {
+			rec.Attrs = append(rec.Attrs, recovered.attrs(c.stackEnabled == nil || c.stackEnabled())...)
+		}
+		if len(record.warnings) > 0 {
+			rec.Attrs = append(rec.Attrs, slog.String("warning", strings.Join(record.warnings, ",")))
+		}
+		if hasDeadline {
+			rec.Attrs = append(rec.Attrs,
+				slog.Duration("deadline_budget", deadline.Sub(start)),
+				slog.Bool("deadline_exceeded", errors.Is(r.Context().Err(), context.DeadlineExceeded)),
+			)
+		}
+		if clientID := info.getClientID(); clientID != "" {
+			rec.Attrs = append(rec.Attrs, slog.String("client_id", clientID))
+		}
+		if err := info.getError(); err != nil {
+			rec.Attrs = append(rec.Attrs, errorAttrs(err)...)
+		}
+		if costs := info.getCosts(); len(costs) > 0 {
+			rec.Attrs = append(rec.Attrs, costAttr(costs))
+		}
+		rec.Attrs = append(rec.Attrs, info.getAttrs()...)
+		if isPreflight(r) {
+			rec.Attrs = append(rec.Attrs, slog.Bool("preflight", true))
+		}
+		for _, extract := range extractors {
+			extract(&rec)
+		}
+		for _, extract := range c.extractors {
+			extract(&rec)
+		}
+		sanitizeRecord(&rec)
+		c.limits.truncate(&rec)
+		for _, finalize := range c.finalizers {
+			finalize(&rec)
+		}
+		if recovered != nil && !recovered.abort() {
+			for _, hook := range c.panicHooks {
+				hook.OnPanic(rec, recovered.value, recovered.stack)
+			}
+		}
+		for _, hook := range c.completeHooks {
+			hook.OnComplete(rec)
+		}
+		for _, keep := range c.filters {
+			if !keep(&rec) {
+				stats.filtered.Add(1)
+				for _, drop := range c.dropped {
+					drop(&rec)
+				}
+				stats.overheadNanos.Add(uint64(handlerStart.Sub(begin) + time.Since(handlerEnd)))
+				return
+			}
+		}
+		overhead := handlerStart.Sub(begin) + time.Since(handlerEnd)
+		stats.overheadNanos.Add(uint64(overhead))
+		if c.overheadField {
+			rec.Attrs = append(rec.Attrs, slog.Duration("httplog_overhead", overhead))
+		}
+		for _, emit := range captures {
+			emit(rec)
+		}
+		stats.emitted.Add(1)
+		emitStart := time.Now()
+		fn(rec)
+		stats.emitNanos.Add(uint64(time.Since(emitStart)))
 	}
 }
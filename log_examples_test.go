@@ -1,10 +1,18 @@
 package httplog_test
 
 import (
+	"bytes"
+	"context"
 	"io"
+	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/crhntr/httplog"
 )
@@ -36,3 +44,165 @@ func TestWrap(t *testing.T) {
 	r := httptest.NewRequest(http.MethodGet, "/", nil)
 	logMux.ServeHTTP(w, r)
 }
+
+func TestWrap_implicitStatus(t *testing.T) {
+	for _, tt := range []struct {
+		Name    string
+		Handler http.HandlerFunc
+	}{
+		{Name: "write", Handler: func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, "Hello, world!")
+		}},
+		{Name: "no write", Handler: func(w http.ResponseWriter, r *http.Request) {}},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var rec httplog.Record
+			logMux := httplog.Wrap(tt.Handler, httplog.RecordFunc(func(r httplog.Record) {
+				rec = r
+			}))
+
+			logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Status != http.StatusOK {
+				t.Errorf("expected status %d got %d", http.StatusOK, rec.Status)
+			}
+		})
+	}
+}
+
+func TestWrap_clientCanceled(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+	if rec.Status != httplog.StatusClientClosedRequest {
+		t.Errorf("expected status %d got %d", httplog.StatusClientClosedRequest, rec.Status)
+	}
+	if len(rec.Attrs) != 1 || rec.Attrs[0].Key != "client_canceled" || !rec.Attrs[0].Value.Bool() {
+		t.Errorf("expected client_canceled field got %v", rec.Attrs)
+	}
+}
+
+func TestWrap_canceledAfterWrite(t *testing.T) {
+	var rec httplog.Record
+	ctx, cancel := context.WithCancel(context.Background())
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		cancel()
+	}), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+	if rec.Status != http.StatusAccepted {
+		t.Errorf("expected status %d got %d", http.StatusAccepted, rec.Status)
+	}
+	if len(rec.Attrs) != 0 {
+		t.Errorf("expected no fields got %v", rec.Attrs)
+	}
+}
+
+func TestWrap_deadline(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+	fields := map[string]any{}
+	for _, a := range rec.Attrs {
+		fields[a.Key] = a.Value.Any()
+	}
+	if budget, ok := fields["deadline_budget"].(time.Duration); !ok || budget <= 0 || budget > 10*time.Millisecond {
+		t.Errorf("expected deadline_budget up to 10ms got %v", fields["deadline_budget"])
+	}
+	if fields["deadline_exceeded"] != true {
+		t.Errorf("expected deadline_exceeded to be true got %v", fields["deadline_exceeded"])
+	}
+}
+
+func TestWrap_noDeadline(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(rec.Attrs) != 0 {
+		t.Errorf("expected no fields got %v", rec.Attrs)
+	}
+}
+
+type errorWriter struct {
+	http.ResponseWriter
+	err error
+}
+
+func (w errorWriter) Write([]byte) (int, error) { return 0, w.err }
+
+func TestWrap_writeError(t *testing.T) {
+	for _, tt := range []struct {
+		name           string
+		err            error
+		clientCanceled bool
+	}{
+		{"broken pipe", &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)}, true},
+		{"connection reset", &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.ECONNRESET)}, true},
+		{"handler timeout", http.ErrHandlerTimeout, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var rec httplog.Record
+			h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("hello"))
+				_, _ = w.Write([]byte("world"))
+			}), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+			h.ServeHTTP(errorWriter{ResponseWriter: httptest.NewRecorder(), err: tt.err}, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			fields := recordFields(rec)
+			if got := fields["write_error"]; got != tt.err.Error() {
+				t.Errorf("expected write_error %q got %v", tt.err, got)
+			}
+			if _, got := fields["client_canceled"]; got != tt.clientCanceled {
+				t.Errorf("expected client_canceled %t got %v", tt.clientCanceled, fields)
+			}
+			if rec.Status != http.StatusOK {
+				t.Errorf("expected status %d got %d", http.StatusOK, rec.Status)
+			}
+		})
+	}
+}
+
+func TestWrap_funcOptions(t *testing.T) {
+	var out bytes.Buffer
+	var jsonFunc httplog.Func = httplog.JSON(log.New(&out, "", 0), log.New(io.Discard, "", 0))
+	var statuses []int
+	logMux := httplog.Wrap(http.NotFoundHandler(),
+		httplog.Func(func(req *http.Request, elapsed time.Duration, status int) { statuses = append(statuses, status) }),
+		httplog.RecordFunc(func(rec httplog.Record) { statuses = append(statuses, rec.Status) }),
+		jsonFunc,
+		httplog.WithServiceInfo("billing", "", ""),
+	)
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(statuses) != 2 || statuses[0] != http.StatusNotFound || statuses[1] != http.StatusNotFound {
+		t.Errorf("expected both functions to be called got %v", statuses)
+	}
+	if got := out.String(); !strings.Contains(got, `"host": "example.com"`) || !strings.Contains(got, `"service": "billing"`) {
+		t.Errorf("expected JSON to write the full record got %q", got)
+	}
+}
@@ -0,0 +1,223 @@
+package httplog
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"sync"
+)
+
+// MessagePack returns a RecordFunc that writes records to w as MessagePack
+// maps, one after another. Failed writes are counted in Stats.WriteErrors.
+func MessagePack(w io.Writer) RecordFunc {
+	var mu sync.Mutex
+	return func(rec Record) {
+		b := AppendMessagePack(nil, rec)
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := w.Write(b); err != nil {
+			stats.writeErrors.Add(1)
+		}
+	}
+}
+
+// AppendMessagePack appends rec to b as a MessagePack map. Keys match the
+// JSON output: time and duration are integers of nanoseconds, since the Unix
+// epoch for time, and attrs are added as keys with groups as nested maps.
+// Records of other types than TypeRequest only have type, time and attrs.
+func AppendMessagePack(b []byte, rec Record) []byte {
+	var m msgpackMap
+	if rec.Type != "" && rec.Type != TypeRequest {
+		m.str("type", rec.Type)
+		if !rec.Time.IsZero() {
+			m.key("time")
+			m.b = msgpackInt(m.b, rec.Time.UnixNano())
+		}
+		m.attrs(rec.Attrs)
+		return m.appendTo(b)
+	}
+	if !rec.Time.IsZero() {
+		m.key("time")
+		m.b = msgpackInt(m.b, rec.Time.UnixNano())
+	}
+	m.str("method", rec.Method)
+	m.optionalStr("host", rec.Host)
+	m.str("path", rec.Path)
+	m.optionalStr("route", rec.Route)
+	m.optionalStr("query", rec.Query)
+	m.key("duration")
+	m.b = msgpackInt(m.b, int64(rec.Duration))
+	m.key("status")
+	m.b = msgpackInt(m.b, int64(rec.Status))
+	m.optionalStr("request_id", rec.RequestID)
+	m.optionalStr("trace_id", rec.TraceID)
+	m.optionalStr("client_ip", rec.ClientIP)
+	m.optionalStr("user_agent", rec.UserAgent)
+	m.attrs(rec.Attrs)
+	return m.appendTo(b)
+}
+
+// msgpackMap collects encoded map entries so the map header, which holds the
+// number of entries, can be written first.
+type msgpackMap struct {
+	b []byte
+	n int
+}
+
+func (m *msgpackMap) key(k string) {
+	m.n++
+	m.b = msgpackString(m.b, k)
+}
+
+func (m *msgpackMap) str(k, v string) {
+	m.key(k)
+	m.b = msgpackString(m.b, v)
+}
+
+func (m *msgpackMap) optionalStr(k, v string) {
+	if v != "" {
+		m.str(k, v)
+	}
+}
+
+func (m *msgpackMap) attrs(attrs []slog.Attr) {
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Equal(slog.Attr{}) {
+			continue
+		}
+		if a.Value.Kind() == slog.KindGroup {
+			if a.Key == "" {
+				m.attrs(a.Value.Group())
+				continue
+			}
+			var group msgpackMap
+			group.attrs(a.Value.Group())
+			if group.n == 0 {
+				continue
+			}
+			m.key(a.Key)
+			m.b = group.appendTo(m.b)
+			continue
+		}
+		m.key(a.Key)
+		m.b = appendMessagePackValue(m.b, a.Value)
+	}
+}
+
+func (m *msgpackMap) appendTo(b []byte) []byte {
+	switch {
+	case m.n < 16:
+		b = append(b, 0x80|byte(m.n))
+	case m.n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xde), uint16(m.n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(m.n))
+	}
+	return append(b, m.b...)
+}
+
+func appendMessagePackValue(b []byte, v slog.Value) []byte {
+	switch v.Kind() {
+	case slog.KindString:
+		return msgpackString(b, v.String())
+	case slog.KindInt64:
+		return msgpackInt(b, v.Int64())
+	case slog.KindUint64:
+		if n := v.Uint64(); n <= math.MaxInt64 {
+			return msgpackInt(b, int64(n))
+		}
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), v.Uint64())
+	case slog.KindFloat64:
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v.Float64()))
+	case slog.KindBool:
+		if v.Bool() {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case slog.KindDuration:
+		return msgpackInt(b, int64(v.Duration()))
+	case slog.KindTime:
+		return msgpackInt(b, v.Time().UnixNano())
+	default:
+		if v.Any() == nil {
+			return append(b, 0xc0)
+		}
+		if err, ok := v.Any().(error); ok {
+			return msgpackString(b, err.Error())
+		}
+		if structured, ok := structuredValue(v.Any()); ok {
+			return appendMessagePackStructured(b, structured)
+		}
+		return msgpackString(b, fmt.Sprint(v.Any()))
+	}
+}
+
+// appendMessagePackStructured appends a value returned by structuredValue
+// as the MessagePack nil, bool, number, string, array or map it holds.
+func appendMessagePackStructured(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case bool:
+		return appendMessagePackValue(b, slog.BoolValue(v))
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return msgpackInt(b, n)
+		}
+		f, _ := v.Float64()
+		return appendMessagePackValue(b, slog.Float64Value(f))
+	case string:
+		return msgpackString(b, v)
+	case []any:
+		switch n := len(v); {
+		case n < 16:
+			b = append(b, 0x90|byte(n))
+		case n <= math.MaxUint16:
+			b = binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+		default:
+			b = binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+		}
+		for _, e := range v {
+			b = appendMessagePackStructured(b, e)
+		}
+		return b
+	case map[string]any:
+		var m msgpackMap
+		for _, key := range sortedKeys(v) {
+			m.key(key)
+			m.b = appendMessagePackStructured(m.b, v[key])
+		}
+		return m.appendTo(b)
+	default:
+		return append(b, 0xc0)
+	}
+}
+
+func msgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+func msgpackInt(b []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n < 128:
+		return append(b, byte(n))
+	case n < 0 && n >= -32:
+		return append(b, byte(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(n))
+	}
+}
@@ -0,0 +1,66 @@
+package httplog_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestAppendMessagePack(t *testing.T) {
+	got := httplog.AppendMessagePack(nil, httplog.Record{
+		Method:   http.MethodGet,
+		Path:     "/",
+		Status:   http.StatusOK,
+		Duration: time.Millisecond,
+		Attrs: []slog.Attr{
+			slog.Group("upstream", slog.Bool("reused", true)),
+			slog.Group("empty"),
+		},
+	})
+	want := []byte{
+		0x85,
+		0xa6, 'm', 'e', 't', 'h', 'o', 'd', 0xa3, 'G', 'E', 'T',
+		0xa4, 'p', 'a', 't', 'h', 0xa1, '/',
+		0xa8, 'd', 'u', 'r', 'a', 't', 'i', 'o', 'n', 0xd2, 0x00, 0x0f, 0x42, 0x40,
+		0xa6, 's', 't', 'a', 't', 'u', 's', 0xd2, 0x00, 0x00, 0x00, 0xc8,
+		0xa8, 'u', 'p', 's', 't', 'r', 'e', 'a', 'm', 0x81, 0xa6, 'r', 'e', 'u', 's', 'e', 'd', 0xc3,
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("expected\n%x\ngot\n%x", want, got)
+	}
+}
+
+func TestMessagePack(t *testing.T) {
+	var buf bytes.Buffer
+	fn := httplog.MessagePack(&buf)
+	rec := httplog.Record{Method: http.MethodGet, Path: "/", Status: http.StatusOK}
+	fn(rec)
+	fn(rec)
+
+	one := httplog.AppendMessagePack(nil, rec)
+	if !bytes.Equal(buf.Bytes(), append(one, one...)) {
+		t.Errorf("expected two concatenated maps got %x", buf.Bytes())
+	}
+}
+
+func TestAppendMessagePack_structured(t *testing.T) {
+	got := httplog.AppendMessagePack(nil, httplog.Record{
+		Type:  httplog.TypeServer,
+		Attrs: []slog.Attr{slog.Any("stack", []httplog.StackFrame{{Function: "main.f", Line: 12}})},
+	})
+	want := []byte{
+		0x82,
+		0xa4, 't', 'y', 'p', 'e', 0xac, 'S', 'E', 'R', 'V', 'E', 'R', '_', 'E', 'R', 'R', 'O', 'R',
+		0xa5, 's', 't', 'a', 'c', 'k', 0x91, 0x83,
+		0xa4, 'f', 'i', 'l', 'e', 0xa0,
+		0xa8, 'f', 'u', 'n', 'c', 't', 'i', 'o', 'n', 0xa6, 'm', 'a', 'i', 'n', '.', 'f',
+		0xa4, 'l', 'i', 'n', 'e', 0x0c,
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("expected\n%x\ngot\n%x", want, got)
+	}
+}
@@ -0,0 +1,100 @@
+package httplog
+
+import (
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+)
+
+// maxMultipartFieldNames bounds the field names logged for a single request.
+const maxMultipartFieldNames = 64
+
+// WithMultipartSummary adds a "multipart" group to the records of
+// multipart/form-data requests holding the number of parts, the distinct
+// field names, and the size in bytes of the upload. Part contents are never
+// logged.
+//
+// The body is parsed as the handler reads it so nothing is buffered; only
+// the portion the handler actually read is summarized.
+func WithMultipartSummary() Option {
+	return optionFunc(func(c *config) {
+		c.inspectors = append(c.inspectors, func(req *http.Request) func(rec *Record) {
+			if req.Body == nil || req.Body == http.NoBody {
+				return nil
+			}
+			mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+			if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+				return nil
+			}
+			s := newMultipartSummary(req, params["boundary"])
+			return func(rec *Record) {
+				rec.Attrs = append(rec.Attrs, s.attr())
+			}
+		})
+	})
+}
+
+type multipartSummary struct {
+	pw   *io.PipeWriter
+	size int64
+	done chan struct{}
+
+	parts  int
+	fields []string
+}
+
+func newMultipartSummary(req *http.Request, boundary string) *multipartSummary {
+	pr, pw := io.Pipe()
+	s := &multipartSummary{pw: pw, done: make(chan struct{})}
+	go s.parse(pr, boundary)
+	body := req.Body
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.TeeReader(&countingReader{r: body, n: &s.size}, pw),
+		Closer: body,
+	}
+	return s
+}
+
+func (s *multipartSummary) parse(r *io.PipeReader, boundary string) {
+	defer close(s.done)
+	defer func() { _, _ = io.Copy(io.Discard, r) }()
+	mr := multipart.NewReader(r, boundary)
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return
+		}
+		s.parts++
+		if name := part.FormName(); name != "" && len(s.fields) < maxMultipartFieldNames && !containsString(s.fields, name) {
+			s.fields = append(s.fields, name)
+		}
+		_ = part.Close()
+	}
+}
+
+func (s *multipartSummary) attr() slog.Attr {
+	_ = s.pw.Close()
+	<-s.done
+	return slog.Group("multipart",
+		slog.Int("parts", s.parts),
+		slog.Any("fields", s.fields),
+		slog.Int64("size", s.size),
+	)
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.n += int64(n)
+	return n, err
+}
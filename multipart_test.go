@@ -0,0 +1,56 @@
+package httplog_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithMultipartSummary(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("title", "holiday")
+	fw, _ := mw.CreateFormFile("photo", "beach.jpg")
+	_, _ = fw.Write(bytes.Repeat([]byte{0xff}, 1024))
+	fw, _ = mw.CreateFormFile("photo", "sunset.jpg")
+	_, _ = fw.Write(bytes.Repeat([]byte{0xff}, 1024))
+	_ = mw.Close()
+	size := int64(body.Len())
+
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+		if got := len(r.MultipartForm.File["photo"]); got != 2 {
+			t.Errorf("expected the handler to receive 2 photos got %d", got)
+		}
+	}), httplog.WithMultipartSummary(), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	if len(rec.Attrs) != 1 || rec.Attrs[0].Key != "multipart" {
+		t.Fatalf("expected a multipart group got %v", rec.Attrs)
+	}
+	fields := map[string]any{}
+	for _, a := range rec.Attrs[0].Value.Group() {
+		fields[a.Key] = a.Value.Any()
+	}
+	if fields["parts"] != int64(3) {
+		t.Errorf("expected 3 parts got %v", fields["parts"])
+	}
+	if names, ok := fields["fields"].([]string); !ok || len(names) != 2 || names[0] != "title" || names[1] != "photo" {
+		t.Errorf("expected fields title and photo got %v", fields["fields"])
+	}
+	if fields["size"] != size {
+		t.Errorf("expected size %d got %v", size, fields["size"])
+	}
+}
@@ -0,0 +1,166 @@
+package httplog
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Framing selects how NetWriter delimits records on a stream connection.
+type Framing int
+
+const (
+	// FramingNewline ends each record with a newline, like the JSON lines of
+	// JSON. It suits the newline codec of Logstash and Vector TCP inputs.
+	FramingNewline Framing = iota
+	// FramingLengthPrefix precedes each record with its length as a 4 byte
+	// big endian integer, like the length_delimited framing of Vector.
+	FramingLengthPrefix
+)
+
+// Timeouts and reconnect backoff of NetWriter.
+const (
+	netDialTimeout  = 5 * time.Second
+	netWriteTimeout = 5 * time.Second
+	netMaxBackoff   = 30 * time.Second
+)
+
+// NetWriter sends the JSON objects of JSON to a network address, such as
+// the TCP input of Logstash or Vector. It is created with NewNetWriter. A
+// NetWriter is an Option so it can be passed to Wrap directly.
+//
+// The connection is dialed on the first record and redialed after a write
+// fails, waiting up to 30 seconds between attempts while the address is
+// unreachable. Writes happen on the goroutine of the request, so wrap the
+// NetWriter with Async to keep a slow collector from adding latency.
+type NetWriter struct {
+	network, address string
+	framing          Framing
+	format           format
+
+	mu       sync.Mutex
+	conn     net.Conn
+	closed   bool
+	err      error
+	retry    time.Time
+	backoff  time.Duration
+	datagram bool
+}
+
+// NewNetWriter returns a NetWriter sending to address on network, which is
+// one of the networks of net.Dial such as "tcp" or "udp". Each record is
+// a single datagram on datagram networks and framing only matters on
+// stream networks.
+//
+// Node local collectors such as Vector and Fluent Bit usually listen on a
+// unix socket, which is used with the network "unix" for a stream socket or
+// "unixgram" for a datagram socket and the path of the socket as address:
+//
+//	httplog.NewNetWriter("unixgram", "/run/vector/http.sock", httplog.FramingNewline)
+func NewNetWriter(network, address string, framing Framing, options ...FormatOption) *NetWriter {
+	datagram := false
+	switch network {
+	case "udp", "udp4", "udp6", "unixgram", "unixpacket":
+		datagram = true
+	}
+	return &NetWriter{
+		network:  network,
+		address:  address,
+		framing:  framing,
+		format:   newFormat(options),
+		datagram: datagram,
+	}
+}
+
+// Log sends rec. Records that cannot be sent, including those logged while
+// waiting to reconnect, are counted in Stats.WriteErrors.
+func (w *NetWriter) Log(rec Record) {
+	b := w.frame(rec)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.write(b); err != nil {
+		stats.writeErrors.Add(1)
+	}
+}
+
+func (w *NetWriter) apply(c *config) { RecordFunc(w.Log).apply(c) }
+
+func (w *NetWriter) frame(rec Record) []byte {
+	if w.datagram || w.framing == FramingNewline {
+		b := w.format.appendJSON(nil, rec)
+		if w.datagram {
+			return b
+		}
+		return append(b, '\n')
+	}
+	b := w.format.appendJSON(make([]byte, 4, 256), rec)
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	return b
+}
+
+// write sends b with w.mu held, dialing when there is no connection.
+func (w *NetWriter) write(b []byte) error {
+	if w.closed {
+		return errors.New("httplog: net writer closed")
+	}
+	if w.conn == nil {
+		if err := w.dial(); err != nil {
+			return err
+		}
+	}
+	_ = w.conn.SetWriteDeadline(time.Now().Add(netWriteTimeout))
+	if _, err := w.conn.Write(b); err != nil {
+		// The frame may have been partially written so the connection
+		// cannot be used for further frames.
+		_ = w.conn.Close()
+		w.conn, w.err = nil, err
+		w.retry = time.Time{}
+		return err
+	}
+	return nil
+}
+
+func (w *NetWriter) dial() error {
+	if time.Now().Before(w.retry) {
+		return w.err
+	}
+	conn, err := net.DialTimeout(w.network, w.address, netDialTimeout)
+	if err != nil {
+		w.backoff = min(max(2*w.backoff, 100*time.Millisecond), netMaxBackoff)
+		w.retry = time.Now().Add(w.backoff)
+		w.err = err
+		return err
+	}
+	w.conn, w.err, w.backoff = conn, nil, 0
+	return nil
+}
+
+// Close closes the connection.
+func (w *NetWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// Ping dials the address unless connected and reports an error when that
+// fails or the NetWriter is closed.
+func (w *NetWriter) Ping(context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return errors.New("httplog: net writer closed")
+	}
+	if w.conn != nil {
+		return nil
+	}
+	return w.dial()
+}
@@ -0,0 +1,216 @@
+package httplog_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestNetWriter_tcp(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		framing httplog.Framing
+		read    func(r *bufio.Reader) ([]byte, error)
+	}{
+		{"newline", httplog.FramingNewline, func(r *bufio.Reader) ([]byte, error) {
+			return r.ReadBytes('\n')
+		}},
+		{"length prefix", httplog.FramingLengthPrefix, func(r *bufio.Reader) ([]byte, error) {
+			var n uint32
+			if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+				return nil, err
+			}
+			b := make([]byte, n)
+			_, err := io.ReadFull(r, b)
+			return b, err
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+			paths := make(chan string, 2)
+			read := tt.read
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					frame, err := read(r)
+					if err != nil {
+						return
+					}
+					var line struct{ Path string }
+					if err := json.Unmarshal(frame, &line); err != nil {
+						t.Errorf("invalid frame %q: %s", frame, err)
+					}
+					paths <- line.Path
+				}
+			}()
+
+			w := httplog.NewNetWriter("tcp", ln.Addr().String(), tt.framing)
+			defer w.Close()
+			if err := w.Ping(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			w.Log(httplog.Record{Method: http.MethodGet, Path: "/a"})
+			w.Log(httplog.Record{Method: http.MethodGet, Path: "/b"})
+			for _, want := range []string{"/a", "/b"} {
+				if got := <-paths; got != want {
+					t.Errorf("expected %s got %s", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestNetWriter_reconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 100)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// Each connection is closed after its first line, like a
+			// restarting collector.
+			line, _ := bufio.NewReader(conn).ReadString('\n')
+			_ = conn.Close()
+			lines <- line
+		}
+	}()
+
+	w := httplog.NewNetWriter("tcp", ln.Addr().String(), httplog.FramingNewline)
+	defer w.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for received := 0; received < 2; {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected records on a new connection got %d", received)
+		}
+		w.Log(httplog.Record{Method: http.MethodGet, Path: "/"})
+		select {
+		case <-lines:
+			received++
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestNetWriter_udp(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w := httplog.NewNetWriter("udp", conn.LocalAddr().String(), httplog.FramingLengthPrefix)
+	defer w.Close()
+	w.Log(httplog.Record{Method: http.MethodGet, Path: "/a", Status: http.StatusOK})
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b := make([]byte, 64<<10)
+	n, _, err := conn.ReadFrom(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var line struct{ Path string }
+	if err := json.Unmarshal(b[:n], &line); err != nil || line.Path != "/a" {
+		t.Errorf("expected a datagram holding the record got %q", b[:n])
+	}
+}
+
+func TestNetWriter_unreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	w := httplog.NewNetWriter("tcp", addr, httplog.FramingNewline)
+	defer w.Close()
+	before := httplog.ReadStats().WriteErrors
+	w.Log(httplog.Record{})
+	w.Log(httplog.Record{})
+	if got := httplog.ReadStats().WriteErrors - before; got != 2 {
+		t.Errorf("expected 2 write errors got %d", got)
+	}
+	if err := w.Ping(context.Background()); err == nil {
+		t.Error("expected Ping to fail")
+	}
+}
+
+func TestNetWriter_unix(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("stream", func(t *testing.T) {
+		ln, err := net.Listen("unix", filepath.Join(dir, "stream.sock"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		lines := make(chan string, 1)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			line, _ := bufio.NewReader(conn).ReadString('\n')
+			lines <- line
+		}()
+
+		w := httplog.NewNetWriter("unix", ln.Addr().String(), httplog.FramingNewline)
+		defer w.Close()
+		w.Log(httplog.Record{Method: http.MethodGet, Path: "/a"})
+		if line := <-lines; !strings.Contains(line, `"path": "/a"`) {
+			t.Errorf("unexpected line %q", line)
+		}
+	})
+
+	t.Run("datagram", func(t *testing.T) {
+		conn, err := net.ListenPacket("unixgram", filepath.Join(dir, "datagram.sock"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		w := httplog.NewNetWriter("unixgram", conn.LocalAddr().String(), httplog.FramingNewline)
+		defer w.Close()
+		w.Log(httplog.Record{Method: http.MethodGet, Path: "/a"})
+		w.Log(httplog.Record{Method: http.MethodGet, Path: "/b"})
+
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for _, want := range []string{"/a", "/b"} {
+			b := make([]byte, 64<<10)
+			n, _, err := conn.ReadFrom(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var line struct{ Path string }
+			if err := json.Unmarshal(b[:n], &line); err != nil || line.Path != want {
+				t.Errorf("expected a datagram for %s got %q", want, b[:n])
+			}
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package httplog
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Option configures Wrap. Options are returned by the functions of this
+// package, such as WithContextLogger, or are sinks such as AsyncFunc. A
+// Func or RecordFunc is an Option that adds the function to those called
+// for each request; convert function literals to one of them:
+//
+//	httplog.Wrap(h, httplog.Func(func(req *http.Request, elapsed time.Duration, status int) {
+//		// ...
+//	}))
+type Option interface {
+	apply(*config)
+}
+
+type config struct {
+	funcs      []RecordFunc
+	extractors []func(rec *Record)
+	filters    []func(rec *Record) bool
+
+	// inspectors run before the handler and may replace the request body;
+	// the extractor they return, if any, runs with the other extractors.
+	inspectors []func(req *http.Request) func(rec *Record)
+	// captures run before the handler like inspectors; the func they
+	// return, if any, is called with the record once it passed the filters.
+	captures  []func(req *http.Request) func(rec Record)
+	logger    *slog.Logger
+	normalize func(path string) string
+	limits    Limits
+	anonymize func(ip string) string
+
+	// finalizers run after sanitizing and truncation, right before filters.
+	finalizers []func(rec *Record)
+
+	startHooks    []RequestStartHook
+	writeHooks    []ResponseWriteHook
+	completeHooks []CompleteHook
+	panicHooks    []PanicHook
+
+	// dropped are called with the records filters drop.
+	dropped []func(rec *Record)
+
+	pprofLabels   bool
+	recoverPanics bool
+	overheadField bool
+	// stackEnabled, when set, reports whether the stack of a recovered
+	// panic is logged.
+	stackEnabled func() bool
+
+	// errorStatus maps the errors returned to WrapErr to a status.
+	errorStatus func(err error) int
+}
+
+type optionFunc func(*config)
+
+func (fn optionFunc) apply(c *config) { fn(c) }
+
+// withFilter drops records for which keep returns false.
+func withFilter(keep func(rec *Record) bool) Option {
+	return optionFunc(func(c *config) {
+		c.filters = append(c.filters, keep)
+	})
+}
+
+func (fn Func) apply(c *config) {
+	c.funcs = append(c.funcs, func(rec Record) {
+		if rec.Request == nil {
+			// Records of other types than TypeRequest have no request.
+			return
+		}
+		if info, ok := requestInfoFrom(rec.Request.Context()); ok {
+			// Kept for recordFor so passing the record allocates nothing.
+			info.setRecord(rec)
+		}
+		fn(rec.Request, rec.Duration, rec.Status)
+	})
+}
+
+// recordFor returns the record Wrap passes to a Func with req or, when req
+// does not come from Wrap, one with the fields of req.
+func recordFor(req *http.Request, elapsed time.Duration, status int) Record {
+	if rec, ok := recordOf(req); ok {
+		return rec
+	}
+	rec := Record{
+		Request:   req,
+		Time:      timeNow().Add(-elapsed),
+		Method:    req.Method,
+		Host:      req.Host,
+		Path:      req.URL.Path,
+		Query:     req.URL.RawQuery,
+		UserAgent: req.UserAgent(),
+		ClientIP:  clientIP(req),
+		Status:    status,
+		Duration:  elapsed,
+	}
+	if info, ok := requestInfoFrom(req.Context()); ok {
+		rec.Route, rec.ClientIP = info.Route, info.ClientIP
+		rec.RequestID, rec.TraceID = info.ID, info.Trace.TraceID
+	}
+	return rec
+}
+
+func (fn RecordFunc) apply(c *config) { c.funcs = append(c.funcs, fn) }
+
+// WithContextLogger stores a request scoped logger derived from logger in
+// the request context. The logger includes the request_id, route, client_ip,
+// and, when propagated, trace_id of the request. Handlers retrieve it with
+// FromContext.
+func WithContextLogger(logger *slog.Logger) Option {
+	return optionFunc(func(c *config) {
+		c.logger = logger
+	})
+}
+
+func (c *config) requestLogger(info *requestInfo, req *http.Request) *slog.Logger {
+	attrs := []any{
+		slog.String("request_id", info.ID),
+		slog.String("route", info.route()),
+		slog.String("client_ip", info.ClientIP),
+	}
+	if info.Trace.TraceID != "" {
+		attrs = append(attrs, slog.String("trace_id", info.Trace.TraceID))
+	}
+	return c.logger.With(attrs...)
+}
+
+// WithDeadlineFields adds the read_deadline and write_deadline set by the
+// handler through http.ResponseController, and full_duplex when it was
+// enabled, to each record. Deadline overrides are a common source of
+// confusing behavior with slow clients.
+func WithDeadlineFields() Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			if rec.writer == nil {
+				return
+			}
+			if !rec.writer.readDeadline.IsZero() {
+				rec.Attrs = append(rec.Attrs, slog.Time("read_deadline", rec.writer.readDeadline))
+			}
+			if !rec.writer.writeDeadline.IsZero() {
+				rec.Attrs = append(rec.Attrs, slog.Time("write_deadline", rec.writer.writeDeadline))
+			}
+			if rec.writer.fullDuplex {
+				rec.Attrs = append(rec.Attrs, slog.Bool("full_duplex", true))
+			}
+		})
+	})
+}
+
+// WithInterimFields adds interim_statuses, the informational 1xx statuses
+// such as 100 Continue sent before the final response, and early_hints=true
+// when one of them was 103 Early Hints, to records of such responses.
+func WithInterimFields() Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			if rec.writer == nil || len(rec.writer.interim) == 0 {
+				return
+			}
+			rec.Attrs = append(rec.Attrs, slog.Any("interim_statuses", rec.writer.interim))
+			for _, status := range rec.writer.interim {
+				if status == http.StatusEarlyHints {
+					rec.Attrs = append(rec.Attrs, slog.Bool("early_hints", true))
+					break
+				}
+			}
+		})
+	})
+}
+
+// WithResponseTiming adds time_to_first_byte and time_to_last_byte, measured
+// from the start of the request to when the handler first wrote and when its
+// last write or flush returned, and write_duration, the time spent blocked in
+// writes and flushes. Duration covers the whole handler call so a large
+// write_duration points at a slow client rather than a slow handler. Bytes
+// net/http buffers and sends after the handler returns are not included.
+func WithResponseTiming() Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			if rec.writer == nil || rec.writer.firstByte.IsZero() {
+				return
+			}
+			rec.Attrs = append(rec.Attrs,
+				slog.Duration("time_to_first_byte", rec.writer.firstByte.Sub(rec.Time)),
+				slog.Duration("time_to_last_byte", rec.writer.lastByte.Sub(rec.Time)),
+				slog.Duration("write_duration", rec.writer.writeTime),
+			)
+		})
+	})
+}
@@ -0,0 +1,315 @@
+package httplog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ParquetWriter buffers records and writes them to Parquet files for
+// analytics tools such as DuckDB, Athena or BigQuery. It is created with
+// NewParquetWriter. A ParquetWriter is an Option so it can be passed to Wrap
+// directly.
+//
+// Each file holds a single row group with these required columns:
+//
+//	time        INT64 (TIMESTAMP_MILLIS, UTC)
+//	method      BYTE_ARRAY (UTF8)
+//	path        BYTE_ARRAY (UTF8)
+//	route       BYTE_ARRAY (UTF8)
+//	query       BYTE_ARRAY (UTF8)
+//	status      INT32
+//	duration_ms DOUBLE
+//	request_id  BYTE_ARRAY (UTF8)
+//	trace_id    BYTE_ARRAY (UTF8)
+//	client_ip   BYTE_ARRAY (UTF8)
+//	user_agent  BYTE_ARRAY (UTF8)
+//	attrs       BYTE_ARRAY (UTF8, a JSON object)
+//
+// Missing values are written as empty strings. Pages are PLAIN encoded and
+// uncompressed.
+type ParquetWriter struct {
+	dir     string
+	maxRows int
+	format  format
+
+	mu   sync.Mutex
+	rows []parquetRow
+	seq  int
+}
+
+type parquetRow struct {
+	rec   Record
+	attrs []byte
+}
+
+// NewParquetWriter returns a ParquetWriter that writes a new file to dir
+// each time maxRows records have been buffered. Call Flush to write a
+// partial file, for example from a time.Ticker, and Close before the
+// program exits. Files are named requests-<UTC time>-<n>.parquet and only
+// appear in dir once complete.
+func NewParquetWriter(dir string, maxRows int, options ...FormatOption) *ParquetWriter {
+	return &ParquetWriter{dir: dir, maxRows: maxRows, format: newFormat(options)}
+}
+
+// Log buffers rec, writing a file when the buffer is full. Write failures
+// are counted in Stats.WriteErrors.
+func (w *ParquetWriter) Log(rec Record) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.rows = append(w.rows, parquetRow{rec: rec, attrs: w.format.appendJSONAttrs(nil, rec.Attrs)})
+	if len(w.rows) >= w.maxRows {
+		if err := w.flush(); err != nil {
+			stats.writeErrors.Add(1)
+		}
+	}
+}
+
+func (w *ParquetWriter) apply(c *config) { RecordFunc(w.Log).apply(c) }
+
+// Flush writes the buffered records to a new file.
+func (w *ParquetWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flush()
+}
+
+// Close flushes the buffered records.
+func (w *ParquetWriter) Close() error { return w.Flush() }
+
+func (w *ParquetWriter) flush() error {
+	if len(w.rows) == 0 {
+		return nil
+	}
+	rows := w.rows
+	w.rows = nil
+	w.seq++
+	name := fmt.Sprintf("requests-%s-%d.parquet", timeNow().UTC().Format("20060102T150405Z"), w.seq)
+	tmp, err := os.CreateTemp(w.dir, "."+name+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(encodeParquet(rows)); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(w.dir, name))
+}
+
+// Parquet physical and converted types used by the columns.
+const (
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+)
+
+type parquetColumn struct {
+	name      string
+	typ       int32
+	converted int32 // -1 when unset
+	value     func(row parquetRow, b []byte) []byte
+}
+
+var parquetColumns = []parquetColumn{
+	{"time", parquetInt64, parquetTimestampMillis, func(row parquetRow, b []byte) []byte {
+		return binary.LittleEndian.AppendUint64(b, uint64(row.rec.Time.UnixMilli()))
+	}},
+	{"method", parquetByteArray, parquetUTF8, parquetString(func(rec Record) string { return rec.Method })},
+	{"path", parquetByteArray, parquetUTF8, parquetString(func(rec Record) string { return rec.Path })},
+	{"route", parquetByteArray, parquetUTF8, parquetString(func(rec Record) string { return rec.Route })},
+	{"query", parquetByteArray, parquetUTF8, parquetString(func(rec Record) string { return rec.Query })},
+	{"status", parquetInt32, -1, func(row parquetRow, b []byte) []byte {
+		return binary.LittleEndian.AppendUint32(b, uint32(row.rec.Status))
+	}},
+	{"duration_ms", parquetDouble, -1, func(row parquetRow, b []byte) []byte {
+		ms := float64(row.rec.Duration) / float64(time.Millisecond)
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(ms))
+	}},
+	{"request_id", parquetByteArray, parquetUTF8, parquetString(func(rec Record) string { return rec.RequestID })},
+	{"trace_id", parquetByteArray, parquetUTF8, parquetString(func(rec Record) string { return rec.TraceID })},
+	{"client_ip", parquetByteArray, parquetUTF8, parquetString(func(rec Record) string { return rec.ClientIP })},
+	{"user_agent", parquetByteArray, parquetUTF8, parquetString(func(rec Record) string { return rec.UserAgent })},
+	{"attrs", parquetByteArray, parquetUTF8, func(row parquetRow, b []byte) []byte {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(row.attrs)))
+		return append(b, row.attrs...)
+	}},
+}
+
+func parquetString(field func(Record) string) func(parquetRow, []byte) []byte {
+	return func(row parquetRow, b []byte) []byte {
+		s := field(row.rec)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(s)))
+		return append(b, s...)
+	}
+}
+
+// encodeParquet encodes rows as a Parquet file with one row group and one
+// data page per column.
+func encodeParquet(rows []parquetRow) []byte {
+	var file bytes.Buffer
+	file.WriteString("PAR1")
+
+	type chunk struct {
+		offset, size int64
+	}
+	chunks := make([]chunk, len(parquetColumns))
+	var values []byte
+	for i, col := range parquetColumns {
+		values = values[:0]
+		for _, row := range rows {
+			values = col.value(row, values)
+		}
+		var header thriftWriter
+		header.i32(1, 0) // DATA_PAGE
+		header.i32(2, int32(len(values)))
+		header.i32(3, int32(len(values)))
+		header.begin(5, thriftStruct)
+		header.i32(1, int32(len(rows)))
+		header.i32(2, 0) // PLAIN
+		header.i32(3, 3) // RLE
+		header.i32(4, 3) // RLE
+		header.close()
+		header.stop()
+
+		chunks[i].offset = int64(file.Len())
+		file.Write(header.b)
+		file.Write(values)
+		chunks[i].size = int64(file.Len()) - chunks[i].offset
+	}
+
+	var meta thriftWriter
+	meta.i32(1, 1)
+	meta.list(2, thriftStruct, len(parquetColumns)+1)
+	meta.open()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(parquetColumns)))
+	meta.close()
+	for _, col := range parquetColumns {
+		meta.open()
+		meta.i32(1, col.typ)
+		meta.i32(3, 0) // REQUIRED
+		meta.binary(4, col.name)
+		if col.converted >= 0 {
+			meta.i32(6, col.converted)
+		}
+		meta.close()
+	}
+	meta.i64(3, int64(len(rows)))
+	meta.list(4, thriftStruct, 1)
+	meta.open()
+	meta.list(1, thriftStruct, len(parquetColumns))
+	var total int64
+	for i, col := range parquetColumns {
+		meta.open()
+		meta.i64(2, chunks[i].offset)
+		meta.begin(3, thriftStruct)
+		meta.i32(1, col.typ)
+		meta.list(2, thriftI32, 1)
+		meta.varint(0) // PLAIN
+		meta.list(3, thriftBinary, 1)
+		meta.str(col.name)
+		meta.i32(4, 0) // UNCOMPRESSED
+		meta.i64(5, int64(len(rows)))
+		meta.i64(6, chunks[i].size)
+		meta.i64(7, chunks[i].size)
+		meta.i64(9, chunks[i].offset)
+		meta.close()
+		meta.close()
+		total += chunks[i].size
+	}
+	meta.i64(2, total)
+	meta.i64(3, int64(len(rows)))
+	meta.close()
+	meta.binary(6, "github.com/crhntr/httplog")
+	meta.stop()
+
+	file.Write(meta.b)
+	file.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(meta.b))))
+	file.WriteString("PAR1")
+	return file.Bytes()
+}
+
+// Thrift compact protocol types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter writes the subset of the Thrift compact protocol needed for
+// Parquet metadata.
+type thriftWriter struct {
+	b     []byte
+	last  int16
+	stack []int16
+}
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	if delta := id - w.last; delta > 0 && delta <= 15 {
+		w.b = append(w.b, byte(delta)<<4|typ)
+	} else {
+		w.b = append(w.b, typ)
+		w.varint(int64(id))
+	}
+	w.last = id
+}
+
+func (w *thriftWriter) varint(v int64) {
+	w.b = binary.AppendUvarint(w.b, uint64(v<<1^v>>63))
+}
+
+func (w *thriftWriter) str(s string) {
+	w.b = binary.AppendUvarint(w.b, uint64(len(s)))
+	w.b = append(w.b, s...)
+}
+
+func (w *thriftWriter) i32(id int16, v int32) { w.field(id, thriftI32); w.varint(int64(v)) }
+
+func (w *thriftWriter) i64(id int16, v int64) { w.field(id, thriftI64); w.varint(v) }
+
+func (w *thriftWriter) binary(id int16, s string) { w.field(id, thriftBinary); w.str(s) }
+
+func (w *thriftWriter) list(id int16, elem byte, n int) {
+	w.field(id, thriftList)
+	if n < 15 {
+		w.b = append(w.b, byte(n)<<4|elem)
+		return
+	}
+	w.b = append(w.b, 0xf0|elem)
+	w.b = binary.AppendUvarint(w.b, uint64(n))
+}
+
+// begin starts a struct field. It is finished with close.
+func (w *thriftWriter) begin(id int16, typ byte) {
+	w.field(id, typ)
+	w.open()
+}
+
+// open starts a struct that is a list element and close finishes it.
+func (w *thriftWriter) open() {
+	w.stack = append(w.stack, w.last)
+	w.last = 0
+}
+
+func (w *thriftWriter) close() {
+	w.stop()
+	w.last = w.stack[len(w.stack)-1]
+	w.stack = w.stack[:len(w.stack)-1]
+}
+
+func (w *thriftWriter) stop() { w.b = append(w.b, 0) }
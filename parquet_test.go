@@ -0,0 +1,199 @@
+package httplog_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestParquetWriter(t *testing.T) {
+	dir := t.TempDir()
+	w := httplog.NewParquetWriter(dir, 2)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, path := range []string{"/a", "/b", "/c"} {
+		w.Log(httplog.Record{Time: start, Method: http.MethodGet, Path: path, Status: http.StatusOK})
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.parquet"))
+	if len(files) != 1 {
+		t.Fatalf("expected a file after 2 rows got %v", files)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, _ = filepath.Glob(filepath.Join(dir, "*.parquet"))
+	if len(files) != 2 {
+		t.Fatalf("expected Close to write the partial file got %v", files)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("expected no temporary files got %d entries", len(entries))
+	}
+
+	p, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(p, []byte("PAR1")) || !bytes.HasSuffix(p, []byte("PAR1")) {
+		t.Fatalf("expected parquet magic")
+	}
+	footer := int(binary.LittleEndian.Uint32(p[len(p)-8:]))
+	if footer <= 0 || footer > len(p)-12 {
+		t.Fatalf("unexpected footer length %d", footer)
+	}
+	meta := p[len(p)-8-footer : len(p)-8]
+	for _, name := range []string{"time", "method", "path", "status", "duration_ms", "attrs"} {
+		if !bytes.Contains(meta, []byte(name)) {
+			t.Errorf("expected the schema to contain %s", name)
+		}
+	}
+	if !bytes.Contains(p, []byte("\x02\x00\x00\x00/a\x02\x00\x00\x00/b")) {
+		t.Errorf("expected PLAIN encoded paths")
+	}
+}
+
+// TestParquetWriter_roundTrip decodes the footer and the column chunks of a
+// file the way a Parquet reader does. The files were also checked to be read
+// back by github.com/parquet-go/parquet-go, which is not a dependency.
+func TestParquetWriter_roundTrip(t *testing.T) {
+	dir := t.TempDir()
+	w := httplog.NewParquetWriter(dir, 10)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, path := range []string{"/a", "/b"} {
+		w.Log(httplog.Record{Time: start, Method: http.MethodGet, Path: path, Status: http.StatusOK + i, Duration: 1500 * time.Microsecond})
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.parquet"))
+	if len(files) != 1 {
+		t.Fatalf("expected one file got %v", files)
+	}
+	p, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	footer := int(binary.LittleEndian.Uint32(p[len(p)-8:]))
+	meta := thriftStruct(t, bytes.NewReader(p[len(p)-8-footer:len(p)-8]))
+	if meta[3] != int64(2) {
+		t.Errorf("expected 2 rows got %v", meta[3])
+	}
+	schema := meta[2].([]any)[1:]
+	rowGroups := meta[4].([]any)
+	if len(rowGroups) != 1 {
+		t.Fatalf("expected one row group got %d", len(rowGroups))
+	}
+	chunks := rowGroups[0].(map[int16]any)[1].([]any)
+	if len(chunks) != len(schema) {
+		t.Fatalf("expected a column chunk per schema column got %d and %d", len(chunks), len(schema))
+	}
+
+	columns := make(map[string][]byte)
+	for i, chunk := range chunks {
+		column := chunk.(map[int16]any)[3].(map[int16]any)
+		name := string(schema[i].(map[int16]any)[4].([]byte))
+		if path := column[3].([]any); len(path) != 1 || string(path[0].([]byte)) != name {
+			t.Errorf("expected the chunk of %s got %v", name, path)
+		}
+		offset, size := column[9].(int64), column[7].(int64)
+		r := bytes.NewReader(p[offset : offset+size])
+		header := thriftStruct(t, r)
+		if header[5].(map[int16]any)[1] != int32(2) || header[3] != int32(r.Len()) {
+			t.Errorf("unexpected page header of %s %v", name, header)
+		}
+		columns[name] = p[offset+size-int64(r.Len()) : offset+size]
+	}
+
+	if got := binary.LittleEndian.Uint64(columns["time"]); got != uint64(start.UnixMilli()) {
+		t.Errorf("unexpected time %d", got)
+	}
+	if got := string(columns["path"]); got != "\x02\x00\x00\x00/a\x02\x00\x00\x00/b" {
+		t.Errorf("unexpected paths %q", got)
+	}
+	if got := binary.LittleEndian.Uint32(columns["status"][4:]); got != http.StatusCreated {
+		t.Errorf("unexpected status %d", got)
+	}
+	if got := math.Float64frombits(binary.LittleEndian.Uint64(columns["duration_ms"])); got != 1.5 {
+		t.Errorf("unexpected duration %v", got)
+	}
+}
+
+// thriftStruct decodes a Thrift compact protocol struct into its fields by
+// id: int32, int64, []byte, []any of elements and map[int16]any of structs.
+func thriftStruct(t *testing.T, r *bytes.Reader) map[int16]any {
+	t.Helper()
+	fields := make(map[int16]any)
+	var id int16
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b == 0 {
+			return fields
+		}
+		if delta := int16(b >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(thriftZigzag(t, r))
+		}
+		fields[id] = thriftValue(t, r, b&0x0f)
+	}
+}
+
+func thriftValue(t *testing.T, r *bytes.Reader, typ byte) any {
+	t.Helper()
+	switch typ {
+	case 5:
+		return int32(thriftZigzag(t, r))
+	case 6:
+		return thriftZigzag(t, r)
+	case 8:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := make([]byte, n)
+		if _, err := io.ReadFull(r, p); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	case 9:
+		b, err := r.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := uint64(b >> 4)
+		if n == 15 {
+			if n, err = binary.ReadUvarint(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = thriftValue(t, r, b&0x0f)
+		}
+		return list
+	case 12:
+		return thriftStruct(t, r)
+	}
+	t.Fatalf("unexpected thrift type %d", typ)
+	return nil
+}
+
+func thriftZigzag(t *testing.T, r *bytes.Reader) int64 {
+	t.Helper()
+	v, err := binary.ReadUvarint(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int64(v>>1) ^ -int64(v&1)
+}
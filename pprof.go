@@ -0,0 +1,27 @@
+package httplog
+
+import (
+	"context"
+	"net/http"
+	"runtime/pprof"
+)
+
+// WithPprofLabels sets the pprof labels "http_route", the path normalized by
+// WithNormalizedPath when used, and "http_method" while the handler runs so
+// CPU profiles can be broken down by endpoint, for example with
+//
+//	go tool pprof -tagfocus http_route=/items/{id} profile.pb.gz
+//
+// Goroutines the handler starts inherit the labels.
+func WithPprofLabels() Option {
+	return optionFunc(func(c *config) {
+		c.pprofLabels = true
+	})
+}
+
+func serveWithLabels(h http.Handler, w http.ResponseWriter, r *http.Request, info *requestInfo) {
+	labels := pprof.Labels("http_route", info.route(), "http_method", r.Method)
+	pprof.Do(r.Context(), labels, func(ctx context.Context) {
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
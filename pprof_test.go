@@ -0,0 +1,26 @@
+package httplog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithPprofLabels(t *testing.T) {
+	labels := map[string]string{}
+	h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pprof.ForLabels(r.Context(), func(key, value string) bool {
+			labels[key] = value
+			return true
+		})
+	}), httplog.WithNormalizedPath(), httplog.WithPprofLabels(), httplog.Func(func(*http.Request, time.Duration, int) {}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/items/42", nil))
+
+	if labels["http_route"] != "/items/{id}" || labels["http_method"] != http.MethodPut {
+		t.Errorf("unexpected labels %v", labels)
+	}
+}
@@ -0,0 +1,59 @@
+package httplog
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// WithPriority adds priority_urgency and priority_incremental, parsed from
+// the Priority header defined by RFC 9218, to records of requests that send
+// one. Missing parameters take their defaults of urgency 3 and not
+// incremental.
+func WithPriority() Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			header := rec.Request.Header.Get("Priority")
+			if header == "" {
+				return
+			}
+			urgency, incremental := parsePriority(header)
+			rec.Attrs = append(rec.Attrs,
+				slog.Int("priority_urgency", urgency),
+				slog.Bool("priority_incremental", incremental),
+			)
+		})
+	})
+}
+
+// parsePriority parses the structured field dictionary of a Priority
+// header, ignoring unknown and invalid members as RFC 9218 requires.
+func parsePriority(header string) (urgency int, incremental bool) {
+	urgency = 3
+	for _, member := range strings.Split(header, ",") {
+		key, value, hasValue := strings.Cut(strings.TrimSpace(member), "=")
+		if i := strings.IndexByte(value, ';'); i >= 0 {
+			value = value[:i]
+		}
+		switch key {
+		case "u":
+			if n, err := strconv.Atoi(value); err == nil && n >= 0 && n <= 7 {
+				urgency = n
+			}
+		case "i":
+			incremental = !hasValue || value == "?1"
+		}
+	}
+	return urgency, incremental
+}
+
+// SetQoS records the internal quality of service class the application
+// assigned to the request, such as "interactive" or "batch". It is logged as
+// qos. SetQoS does nothing when ctx does not belong to a request handled by
+// Wrap.
+func SetQoS(ctx context.Context, class string) {
+	if info, ok := requestInfoFrom(ctx); ok {
+		info.setAttr(slog.String("qos", class))
+	}
+}
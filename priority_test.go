@@ -0,0 +1,49 @@
+package httplog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithPriority(t *testing.T) {
+	for _, tt := range []struct {
+		Header      string
+		Urgency     any
+		Incremental any
+	}{
+		{Header: ""},
+		{Header: "u=1", Urgency: int64(1), Incremental: false},
+		{Header: "u=5, i", Urgency: int64(5), Incremental: true},
+		{Header: "i=?0, foo=bar", Urgency: int64(3), Incremental: false},
+		{Header: "u=9", Urgency: int64(3), Incremental: false},
+	} {
+		var rec httplog.Record
+		h := httplog.Wrap(http.NotFoundHandler(), httplog.WithPriority(), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.Header != "" {
+			r.Header.Set("Priority", tt.Header)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+
+		fields := recordFields(rec)
+		if fields["priority_urgency"] != tt.Urgency || fields["priority_incremental"] != tt.Incremental {
+			t.Errorf("unexpected fields for %q: %v", tt.Header, fields)
+		}
+	}
+}
+
+func TestSetQoS(t *testing.T) {
+	var rec httplog.Record
+	h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httplog.SetQoS(r.Context(), "interactive")
+		httplog.SetQoS(r.Context(), "batch")
+	}), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := recordFields(rec)["qos"]; got != "batch" || len(rec.Attrs) != 1 {
+		t.Errorf("expected a single qos field batch got %v", rec.Attrs)
+	}
+}
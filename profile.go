@@ -0,0 +1,94 @@
+package httplog
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// Profile names the keys StructuredWriter uses for the level, message and
+// time of each line and how it writes the level, so log agents in a cluster
+// classify entries without a custom parser. Use it with WithProfile.
+type Profile struct {
+	LevelKey, MessageKey, TimeKey string
+
+	// Severity returns the value written for level.
+	Severity func(level slog.Level) string
+}
+
+var (
+	// ProfileStackdriver follows the structured logging conventions of
+	// Google Cloud Logging, which the GKE logging agent parses from stdout:
+	// severity is one of DEBUG, INFO, WARNING, ERROR or CRITICAL.
+	ProfileStackdriver = Profile{
+		LevelKey:   "severity",
+		MessageKey: "message",
+		TimeKey:    "timestamp",
+		Severity:   stackdriverSeverity,
+	}
+
+	// ProfileBanzai follows the conventions of the Banzai Cloud logging
+	// operator and the fluent-bit parsers it configures: severity is the
+	// lower case level name such as info, warn or error.
+	ProfileBanzai = Profile{
+		LevelKey:   "severity",
+		MessageKey: "message",
+		TimeKey:    "timestamp",
+		Severity: func(level slog.Level) string {
+			return strings.ToLower(level.String())
+		},
+	}
+)
+
+func stackdriverSeverity(level slog.Level) string {
+	switch {
+	case level < slog.LevelInfo:
+		return "DEBUG"
+	case level < slog.LevelWarn:
+		return "INFO"
+	case level < slog.LevelError:
+		return "WARNING"
+	case level < slog.LevelError+4:
+		return "ERROR"
+	default:
+		return "CRITICAL"
+	}
+}
+
+// WithProfile makes StructuredWriter write lines with the keys and severity
+// values of p, for example
+//
+//	httplog.StructuredWriter(os.Stdout, httplog.WithProfile(httplog.ProfileStackdriver))
+//
+// Structured uses the handler of its logger as is and ignores the profile.
+func WithProfile(p Profile) FormatOption {
+	return func(f *format) {
+		f.profile = &p
+	}
+}
+
+// replaceAttr renames the built in keys of slog for p. An empty key in p
+// keeps the slog default.
+func (p *Profile) replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) != 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.LevelKey:
+		a.Key = keyOr(p.LevelKey, a.Key)
+		if level, ok := a.Value.Any().(slog.Level); ok && p.Severity != nil {
+			a.Value = slog.StringValue(p.Severity(level))
+		}
+	case slog.MessageKey:
+		a.Key = keyOr(p.MessageKey, a.Key)
+	case slog.TimeKey:
+		a.Key = keyOr(p.TimeKey, a.Key)
+	}
+	return a
+}
+
+func keyOr(key, fallback string) string {
+	if key == "" {
+		return fallback
+	}
+	return key
+}
@@ -0,0 +1,57 @@
+package httplog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithProfile(t *testing.T) {
+	for _, tt := range []struct {
+		Name             string
+		Profile          httplog.Profile
+		Status           int
+		Severity         string
+		Message, TimeKey string
+	}{
+		{Name: "stackdriver info", Profile: httplog.ProfileStackdriver, Status: http.StatusOK, Severity: "INFO", Message: "request", TimeKey: "timestamp"},
+		{Name: "stackdriver error", Profile: httplog.ProfileStackdriver, Status: http.StatusBadGateway, Severity: "ERROR", Message: "request error", TimeKey: "timestamp"},
+		{Name: "banzai", Profile: httplog.ProfileBanzai, Status: http.StatusOK, Severity: "info", Message: "request", TimeKey: "timestamp"},
+		{Name: "default keys", Profile: httplog.Profile{LevelKey: "lvl"}, Status: http.StatusOK, Severity: "INFO", Message: "request", TimeKey: "time"},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			status := tt.Status
+			logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(status)
+			}), httplog.StructuredWriter(&buf, httplog.WithProfile(tt.Profile)))
+			logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			var line map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+				t.Fatal(err)
+			}
+			levelKey := tt.Profile.LevelKey
+			messageKey := tt.Profile.MessageKey
+			if messageKey == "" {
+				messageKey = "msg"
+			}
+			if got := line[levelKey]; got != tt.Severity {
+				t.Errorf("expected %s to be %q got %v in %s", levelKey, tt.Severity, got, buf.String())
+			}
+			if got := line[messageKey]; got != tt.Message {
+				t.Errorf("expected %s to be %q got %v in %s", messageKey, tt.Message, got, buf.String())
+			}
+			if _, ok := line[tt.TimeKey].(string); !ok {
+				t.Errorf("expected a %s field in %s", tt.TimeKey, buf.String())
+			}
+			if _, ok := line["level"]; ok {
+				t.Errorf("expected no level field in %s", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package httplog
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"sync"
+)
+
+// Protobuf returns a RecordFunc that writes records to w as httplog.v1.Record
+// messages, defined in httplog.proto, each prefixed with its length as a
+// varint. This is the framing read by protodelim in
+// google.golang.org/protobuf. Failed writes are counted in
+// Stats.WriteErrors.
+func Protobuf(w io.Writer) RecordFunc {
+	var mu sync.Mutex
+	return func(rec Record) {
+		msg := AppendProtobuf(nil, rec)
+		b := binary.AppendUvarint(make([]byte, 0, len(msg)+binary.MaxVarintLen32), uint64(len(msg)))
+		b = append(b, msg...)
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := w.Write(b); err != nil {
+			stats.writeErrors.Add(1)
+		}
+	}
+}
+
+// AppendProtobuf appends the httplog.v1.Record encoding of rec to b. Attr
+// values that are not one of the scalar kinds are encoded like their JSON
+// encoding: objects as a Group, arrays as a List and null as an Attr without
+// value. Errors, and values that cannot be encoded as JSON, are strings.
+func AppendProtobuf(b []byte, rec Record) []byte {
+	if !rec.Time.IsZero() {
+		b = protoVarint(b, 1, uint64(rec.Time.UnixNano()))
+	}
+	b = protoString(b, 2, rec.Method)
+	b = protoString(b, 3, rec.Path)
+	b = protoString(b, 4, rec.Route)
+	b = protoString(b, 5, rec.Query)
+	b = protoVarint(b, 6, uint64(int64(rec.Status)))
+	b = protoVarint(b, 7, uint64(rec.Duration))
+	b = protoString(b, 8, rec.RequestID)
+	b = protoString(b, 9, rec.TraceID)
+	b = protoString(b, 10, rec.ClientIP)
+	b = protoString(b, 11, rec.UserAgent)
+	b = appendProtoAttrs(b, 12, rec.Attrs)
+	if rec.Type != TypeRequest {
+		b = protoString(b, 13, rec.Type)
+	}
+	b = protoString(b, 14, rec.Host)
+	return b
+}
+
+func appendProtoAttrs(b []byte, field int, attrs []slog.Attr) []byte {
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() == slog.KindGroup && a.Key == "" {
+			b = appendProtoAttrs(b, field, a.Value.Group())
+			continue
+		}
+		if a.Equal(slog.Attr{}) || a.Value.Kind() == slog.KindGroup && len(a.Value.Group()) == 0 {
+			continue
+		}
+		b = protoBytes(b, field, appendProtoAttr(nil, a))
+	}
+	return b
+}
+
+func appendProtoAttr(b []byte, a slog.Attr) []byte {
+	b = protoString(b, 1, a.Key)
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindString:
+		return protoBytes(b, 2, []byte(v.String()))
+	case slog.KindInt64:
+		return protoTag(b, 3, uint64(v.Int64()))
+	case slog.KindUint64:
+		return protoTag(b, 4, v.Uint64())
+	case slog.KindFloat64:
+		b = binary.AppendUvarint(b, 5<<3|1)
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(v.Float64()))
+	case slog.KindBool:
+		var n uint64
+		if v.Bool() {
+			n = 1
+		}
+		return protoTag(b, 6, n)
+	case slog.KindDuration:
+		return protoTag(b, 7, uint64(v.Duration()))
+	case slog.KindTime:
+		return protoTag(b, 8, uint64(v.Time().UnixNano()))
+	case slog.KindGroup:
+		return protoBytes(b, 9, appendProtoAttrs(nil, 1, v.Group()))
+	default:
+		if err, ok := v.Any().(error); ok {
+			return protoBytes(b, 2, []byte(err.Error()))
+		}
+		if structured, ok := structuredValue(v.Any()); ok {
+			return appendProtoStructured(b, structured)
+		}
+		return protoBytes(b, 2, []byte(fmt.Sprint(v.Any())))
+	}
+}
+
+// appendProtoStructured appends the value of an Attr for a value returned by
+// structuredValue.
+func appendProtoStructured(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case bool:
+		var n uint64
+		if v {
+			n = 1
+		}
+		return protoTag(b, 6, n)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return protoTag(b, 3, uint64(n))
+		}
+		f, _ := v.Float64()
+		b = binary.AppendUvarint(b, 5<<3|1)
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(f))
+	case string:
+		return protoBytes(b, 2, []byte(v))
+	case []any:
+		var list []byte
+		for _, e := range v {
+			list = protoBytes(list, 1, appendProtoStructured(nil, e))
+		}
+		return protoBytes(b, 10, list)
+	case map[string]any:
+		var group []byte
+		for _, key := range sortedKeys(v) {
+			group = protoBytes(group, 1, appendProtoStructured(protoString(nil, 1, key), v[key]))
+		}
+		return protoBytes(b, 9, group)
+	default:
+		return b
+	}
+}
+
+// protoTag appends a field with a varint value even when it is zero, as
+// required for members of a oneof.
+func protoTag(b []byte, field int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3)
+	return binary.AppendUvarint(b, v)
+}
+
+func protoVarint(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	return protoTag(b, field, v)
+}
+
+func protoString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func protoBytes(b []byte, field int, p []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(p)))
+	return append(b, p...)
+}
@@ -0,0 +1,62 @@
+package httplog_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestAppendProtobuf(t *testing.T) {
+	got := httplog.AppendProtobuf(nil, httplog.Record{
+		Method:   http.MethodGet,
+		Path:     "/",
+		Status:   http.StatusOK,
+		Duration: time.Millisecond,
+		Attrs: []slog.Attr{
+			slog.Bool("ok", false),
+			slog.Group("empty"),
+		},
+	})
+	want := []byte{
+		2<<3 | 2, 3, 'G', 'E', 'T',
+		3<<3 | 2, 1, '/',
+		6 << 3, 0xc8, 0x01,
+		7 << 3, 0xc0, 0x84, 0x3d,
+		12<<3 | 2, 6, 1<<3 | 2, 2, 'o', 'k', 6 << 3, 0,
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("expected\n%x\ngot\n%x", want, got)
+	}
+}
+
+func TestProtobuf(t *testing.T) {
+	var buf bytes.Buffer
+	rec := httplog.Record{Method: http.MethodGet, Path: "/", Status: http.StatusOK}
+	httplog.Protobuf(&buf)(rec)
+
+	msg := httplog.AppendProtobuf(nil, rec)
+	if !bytes.Equal(buf.Bytes(), append([]byte{byte(len(msg))}, msg...)) {
+		t.Errorf("expected a length delimited message got %x", buf.Bytes())
+	}
+}
+
+func TestAppendProtobuf_structured(t *testing.T) {
+	got := httplog.AppendProtobuf(nil, httplog.Record{
+		Type:  httplog.TypeServer,
+		Attrs: []slog.Attr{slog.Any("statuses", []any{103, map[string]any{"ok": true}})},
+	})
+	want := []byte{
+		12<<3 | 2, 28,
+		1<<3 | 2, 8, 's', 't', 'a', 't', 'u', 's', 'e', 's',
+		10<<3 | 2, 16,
+		1<<3 | 2, 2, 3 << 3, 103,
+		1<<3 | 2, 10, 9<<3 | 2, 8, 1<<3 | 2, 6, 1<<3 | 2, 2, 'o', 'k', 6 << 3, 1,
+	}
+	if !bytes.HasPrefix(got, want) {
+		t.Errorf("expected the attr as a list holding a group\n%x\ngot\n%x", want, got)
+	}
+}
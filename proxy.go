@@ -0,0 +1,54 @@
+package httplog
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+)
+
+// UpstreamTransport wraps the transport of an httputil.ReverseProxy (or any
+// client used on behalf of a request handled by Wrap) so the record of the
+// incoming request includes an "upstream" group with the upstream host, its
+// status, the time to its response headers, the connection timings described
+// on ClientTransport, and any transport error. When next is nil
+// http.DefaultTransport is used.
+//
+//	proxy := httputil.NewSingleHostReverseProxy(target)
+//	proxy.Transport = httplog.UpstreamTransport(proxy.Transport)
+//	http.Handle("/", httplog.Wrap(proxy))
+//
+// The upstream status is logged separately from the status the proxy sent
+// to its client, which differs, for example, when the upstream is down.
+func UpstreamTransport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return upstreamTransport{next: next}
+}
+
+type upstreamTransport struct {
+	next http.RoundTripper
+}
+
+func (t upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	info, ok := requestInfoFrom(req.Context())
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+	timing := new(connTiming)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), timing.trace()))
+	start := timeNow()
+	res, err := t.next.RoundTrip(req)
+	attrs := []slog.Attr{
+		slog.String("host", req.URL.Host),
+		slog.Duration("duration", timeSince(start)),
+	}
+	attrs = append(attrs, timing.attrs(start)...)
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	} else {
+		attrs = append(attrs, slog.Int("status", res.StatusCode))
+	}
+	info.addAttrs(slog.Attr{Key: "upstream", Value: slog.GroupValue(attrs...)})
+	return res, err
+}
@@ -0,0 +1,80 @@
+package httplog_test
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestUpstreamTransport(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer upstream.Close()
+	target, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = httplog.UpstreamTransport(nil)
+
+	var rec httplog.Record
+	h := httplog.Wrap(proxy, httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Status != http.StatusCreated {
+		t.Errorf("expected edge status %d got %d", http.StatusCreated, rec.Status)
+	}
+	up, ok := groupFields(rec, "upstream")
+	if !ok {
+		t.Fatalf("expected upstream group got %v", recordFields(rec))
+	}
+	if up["host"] != target.Host {
+		t.Errorf("expected host %q got %v", target.Host, up["host"])
+	}
+	if up["status"] != int64(http.StatusCreated) {
+		t.Errorf("expected upstream status 201 got %v", up["status"])
+	}
+	if _, ok := up["connect"]; !ok {
+		t.Errorf("expected connect timing got %v", up)
+	}
+	if up["reused"] != false {
+		t.Errorf("expected a new connection got %v", up["reused"])
+	}
+}
+
+func TestUpstreamTransport_unavailable(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	target, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream.Close()
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = httplog.UpstreamTransport(nil)
+	proxy.ErrorLog = log.New(io.Discard, "", 0)
+
+	var rec httplog.Record
+	h := httplog.Wrap(proxy, httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Status != http.StatusBadGateway {
+		t.Errorf("expected edge status %d got %d", http.StatusBadGateway, rec.Status)
+	}
+	up, ok := groupFields(rec, "upstream")
+	if !ok {
+		t.Fatalf("expected upstream group got %v", recordFields(rec))
+	}
+	if _, ok := up["status"]; ok {
+		t.Errorf("expected no upstream status got %v", up["status"])
+	}
+	if up["error"] == nil {
+		t.Errorf("expected an upstream error")
+	}
+}
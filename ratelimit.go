@@ -0,0 +1,83 @@
+package httplog
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// WithRateLimitFields adds retry_after, the Retry-After response header, and
+// a "ratelimit" group with the X-RateLimit-* and RateLimit-* response
+// headers, keyed by the rest of their name such as "remaining", to records
+// of 429 Too Many Requests responses.
+func WithRateLimitFields() Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			if rec.Status != http.StatusTooManyRequests {
+				return
+			}
+			if v := rec.ResponseHeader.Get("Retry-After"); v != "" {
+				rec.Attrs = append(rec.Attrs, slog.String("retry_after", v))
+			}
+			var attrs []slog.Attr
+			for name, values := range rec.ResponseHeader {
+				key, ok := strings.CutPrefix(name, "X-Ratelimit-")
+				if !ok {
+					key, ok = strings.CutPrefix(name, "Ratelimit-")
+				}
+				if !ok || len(values) == 0 {
+					continue
+				}
+				attrs = append(attrs, slog.String(strings.ToLower(strings.ReplaceAll(key, "-", "_")), values[0]))
+			}
+			if len(attrs) > 0 {
+				slices.SortFunc(attrs, func(a, b slog.Attr) int { return strings.Compare(a.Key, b.Key) })
+				rec.Attrs = append(rec.Attrs, slog.Attr{Key: "ratelimit", Value: slog.GroupValue(attrs...)})
+			}
+		})
+	})
+}
+
+// ThrottleCounter counts 429 Too Many Requests responses per client, the
+// client_id set by SetClientID or WithClientIDHeader when present and the
+// client IP otherwise. It is created with NewThrottleCounter. A
+// ThrottleCounter is an Option so it can be passed to Wrap directly.
+type ThrottleCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// NewThrottleCounter returns an empty ThrottleCounter.
+func NewThrottleCounter() *ThrottleCounter {
+	return &ThrottleCounter{counts: make(map[string]int)}
+}
+
+// Log counts rec when it is a 429 response.
+func (t *ThrottleCounter) Log(rec Record) {
+	if rec.Status != http.StatusTooManyRequests {
+		return
+	}
+	client := rec.ClientIP
+	for _, a := range rec.Attrs {
+		if a.Key == "client_id" {
+			client = a.Value.String()
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.counts[client]++
+}
+
+func (t *ThrottleCounter) apply(c *config) { RecordFunc(t.Log).apply(c) }
+
+// Counts returns the number of 429 responses per client since the last call
+// and starts counting again, so it can be called from a periodic report.
+func (t *ThrottleCounter) Counts() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counts := t.counts
+	t.counts = make(map[string]int)
+	return counts
+}
@@ -0,0 +1,53 @@
+package httplog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithRateLimitFields(t *testing.T) {
+	var rec httplog.Record
+	h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("RateLimit-Reset", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}), httplog.WithRateLimitFields(), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := recordFields(rec)["retry_after"]; got != "30" {
+		t.Errorf("expected retry_after 30 got %v", got)
+	}
+	ratelimit, _ := groupFields(rec, "ratelimit")
+	if ratelimit["limit"] != "100" || ratelimit["remaining"] != "0" || ratelimit["reset"] != "30" {
+		t.Errorf("unexpected ratelimit group %v", ratelimit)
+	}
+}
+
+func TestThrottleCounter(t *testing.T) {
+	counter := httplog.NewThrottleCounter()
+	h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}), httplog.WithClientIDHeader("X-Client-Id"), counter)
+
+	for _, client := range []string{"", "", "mobile", ""} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if client != "" {
+			r.Header.Set("X-Client-Id", client)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	counter.Log(httplog.Record{Status: http.StatusOK, ClientIP: "192.0.2.1"})
+
+	counts := counter.Counts()
+	if counts["192.0.2.1"] != 3 || counts["mobile"] != 1 || len(counts) != 2 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+	if counts := counter.Counts(); len(counts) != 0 {
+		t.Errorf("expected Counts to reset got %v", counts)
+	}
+}
@@ -0,0 +1,59 @@
+package httplog
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Record types set as Record.Type.
+const (
+	TypeRequest  = "HTTP_REQUEST"
+	TypeRuntime  = "RUNTIME_STATS"
+	TypeSecurity = "SECURITY_EVENT"
+	TypeServer   = "SERVER_ERROR"
+	TypeSummary  = "LATENCY_SUMMARY"
+	TypeSLO      = "SLO_BURN_RATE"
+	TypeSampling = "SAMPLING_META"
+	TypePipeline = "PIPELINE_WARNING"
+	TypeInFlight = "HTTP_REQUEST_IN_FLIGHT"
+)
+
+// Record describes a request handled by Wrap.
+type Record struct {
+	// Type is empty, meaning TypeRequest, for records of requests. Other
+	// records, such as those of RuntimeStats, only set Time and Attrs.
+	Type string
+
+	// Request is the request passed to the wrapped handler.
+	Request *http.Request
+	// ResponseHeader is the header map of the response, including any
+	// trailers set by the handler.
+	ResponseHeader http.Header
+
+	// Time is when the request started.
+	Time time.Time
+
+	Method string
+	// Host is the Host header, or the host of the URL for client records.
+	Host string
+	Path string
+	// Route is the normalized path set by WithNormalizedPath.
+	Route     string
+	Query     string
+	UserAgent string
+	ClientIP  string
+	Status    int
+	Duration  time.Duration
+	RequestID string
+	TraceID   string
+
+	// Attrs holds additional fields added by options such as WithBaggage.
+	Attrs []slog.Attr
+
+	writer   *logRecord
+	minLevel slog.Level
+}
+
+// RecordFunc receives a Record for each request handled by Wrap.
+type RecordFunc func(rec Record)
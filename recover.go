@@ -0,0 +1,92 @@
+package httplog
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+// StackFrame is a frame of the stack logged by WithRecover.
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// WithRecover recovers panics in the handler so they are logged with the
+// request. The record gets a panic field with the panic value and a stack
+// field holding the frames, innermost first, as a list of StackFrame so log
+// UIs can render and group them. When the handler had not written a status,
+// a 500 Internal Server Error response is sent and logged.
+//
+// Panics with http.ErrAbortHandler are logged with the status written so far
+// and then panic again, so net/http still aborts the response.
+func WithRecover() Option {
+	return optionFunc(func(c *config) {
+		c.recoverPanics = true
+	})
+}
+
+type recoveredPanic struct {
+	value any
+	stack []StackFrame
+}
+
+func (p *recoveredPanic) abort() bool {
+	err, ok := p.value.(error)
+	return ok && errors.Is(err, http.ErrAbortHandler)
+}
+
+func (p *recoveredPanic) attrs(stack bool) []slog.Attr {
+	value := fmt.Sprint(p.value)
+	if err, ok := p.value.(error); ok {
+		value = err.Error()
+	}
+	attrs := []slog.Attr{slog.String("panic", value)}
+	if stack {
+		attrs = append(attrs, slog.Any("stack", p.stack))
+	}
+	return attrs
+}
+
+func serveRecover(h http.Handler, w http.ResponseWriter, r *http.Request, info *requestInfo, labels bool) (recovered *recoveredPanic) {
+	defer func() {
+		if v := recover(); v != nil {
+			recovered = &recoveredPanic{value: v, stack: panicStack()}
+		}
+	}()
+	if labels {
+		serveWithLabels(h, w, r, info)
+	} else {
+		h.ServeHTTP(w, r)
+	}
+	return nil
+}
+
+// panicStack returns the frames of the panicking goroutine below the
+// runtime's panic handling. It must be called from the deferred function
+// that recovers.
+func panicStack() []StackFrame {
+	pc := make([]uintptr, 64)
+	pc = pc[:runtime.Callers(0, pc)]
+	frames := runtime.CallersFrames(pc)
+	var stack []StackFrame
+	panicking := false
+	for {
+		frame, more := frames.Next()
+		if panicking {
+			if !strings.HasPrefix(frame.Function, "runtime.") {
+				stack = append(stack, StackFrame{Function: frame.Function, File: frame.File, Line: frame.Line})
+			}
+		} else if frame.Function == "runtime.gopanic" {
+			panicking = true
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
@@ -0,0 +1,78 @@
+package httplog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func panickingHandler(w http.ResponseWriter, r *http.Request) {
+	panic("boom")
+}
+
+func TestWithRecover(t *testing.T) {
+	var rec httplog.Record
+	h := httplog.Wrap(http.HandlerFunc(panickingHandler), httplog.WithRecover(), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusInternalServerError || rec.Status != http.StatusInternalServerError {
+		t.Errorf("expected a 500 response and record got %d and %d", w.Code, rec.Status)
+	}
+	fields := recordFields(rec)
+	if fields["panic"] != "boom" {
+		t.Errorf("expected panic boom got %v", fields["panic"])
+	}
+	stack, _ := fields["stack"].([]httplog.StackFrame)
+	if len(stack) == 0 || !strings.HasSuffix(stack[0].Function, ".panickingHandler") || !strings.HasSuffix(stack[0].File, "recover_test.go") || stack[0].Line == 0 {
+		t.Fatalf("expected the panicking function first got %+v", stack)
+	}
+
+	var buf bytes.Buffer
+	httplog.JSONWriter(&buf, nil)(rec)
+	var line struct {
+		Stack []map[string]any `json:"stack"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if len(line.Stack) == 0 || line.Stack[0]["function"] != stack[0].Function || line.Stack[0]["line"] == nil {
+		t.Errorf("expected structured frames in JSON got %s", buf.String())
+	}
+}
+
+func TestWithRecover_headerWritten(t *testing.T) {
+	var rec httplog.Record
+	h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late")
+	}), httplog.WithRecover(), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Status != http.StatusAccepted || recordFields(rec)["panic"] != "late" {
+		t.Errorf("expected the written status to be logged got %d %v", rec.Status, rec.Attrs)
+	}
+}
+
+func TestWithRecover_abortHandler(t *testing.T) {
+	var rec httplog.Record
+	h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}), httplog.WithRecover(), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("expected ErrAbortHandler to be re-panicked got %v", v)
+		}
+		if recordFields(rec)["panic"] == nil {
+			t.Errorf("expected the panic to be logged")
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,313 @@
+package httplog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReplayEntry is a line of a replay file written by ReplayWriter.
+type ReplayEntry struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	Host   string    `json:"host"`
+	// URI is the request target, the path and query of the request.
+	URI    string      `json:"uri"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+	// Truncated is set when Body does not hold the whole body: it was
+	// longer than DefaultPeekLimit, the handler did not read all of it, or
+	// it could not be parsed to redact it.
+	Truncated bool `json:"truncated,omitempty"`
+	// Status is the status the request was answered with.
+	Status int `json:"status"`
+}
+
+// ReplayWriter captures requests as JSON lines of ReplayEntry so they can
+// be replayed, for example as shadow traffic against a staging environment,
+// with ReplayReader. It is created with NewReplayWriter. A ReplayWriter is
+// an Option so it can be passed to Wrap directly.
+//
+// Entries are written once the record of the request passed the filters of
+// Wrap, so skipped and sampled out requests are not captured, and take the
+// method, host, path and query from the record after options such as Map or
+// WithEncryptedFields ran. Headers holding credentials, such as
+// Authorization, Cookie or X-Api-Key, are not captured, and fields such as
+// password or access_token of form and JSON bodies are replaced with
+// "REDACTED". The body is captured as the handler reads it, up to
+// DefaultPeekLimit bytes.
+type ReplayWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewReplayWriter returns a ReplayWriter writing to w. Write failures are
+// counted in Stats.WriteErrors.
+func NewReplayWriter(w io.Writer) *ReplayWriter {
+	return &ReplayWriter{w: w}
+}
+
+func (w *ReplayWriter) apply(c *config) {
+	c.captures = append(c.captures, func(req *http.Request) func(rec Record) {
+		header := make(http.Header, len(req.Header))
+		for name, values := range req.Header {
+			if !sensitiveName(name) {
+				header[name] = values
+			}
+		}
+		body := &replayBody{eof: true}
+		if req.Body != nil && req.Body != http.NoBody {
+			body = &replayBody{r: req.Body}
+			req.Body = struct {
+				io.Reader
+				io.Closer
+			}{Reader: body, Closer: req.Body}
+		}
+		return func(rec Record) {
+			entry := ReplayEntry{
+				Time:   rec.Time,
+				Method: rec.Method,
+				Host:   rec.Host,
+				URI:    (&url.URL{Path: rec.Path, RawQuery: rec.Query}).RequestURI(),
+				Header: header,
+				Status: rec.Status,
+			}
+			entry.Body, entry.Truncated = body.buf, !body.eof
+			if len(entry.Body) > DefaultPeekLimit {
+				entry.Body, entry.Truncated = entry.Body[:DefaultPeekLimit], true
+			}
+			if !entry.Truncated {
+				var ok bool
+				if entry.Body, ok = redactBody(header.Get("Content-Type"), entry.Body); !ok {
+					// A body that could not be redacted is left out.
+					entry.Truncated = true
+				}
+			}
+			w.write(entry)
+		}
+	})
+}
+
+// replayBody keeps the first DefaultPeekLimit+1 bytes the handler reads of
+// a request body.
+type replayBody struct {
+	r   io.Reader
+	buf []byte
+	eof bool
+}
+
+func (b *replayBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if room := DefaultPeekLimit + 1 - len(b.buf); room > 0 {
+		b.buf = append(b.buf, p[:min(n, room)]...)
+	}
+	if errors.Is(err, io.EOF) {
+		b.eof = true
+	}
+	return n, err
+}
+
+// redactBody replaces the values of sensitive fields of form and JSON
+// bodies with "REDACTED". It reports false when the body could not be
+// parsed.
+func redactBody(contentType string, body []byte) ([]byte, bool) {
+	if len(body) == 0 {
+		return body, true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, false
+		}
+		redacted := false
+		for name := range values {
+			if sensitiveName(name) {
+				values[name], redacted = []string{"REDACTED"}, true
+			}
+		}
+		if !redacted {
+			return body, true
+		}
+		return []byte(values.Encode()), true
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var v any
+		if err := decoder.Decode(&v); err != nil {
+			return nil, false
+		}
+		if !redactJSON(v) {
+			return body, true
+		}
+		redacted, err := json.Marshal(v)
+		return redacted, err == nil
+	}
+	return body, true
+}
+
+// redactJSON replaces the values of sensitive keys in the objects of v and
+// reports whether it replaced any.
+func redactJSON(v any) bool {
+	redacted := false
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if sensitiveName(key) {
+				v[key], redacted = "REDACTED", true
+			} else if redactJSON(value) {
+				redacted = true
+			}
+		}
+	case []any:
+		for _, value := range v {
+			if redactJSON(value) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+func (w *ReplayWriter) write(entry ReplayEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		stats.writeErrors.Add(1)
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.w.Write(append(line, '\n')); err != nil {
+		stats.writeErrors.Add(1)
+	}
+}
+
+// DefaultMaxReplayLine is the longest line ReplayReader reads when
+// WithMaxLineSize is not given. Bodies are base64 encoded so it leaves room
+// for a full DefaultPeekLimit body.
+const DefaultMaxReplayLine = 2*DefaultPeekLimit + 64<<10
+
+// ReplayReader reads the requests of a replay file written by ReplayWriter.
+// It is created with NewReplayReader.
+type ReplayReader struct {
+	reader  *bufio.Reader
+	maxLine int
+	line    []byte
+	skipped int
+}
+
+// ReplayOption configures a ReplayReader.
+type ReplayOption func(*ReplayReader)
+
+// WithMaxLineSize sets the longest line, in bytes, a ReplayReader reads,
+// DefaultMaxReplayLine by default. Longer lines are skipped and counted by
+// Skipped.
+func WithMaxLineSize(n int) ReplayOption {
+	return func(r *ReplayReader) {
+		r.maxLine = max(n, 1)
+	}
+}
+
+// NewReplayReader returns a ReplayReader reading from r.
+func NewReplayReader(r io.Reader, options ...ReplayOption) *ReplayReader {
+	reader := &ReplayReader{
+		reader:  bufio.NewReader(r),
+		maxLine: DefaultMaxReplayLine,
+	}
+	for _, o := range options {
+		o(reader)
+	}
+	return reader
+}
+
+// Next returns the next entry of the file. Lines longer than the limit of
+// WithMaxLineSize are skipped. It returns io.EOF once all entries have been
+// read.
+func (r *ReplayReader) Next() (ReplayEntry, error) {
+	var entry ReplayEntry
+	for {
+		line, tooLong, err := r.readLine()
+		if tooLong {
+			r.skipped++
+		} else if line = bytes.TrimSpace(line); len(line) > 0 {
+			return entry, json.Unmarshal(line, &entry)
+		}
+		if err != nil {
+			return entry, err
+		}
+	}
+}
+
+// Skipped returns the number of lines Next skipped because they were longer
+// than the limit of WithMaxLineSize.
+func (r *ReplayReader) Skipped() int {
+	return r.skipped
+}
+
+// readLine reads the next line without keeping more than the line limit in
+// memory. tooLong reports a line over the limit, whose bytes are discarded.
+func (r *ReplayReader) readLine() (line []byte, tooLong bool, err error) {
+	r.line = r.line[:0]
+	for {
+		chunk, err := r.reader.ReadSlice('\n')
+		if !tooLong {
+			// Leave room for the newline.
+			if len(r.line)+len(chunk) > r.maxLine+1 {
+				tooLong, r.line = true, r.line[:0]
+			} else {
+				r.line = append(r.line, chunk...)
+			}
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		line = bytes.TrimSuffix(r.line, []byte("\n"))
+		if !tooLong && len(line) > r.maxLine {
+			tooLong, line = true, nil
+		}
+		return line, tooLong, err
+	}
+}
+
+// NextRequest returns a client request for the next entry whose body was
+// captured in full, for http.Client.Do. Its URL is that of the original
+// request over plain HTTP; set URL.Scheme, URL.Host and Host to replay it
+// against another server:
+//
+//	req.URL.Scheme, req.URL.Host, req.Host = "https", "staging.example.com", ""
+//
+// It returns io.EOF once all entries have been read.
+func (r *ReplayReader) NextRequest() (*http.Request, error) {
+	for {
+		entry, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if entry.Truncated {
+			continue
+		}
+		return entry.Request()
+	}
+}
+
+// Request returns a client request for the entry. See NextRequest.
+func (e ReplayEntry) Request() (*http.Request, error) {
+	req, err := http.NewRequest(e.Method, "http://"+e.Host+e.URI, bytes.NewReader(e.Body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = e.Header.Clone()
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	return req, nil
+}
@@ -0,0 +1,186 @@
+package httplog_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestReplayWriter(t *testing.T) {
+	var file bytes.Buffer
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"item":1}` && len(body) != httplog.DefaultPeekLimit+1 {
+			t.Errorf("expected the handler to read the whole body got %d bytes", len(body))
+		}
+		w.WriteHeader(http.StatusCreated)
+	}), httplog.NewReplayWriter(&file), httplog.RecordFunc(func(httplog.Record) {}))
+
+	r := httptest.NewRequest(http.MethodPost, "http://shop.example.com/orders?dry_run=1", strings.NewReader(`{"item":1}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer secret")
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+	large := httptest.NewRequest(http.MethodPut, "/uploads", strings.NewReader(strings.Repeat("x", httplog.DefaultPeekLimit+1)))
+	logMux.ServeHTTP(httptest.NewRecorder(), large)
+
+	if strings.Contains(file.String(), "secret") {
+		t.Errorf("expected credentials to be left out got %s", file.String())
+	}
+
+	reader := httplog.NewReplayReader(bytes.NewReader(file.Bytes()))
+	entry, err := reader.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Status != http.StatusCreated || entry.Truncated {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	req, err := entry.Request()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if req.Method != http.MethodPost || req.URL.String() != "http://shop.example.com/orders?dry_run=1" || string(body) != `{"item":1}` {
+		t.Errorf("unexpected request %s %s %q", req.Method, req.URL, body)
+	}
+	if req.Header.Get("Content-Type") != "application/json" || req.Header.Get("Authorization") != "" {
+		t.Errorf("unexpected header %v", req.Header)
+	}
+
+	entry, err = reader.Next()
+	if err != nil || !entry.Truncated || len(entry.Body) != httplog.DefaultPeekLimit {
+		t.Errorf("expected a truncated entry got %d bytes, %v", len(entry.Body), err)
+	}
+	if _, err := reader.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF got %v", err)
+	}
+}
+
+func TestReplayReader_NextRequest(t *testing.T) {
+	file := strings.Join([]string{
+		`{"method":"PUT","host":"example.com","uri":"/big","truncated":true,"status":200}`,
+		``,
+		`{"method":"GET","host":"example.com","uri":"/users/1","status":200}`,
+	}, "\n")
+	var replayed []string
+	staging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		replayed = append(replayed, r.Method+" "+r.URL.Path)
+	}))
+	defer staging.Close()
+
+	reader := httplog.NewReplayReader(strings.NewReader(file))
+	for {
+		req, err := reader.NextRequest()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.URL.Host, req.Host = strings.TrimPrefix(staging.URL, "http://"), ""
+		res, err := staging.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+	}
+
+	if len(replayed) != 1 || replayed[0] != "GET /users/1" {
+		t.Errorf("expected only the complete entry to be replayed got %v", replayed)
+	}
+}
+
+func TestReplayReader_longLine(t *testing.T) {
+	file := strings.Join([]string{
+		`{"method":"POST","host":"example.com","uri":"/big","body":"` + strings.Repeat("A", 10000) + `","status":200}`,
+		`{"method":"GET","host":"example.com","uri":"/users/1","status":200}`,
+	}, "\n")
+
+	reader := httplog.NewReplayReader(strings.NewReader(file), httplog.WithMaxLineSize(5000))
+	entry, err := reader.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.URI != "/users/1" || reader.Skipped() != 1 {
+		t.Errorf("expected the long line to be skipped got %+v and %d skipped", entry, reader.Skipped())
+	}
+	if _, err := reader.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF got %v", err)
+	}
+}
+
+func TestReplayWriter_redacted(t *testing.T) {
+	var file bytes.Buffer
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+	}), httplog.NewReplayWriter(&file), httplog.WithPreflightSampling(0), httplog.RecordFunc(func(httplog.Record) {}))
+
+	r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"user":"ada","password":"hunter2","device":{"access_token":"abc"}}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Api-Key", "key-123")
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+	r = httptest.NewRequest(http.MethodPost, "/token", strings.NewReader("grant_type=refresh&refresh_token=xyz"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+	preflight := httptest.NewRequest(http.MethodOptions, "/login", nil)
+	preflight.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	logMux.ServeHTTP(httptest.NewRecorder(), preflight)
+
+	for _, secret := range []string{"hunter2", "abc", "key-123", "xyz"} {
+		if strings.Contains(file.String(), secret) {
+			t.Errorf("expected %q to be redacted got %s", secret, file.String())
+		}
+	}
+	reader := httplog.NewReplayReader(bytes.NewReader(file.Bytes()))
+	var paths []string
+	for {
+		entry, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, entry.URI)
+		if entry.URI == "/login" && !strings.Contains(string(entry.Body), `"user":"ada"`) {
+			t.Errorf("expected the other fields to be kept got %s", entry.Body)
+		}
+	}
+	if strings.Join(paths, ",") != "/login,/token" {
+		t.Errorf("expected the filtered preflight request to be left out got %v", paths)
+	}
+}
+
+func TestReplayWriter_streaming(t *testing.T) {
+	var file bytes.Buffer
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := make([]byte, 5)
+		_, _ = io.ReadFull(r.Body, p)
+	}), httplog.NewReplayWriter(&file), httplog.RecordFunc(func(httplog.Record) {}))
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go func() { _, _ = pw.Write([]byte("hello")) }()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/stream", pr))
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the handler to run before the body ends")
+	}
+
+	entry, err := httplog.NewReplayReader(&file).Next()
+	if err != nil || string(entry.Body) != "hello" || !entry.Truncated {
+		t.Errorf("expected the part the handler read got %q truncated %t, %v", entry.Body, entry.Truncated, err)
+	}
+}
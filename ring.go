@@ -0,0 +1,47 @@
+package httplog
+
+import "sync"
+
+// Ring keeps the most recent records in memory, for example for Viewer. It
+// is created with NewRing. A Ring is an Option so it can be passed to Wrap
+// directly.
+type Ring struct {
+	mu      sync.Mutex
+	records []Record
+	next    int
+	full    bool
+}
+
+// NewRing returns a Ring that keeps the last size records.
+func NewRing(size int) *Ring {
+	return &Ring{records: make([]Record, size)}
+}
+
+// Log stores rec, replacing the oldest record once the ring is full. The
+// request and response header are not kept so they can be garbage
+// collected.
+func (r *Ring) Log(rec Record) {
+	if len(r.records) == 0 {
+		return
+	}
+	rec.Request, rec.ResponseHeader, rec.writer = nil, nil, nil
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records[r.next] = rec
+	r.next = (r.next + 1) % len(r.records)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *Ring) apply(c *config) { RecordFunc(r.Log).apply(c) }
+
+// Records returns a copy of the stored records, oldest first.
+func (r *Ring) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]Record(nil), r.records[:r.next]...)
+	}
+	return append(append(make([]Record, 0, len(r.records)), r.records[r.next:]...), r.records[:r.next]...)
+}
@@ -0,0 +1,27 @@
+package httplog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestRing(t *testing.T) {
+	ring := httplog.NewRing(3)
+	if got := ring.Records(); len(got) != 0 {
+		t.Errorf("expected no records got %d", len(got))
+	}
+	for status := 200; status < 205; status++ {
+		ring.Log(httplog.Record{Status: status, Request: httptest.NewRequest(http.MethodGet, "/", nil)})
+	}
+
+	got := ring.Records()
+	if len(got) != 3 || got[0].Status != 202 || got[2].Status != 204 {
+		t.Fatalf("expected the last 3 records oldest first got %+v", got)
+	}
+	if got[0].Request != nil {
+		t.Errorf("expected the request not to be kept")
+	}
+}
@@ -0,0 +1,290 @@
+package httplog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// rollupSlots is the number of slots a Rollup window is split into.
+	rollupSlots = 10
+	// maxRollupRoutes bounds the routes per slot; further routes are
+	// counted under the route "other".
+	maxRollupRoutes = 1000
+	// maxRollupSamples bounds the durations sampled per route and slot.
+	maxRollupSamples = 512
+	// maxExemplars is the number of slowest requests kept per route.
+	maxExemplars = 3
+)
+
+// Rollup keeps per route request counts, error counts and latency
+// percentiles over a rolling window, for capacity reviews without a metrics
+// backend. It is created with NewRollup and reported by RollupReport. A
+// Rollup is an Option so it can be passed to Wrap directly.
+//
+// Routes are keyed by method and the Route of the record, or the path when
+// it has none, so use WithNormalizedPath to keep the number of routes
+// bounded.
+type Rollup struct {
+	slot time.Duration
+
+	mu sync.Mutex
+	// slots holds one slot more than the window so the oldest step
+	// LatencySummaries reports is not reused by the step in progress.
+	slots [rollupSlots + 1]rollupSlot
+}
+
+type rollupSlot struct {
+	start  time.Time
+	routes map[rollupKey]*rollupRoute
+}
+
+type rollupKey struct{ method, route string }
+
+type rollupRoute struct {
+	requests, errors int64
+	durations        []time.Duration
+	slowest          []Exemplar
+}
+
+// Exemplar identifies one of the slowest requests of a route so a summary
+// can be followed to its trace or log lines.
+type Exemplar struct {
+	TraceID   string        `json:"trace_id,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// addExemplar inserts e into slowest, which is ordered slowest first, keeping
+// at most maxExemplars.
+func addExemplar(slowest []Exemplar, e Exemplar) []Exemplar {
+	i := sort.Search(len(slowest), func(i int) bool { return slowest[i].Duration < e.Duration })
+	if i >= maxExemplars {
+		return slowest
+	}
+	if len(slowest) < maxExemplars {
+		slowest = append(slowest, Exemplar{})
+	}
+	copy(slowest[i+1:], slowest[i:])
+	slowest[i] = e
+	return slowest
+}
+
+// RouteSummary is the rollup of a route over a Rollup window. Errors counts
+// responses with a status of 500 or more. Exemplars are the slowest
+// requests with a trace or request ID, slowest first.
+type RouteSummary struct {
+	Method    string        `json:"method"`
+	Route     string        `json:"route"`
+	Requests  int64         `json:"requests"`
+	Errors    int64         `json:"errors"`
+	P50       time.Duration `json:"p50"`
+	P95       time.Duration `json:"p95"`
+	Max       time.Duration `json:"max"`
+	Exemplars []Exemplar    `json:"exemplars,omitempty"`
+}
+
+// NewRollup returns a Rollup covering the last window, which advances in
+// steps of a tenth of window. Percentiles are computed from a sample of at
+// most 512 durations per route and step.
+func NewRollup(window time.Duration) *Rollup {
+	slot := window / rollupSlots
+	if slot <= 0 {
+		slot = 1
+	}
+	return &Rollup{slot: slot}
+}
+
+// Log counts rec.
+func (r *Rollup) Log(rec Record) {
+	if rec.Type != "" && rec.Type != TypeRequest {
+		return
+	}
+	name := rec.Route
+	if name == "" {
+		name = rec.Path
+	}
+	key := rollupKey{method: rec.Method, route: name}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := timeNow()
+	start := now.Truncate(r.slot)
+	s := &r.slots[int(start.UnixNano()/int64(r.slot))%len(r.slots)]
+	if !s.start.Equal(start) {
+		s.start, s.routes = start, make(map[rollupKey]*rollupRoute)
+	}
+	route, ok := s.routes[key]
+	if !ok {
+		if len(s.routes) >= maxRollupRoutes {
+			key = rollupKey{route: "other"}
+			route = s.routes[key]
+		}
+		if route == nil {
+			route = new(rollupRoute)
+			s.routes[key] = route
+		}
+	}
+	route.requests++
+	if rec.Status >= 500 {
+		route.errors++
+	}
+	if len(route.durations) < maxRollupSamples {
+		route.durations = append(route.durations, rec.Duration)
+	} else if i := rand.Int63n(route.requests); i < maxRollupSamples {
+		route.durations[i] = rec.Duration
+	}
+	if rec.TraceID != "" || rec.RequestID != "" {
+		route.slowest = addExemplar(route.slowest, Exemplar{TraceID: rec.TraceID, RequestID: rec.RequestID, Duration: rec.Duration})
+	}
+}
+
+func (r *Rollup) apply(c *config) { RecordFunc(r.Log).apply(c) }
+
+// Summaries returns the summary of every route seen in the window, the
+// routes with the most requests first. The window ends with the step in
+// progress.
+func (r *Rollup) Summaries() []RouteSummary {
+	return r.summaries(timeNow().Truncate(r.slot).Add(r.slot))
+}
+
+// summaries merges the slots of the window ending at end.
+func (r *Rollup) summaries(end time.Time) []RouteSummary {
+	type merged struct {
+		RouteSummary
+		durations []time.Duration
+	}
+	routes := make(map[rollupKey]*merged)
+	r.mu.Lock()
+	oldest := end.Add(-r.slot * rollupSlots)
+	for _, s := range r.slots {
+		if s.start.Before(oldest) || !s.start.Before(end) {
+			continue
+		}
+		for key, route := range s.routes {
+			m, ok := routes[key]
+			if !ok {
+				m = &merged{RouteSummary: RouteSummary{Method: key.method, Route: key.route}}
+				routes[key] = m
+			}
+			m.Requests += route.requests
+			m.Errors += route.errors
+			m.durations = append(m.durations, route.durations...)
+			for _, e := range route.slowest {
+				m.Exemplars = addExemplar(m.Exemplars, e)
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	summaries := make([]RouteSummary, 0, len(routes))
+	for _, m := range routes {
+		sort.Slice(m.durations, func(i, j int) bool { return m.durations[i] < m.durations[j] })
+		m.P50 = percentile(m.durations, 0.50)
+		m.P95 = percentile(m.durations, 0.95)
+		m.Max = m.durations[len(m.durations)-1]
+		summaries = append(summaries, m.RouteSummary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Requests != summaries[j].Requests {
+			return summaries[i].Requests > summaries[j].Requests
+		}
+		return summaries[i].Method+" "+summaries[i].Route < summaries[j].Method+" "+summaries[j].Route
+	})
+	return summaries
+}
+
+// LatencySummaries passes a record of type TypeSummary for each route of
+// rollup to fn every interval until ctx is done. Unlike Summaries it covers
+// the window of complete steps before the step in progress, which is left
+// to the next summary, so with the window of the Rollup as interval each
+// request is summarized once. Run it in its own goroutine:
+//
+//	go httplog.LatencySummaries(ctx, rollup, time.Minute, sink)
+//
+// The attrs are method, route, requests, errors, p50, p95, max and
+// exemplars, the Exemplars of the slowest requests, so a slow summary line
+// leads straight to a trace.
+func LatencySummaries(ctx context.Context, rollup *Rollup, interval time.Duration, fn RecordFunc) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := timeNow()
+			for _, s := range rollup.summaries(now.Truncate(rollup.slot)) {
+				attrs := []slog.Attr{
+					slog.String("method", s.Method),
+					slog.String("route", s.Route),
+					slog.Int64("requests", s.Requests),
+					slog.Int64("errors", s.Errors),
+					slog.Duration("p50", s.P50),
+					slog.Duration("p95", s.P95),
+					slog.Duration("max", s.Max),
+				}
+				if len(s.Exemplars) > 0 {
+					attrs = append(attrs, slog.Any("exemplars", s.Exemplars))
+				}
+				fn(Record{Type: TypeSummary, Time: now, Attrs: attrs})
+			}
+		}
+	}
+}
+
+// percentile returns the p-th percentile of the sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted))*p+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// RollupReport returns a handler reporting the top routes of rollup as JSON,
+// or as a text table with ?format=text. The n query parameter sets the
+// number of routes, 10 by default, and sort orders them by requests, errors
+// or p95. Like Viewer the report shows routes so mount it behind
+// authentication.
+func RollupReport(rollup *Rollup) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		n, err := strconv.Atoi(query.Get("n"))
+		if err != nil || n <= 0 {
+			n = 10
+		}
+		summaries := rollup.Summaries()
+		switch query.Get("sort") {
+		case "errors":
+			sort.SliceStable(summaries, func(i, j int) bool { return summaries[i].Errors > summaries[j].Errors })
+		case "p95":
+			sort.SliceStable(summaries, func(i, j int) bool { return summaries[i].P95 > summaries[j].P95 })
+		}
+		if len(summaries) > n {
+			summaries = summaries[:n]
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		if query.Get("format") == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "METHOD\tROUTE\tREQUESTS\tERRORS\tP50\tP95\tMAX")
+			for _, s := range summaries {
+				fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%s\t%s\n", s.Method, s.Route, s.Requests, s.Errors, s.P50, s.P95, s.Max)
+			}
+			_ = tw.Flush()
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(summaries)
+	})
+}
@@ -0,0 +1,120 @@
+package httplog_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestRollup(t *testing.T) {
+	rollup := httplog.NewRollup(time.Hour)
+	for i := 1; i <= 100; i++ {
+		rollup.Log(httplog.Record{Method: http.MethodGet, Route: "/users/{id}", Status: http.StatusOK, Duration: time.Duration(i) * time.Millisecond})
+	}
+	rollup.Log(httplog.Record{Method: http.MethodPost, Path: "/orders", Status: http.StatusBadGateway, Duration: time.Second})
+	rollup.Log(httplog.Record{Type: httplog.TypeRuntime})
+
+	summaries := rollup.Summaries()
+	if len(summaries) != 2 {
+		t.Fatalf("expected 2 routes got %+v", summaries)
+	}
+	users := summaries[0]
+	if users.Route != "/users/{id}" || users.Requests != 100 || users.Errors != 0 {
+		t.Errorf("unexpected summary %+v", users)
+	}
+	if users.P50 != 50*time.Millisecond || users.P95 != 95*time.Millisecond || users.Max != 100*time.Millisecond {
+		t.Errorf("unexpected percentiles %+v", users)
+	}
+	if orders := summaries[1]; orders.Method != http.MethodPost || orders.Route != "/orders" || orders.Errors != 1 {
+		t.Errorf("unexpected summary %+v", orders)
+	}
+}
+
+func TestRollup_window(t *testing.T) {
+	rollup := httplog.NewRollup(50 * time.Millisecond)
+	rollup.Log(httplog.Record{Method: http.MethodGet, Path: "/", Status: http.StatusOK})
+	time.Sleep(60 * time.Millisecond)
+	if summaries := rollup.Summaries(); len(summaries) != 0 {
+		t.Errorf("expected requests outside the window to be forgotten got %+v", summaries)
+	}
+}
+
+func TestRollupReport(t *testing.T) {
+	rollup := httplog.NewRollup(time.Hour)
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}), rollup, httplog.RecordFunc(func(httplog.Record) {}))
+	for _, path := range []string{"/a", "/a", "/b", "/fail"} {
+		logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	report := httplog.RollupReport(rollup)
+
+	w := httptest.NewRecorder()
+	report.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?n=1&sort=errors", nil))
+	var summaries []httplog.RouteSummary
+	if err := json.Unmarshal(w.Body.Bytes(), &summaries); err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 1 || summaries[0].Route != "/fail" {
+		t.Errorf("expected the route with the most errors got %+v", summaries)
+	}
+
+	w = httptest.NewRecorder()
+	report.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?format=text", nil))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "METHOD") || !strings.Contains(lines[1], "/a ") {
+		t.Errorf("unexpected text report %q", w.Body.String())
+	}
+}
+
+func TestLatencySummaries(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	defer httplog.SetClock(func() time.Time { return now })()
+	rollup := httplog.NewRollup(time.Hour)
+	for i, traceID := range []string{"t1", "t2", "t3", "t4", ""} {
+		rollup.Log(httplog.Record{Method: http.MethodGet, Route: "/search", Status: http.StatusOK, TraceID: traceID, Duration: time.Duration(i+1) * time.Millisecond})
+	}
+	// The step in progress is left to the next summary.
+	now = now.Add(6 * time.Minute)
+	rollup.Log(httplog.Record{Method: http.MethodGet, Route: "/search", Status: http.StatusOK, Duration: time.Second})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	records := make(chan httplog.Record, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		httplog.LatencySummaries(ctx, rollup, time.Millisecond, func(rec httplog.Record) {
+			select {
+			case records <- rec:
+			default:
+			}
+		})
+	}()
+	rec := <-records
+	cancel()
+	<-done
+
+	if rec.Type != httplog.TypeSummary {
+		t.Errorf("expected a %s record got %s", httplog.TypeSummary, rec.Type)
+	}
+	fields := recordFields(rec)
+	if fields["route"] != "/search" || fields["requests"] != int64(5) || fields["max"] != 5*time.Millisecond {
+		t.Errorf("unexpected summary %v", fields)
+	}
+	exemplars, _ := fields["exemplars"].([]httplog.Exemplar)
+	var traceIDs []string
+	for _, e := range exemplars {
+		traceIDs = append(traceIDs, e.TraceID)
+	}
+	if strings.Join(traceIDs, ",") != "t4,t3,t2" {
+		t.Errorf("expected the trace IDs of the 3 slowest requests got %v", exemplars)
+	}
+}
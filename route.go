@@ -0,0 +1,70 @@
+package httplog
+
+import "strings"
+
+// WithNormalizedPath sets the Route of each record to NormalizePath of the
+// request path. JSON logs it as route next to the raw path, giving log and
+// metric aggregation a low cardinality key.
+func WithNormalizedPath() Option {
+	return optionFunc(func(c *config) {
+		c.normalize = NormalizePath
+	})
+}
+
+// NormalizePath replaces path segments that look like identifiers with
+// placeholders: numbers become {id}, UUIDs become {uuid}, and hexadecimal
+// hashes of at least 16 digits become {hash}. For example
+// "/users/42/orders/9b2f1c0e-3a4d-4b8e-9f71-2d0c5a6e8b13" becomes
+// "/users/{id}/orders/{uuid}".
+func NormalizePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case segment == "":
+		case isDigits(segment):
+			segments[i] = "{id}"
+		case isUUID(segment):
+			segments[i] = "{uuid}"
+		case len(segment) >= 16 && isHex(segment):
+			segments[i] = "{hash}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') && (c < 'A' || c > 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if i == 8 || i == 13 || i == 18 || i == 23 {
+			if s[i] != '-' {
+				return false
+			}
+			continue
+		}
+		if !isHex(s[i : i+1]) {
+			return false
+		}
+	}
+	return true
+}
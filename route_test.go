@@ -0,0 +1,42 @@
+package httplog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestNormalizePath(t *testing.T) {
+	for _, tt := range []struct {
+		Path, Want string
+	}{
+		{Path: "/", Want: "/"},
+		{Path: "/users/42", Want: "/users/{id}"},
+		{Path: "/users/42/orders/9b2f1c0e-3a4d-4b8e-9f71-2d0c5a6e8b13", Want: "/users/{id}/orders/{uuid}"},
+		{Path: "/blobs/e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", Want: "/blobs/{hash}"},
+		{Path: "/v2/users/", Want: "/v2/users/"},
+		{Path: "/posts/cafe", Want: "/posts/cafe"},
+	} {
+		if got := httplog.NormalizePath(tt.Path); got != tt.Want {
+			t.Errorf("NormalizePath(%q) = %q, want %q", tt.Path, got, tt.Want)
+		}
+	}
+}
+
+func TestWithNormalizedPath(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithNormalizedPath(), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	if rec.Path != "/users/42" {
+		t.Errorf("expected the raw path got %q", rec.Path)
+	}
+	if rec.Route != "/users/{id}" {
+		t.Errorf("expected the normalized route got %q", rec.Route)
+	}
+}
@@ -0,0 +1,57 @@
+package httplog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// RuntimeStats passes a record of type TypeRuntime to fn every interval
+// until ctx is done, giving basic runtime visibility next to the request
+// logs of deployments without a metrics stack. Run it in its own goroutine:
+//
+//	go httplog.RuntimeStats(ctx, time.Minute, sink)
+//
+// The attrs are the number of goroutines, heap_alloc and heap_sys in bytes,
+// the cumulative num_gc and gc_pause_total, and the number of collections
+// and the longest pause since the previous record as gc_since_last and
+// gc_pause_max.
+func RuntimeStats(ctx context.Context, interval time.Duration, fn RecordFunc) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var prev runtime.MemStats
+	runtime.ReadMemStats(&prev)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := timeNow()
+			var m runtime.MemStats
+			runtime.ReadMemStats(&m)
+			fn(Record{Type: TypeRuntime, Time: now, Attrs: runtimeAttrs(&prev, &m)})
+			prev = m
+		}
+	}
+}
+
+func runtimeAttrs(prev, m *runtime.MemStats) []slog.Attr {
+	since := m.NumGC - prev.NumGC
+	var max time.Duration
+	// PauseNs is a circular buffer of the most recent pauses.
+	for i := uint32(0); i < since && i < uint32(len(m.PauseNs)); i++ {
+		if d := time.Duration(m.PauseNs[(m.NumGC-1-i)%uint32(len(m.PauseNs))]); d > max {
+			max = d
+		}
+	}
+	return []slog.Attr{
+		slog.Int("goroutines", runtime.NumGoroutine()),
+		slog.Uint64("heap_alloc", m.HeapAlloc),
+		slog.Uint64("heap_sys", m.HeapSys),
+		slog.Uint64("num_gc", uint64(m.NumGC)),
+		slog.Duration("gc_pause_total", time.Duration(m.PauseTotalNs)),
+		slog.Uint64("gc_since_last", uint64(since)),
+		slog.Duration("gc_pause_max", max),
+	}
+}
@@ -0,0 +1,51 @@
+package httplog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestRuntimeStats(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	records := make(chan httplog.Record, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		httplog.RuntimeStats(ctx, time.Millisecond, func(rec httplog.Record) {
+			select {
+			case records <- rec:
+			default:
+			}
+		})
+	}()
+	runtime.GC()
+	rec := <-records
+	cancel()
+	<-done
+
+	if rec.Type != httplog.TypeRuntime || rec.Time.IsZero() {
+		t.Errorf("unexpected record %+v", rec)
+	}
+	fields := recordFields(rec)
+	for _, key := range []string{"goroutines", "heap_alloc", "heap_sys", "num_gc", "gc_pause_total", "gc_since_last", "gc_pause_max"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("expected %s got %v", key, fields)
+		}
+	}
+
+	var buf bytes.Buffer
+	httplog.JSONWriter(&buf, nil)(rec)
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line["type"] != httplog.TypeRuntime || line["method"] != nil {
+		t.Errorf("unexpected JSON line %v", line)
+	}
+}
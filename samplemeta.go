@@ -0,0 +1,104 @@
+package httplog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// maxSamplingKeys bounds the route and status pairs a SamplingCounter keeps
+// apart; further pairs are counted under the route "other".
+const maxSamplingKeys = 1000
+
+// SamplingCounter counts, per route and status, the records Wrap passed on
+// and those dropped by sampling and filtering options, so analytics can
+// weigh the sampled logs back up. It is created with NewSamplingCounter and
+// reported by SamplingMeta. A SamplingCounter is an Option so it can be
+// passed to Wrap directly; unlike sinks it does not replace the default
+// JSON output.
+type SamplingCounter struct {
+	mu     sync.Mutex
+	counts map[samplingKey]*samplingCount
+}
+
+type samplingKey struct {
+	route  string
+	status int
+}
+
+type samplingCount struct{ seen, dropped int64 }
+
+// NewSamplingCounter returns an empty SamplingCounter.
+func NewSamplingCounter() *SamplingCounter {
+	return &SamplingCounter{counts: make(map[samplingKey]*samplingCount)}
+}
+
+func (s *SamplingCounter) apply(c *config) {
+	c.completeHooks = append(c.completeHooks, s)
+	c.dropped = append(c.dropped, func(rec *Record) { s.add(*rec, 0, 1) })
+}
+
+// OnComplete implements CompleteHook.
+func (s *SamplingCounter) OnComplete(rec Record) { s.add(rec, 1, 0) }
+
+func (s *SamplingCounter) add(rec Record, seen, dropped int64) {
+	route := rec.Route
+	if route == "" {
+		route = rec.Path
+	}
+	key := samplingKey{route: route, status: rec.Status}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.counts[key]
+	if !ok {
+		if len(s.counts) >= maxSamplingKeys {
+			key.route = "other"
+			c = s.counts[key]
+		}
+		if c == nil {
+			c = new(samplingCount)
+			s.counts[key] = c
+		}
+	}
+	c.seen += seen
+	c.dropped += dropped
+}
+
+// SamplingMeta passes a record of type TypeSampling to fn every interval
+// until ctx is done for each route and status of counter with dropped
+// records since the previous interval. The attrs are route, status, kept,
+// dropped and sample_rate, the fraction kept, so a count of logged requests
+// can be divided by sample_rate to estimate the real one. Run it in its own
+// goroutine:
+//
+//	go httplog.SamplingMeta(ctx, counter, time.Minute, sink)
+func SamplingMeta(ctx context.Context, counter *SamplingCounter, interval time.Duration, fn RecordFunc) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := timeNow()
+			counter.mu.Lock()
+			counts := counter.counts
+			counter.counts = make(map[samplingKey]*samplingCount)
+			counter.mu.Unlock()
+			for key, c := range counts {
+				if c.dropped == 0 {
+					continue
+				}
+				kept := c.seen - c.dropped
+				fn(Record{Type: TypeSampling, Time: now, Attrs: []slog.Attr{
+					slog.String("route", key.route),
+					slog.Int("status", key.status),
+					slog.Int64("kept", kept),
+					slog.Int64("dropped", c.dropped),
+					slog.Float64("sample_rate", float64(kept)/float64(c.seen)),
+				}})
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package httplog_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestSamplingMeta(t *testing.T) {
+	counter := httplog.NewSamplingCounter()
+	var logged int
+	logMux := httplog.Wrap(http.NotFoundHandler(), counter, httplog.WithHeadOptionsSampling(0), httplog.RecordFunc(func(httplog.Record) {
+		logged++
+	}))
+	for _, method := range []string{http.MethodHead, http.MethodHead, http.MethodGet, http.MethodGet} {
+		logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/items", nil))
+	}
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/other", nil))
+	if logged != 3 {
+		t.Fatalf("expected the counter not to replace the sink got %d records", logged)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	records := make(chan httplog.Record, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		httplog.SamplingMeta(ctx, counter, time.Millisecond, func(rec httplog.Record) { records <- rec })
+	}()
+	rec := <-records
+	cancel()
+	<-done
+
+	if len(records) != 0 {
+		t.Errorf("expected only routes with dropped records got %d more", len(records))
+	}
+	fields := recordFields(rec)
+	if rec.Type != httplog.TypeSampling || fields["route"] != "/items" || fields["status"] != int64(http.StatusNotFound) {
+		t.Errorf("unexpected record %s %v", rec.Type, fields)
+	}
+	if fields["kept"] != int64(2) || fields["dropped"] != int64(2) || fields["sample_rate"] != 0.5 {
+		t.Errorf("unexpected counts %v", fields)
+	}
+}
@@ -0,0 +1,70 @@
+package httplog
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// WithMinDuration logs only the given fraction of requests that complete in
+// less than d. A rate of 0 suppresses them entirely. Requests with a status
+// of 500 or more are always logged, so a fast failing endpoint stays visible
+// while the chatter of fast internal endpoints is cut down.
+func WithMinDuration(d time.Duration, rate float64) Option {
+	return withFilter(func(rec *Record) bool {
+		if rec.Duration >= d || rec.Status >= 500 {
+			return true
+		}
+		return sampled(rate)
+	})
+}
+
+// MethodPolicy is how WithMethodPolicy logs the requests of a method.
+type MethodPolicy struct {
+	// Level, when set, raises the level of the records to at least Level.
+	Level slog.Leveler
+	// Sample, when set, logs only the fraction Rate of the requests. Like
+	// for the other sampling options a Rate of 0 then logs none of them.
+	Sample bool
+	Rate   float64
+}
+
+// WithMethodPolicy applies the policy of each request's method to its
+// record, so mutating requests can be kept for auditing while reads are
+// sampled:
+//
+//	httplog.WithMethodPolicy(map[string]httplog.MethodPolicy{
+//		http.MethodGet:    {Sample: true, Rate: 0.01},
+//		http.MethodPost:   {Level: slog.LevelWarn},
+//		http.MethodDelete: {Level: slog.LevelWarn},
+//	})
+//
+// Methods without a policy are logged as usual and requests with a status of
+// 500 or more are never sampled out.
+func WithMethodPolicy(policies map[string]MethodPolicy) Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			if p, ok := policies[rec.Method]; ok && p.Level != nil {
+				rec.raiseLevel(p.Level.Level())
+			}
+		})
+		c.filters = append(c.filters, func(rec *Record) bool {
+			p, ok := policies[rec.Method]
+			if !ok || !p.Sample || rec.Status >= 500 {
+				return true
+			}
+			return p.Rate > 0 && sampled(p.Rate)
+		})
+	})
+}
+
+// WithHeadOptionsSampling logs only the given fraction of HEAD and OPTIONS
+// requests, which health checkers, monitors and CORS preflights send in
+// large numbers. A rate of 0 suppresses them entirely. Like
+// WithMethodPolicy, requests with a status of 500 or more are always logged.
+func WithHeadOptionsSampling(rate float64) Option {
+	return WithMethodPolicy(map[string]MethodPolicy{
+		http.MethodHead:    {Sample: true, Rate: rate},
+		http.MethodOptions: {Sample: true, Rate: rate},
+	})
+}
@@ -0,0 +1,88 @@
+package httplog_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithMinDuration(t *testing.T) {
+	var paths []string
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(20 * time.Millisecond)
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}), httplog.WithMinDuration(10*time.Millisecond, 0), httplog.RecordFunc(func(r httplog.Record) {
+		paths = append(paths, r.Path)
+	}))
+
+	for _, path := range []string{"/fast", "/slow", "/error"} {
+		logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if len(paths) != 2 || paths[0] != "/slow" || paths[1] != "/error" {
+		t.Errorf("expected only the fast successful request to be suppressed got %v", paths)
+	}
+}
+
+func TestWithMethodPolicy(t *testing.T) {
+	records := map[string]httplog.Record{}
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}), httplog.WithMethodPolicy(map[string]httplog.MethodPolicy{
+		http.MethodGet:    {Sample: true, Rate: 0},
+		http.MethodPost:   {Level: slog.LevelWarn},
+		http.MethodDelete: {Sample: true, Rate: 1},
+	}), httplog.RecordFunc(func(r httplog.Record) {
+		records[r.Method+" "+r.Path] = r
+	}))
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/"},
+		{http.MethodGet, "/error"},
+		{http.MethodPost, "/"},
+		{http.MethodDelete, "/"},
+		{http.MethodPut, "/"},
+	} {
+		logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+
+	if _, ok := records["GET /"]; ok {
+		t.Error("expected the GET request to be suppressed")
+	}
+	if _, ok := records["GET /error"]; !ok {
+		t.Error("expected the failed GET request to be logged")
+	}
+	if rec, ok := records["POST /"]; !ok || rec.Level() != slog.LevelWarn {
+		t.Errorf("expected the POST request to be logged at warn got %v", rec.Level())
+	}
+	for _, key := range []string{"DELETE /", "PUT /"} {
+		if rec, ok := records[key]; !ok || rec.Level() != slog.LevelInfo {
+			t.Errorf("expected %s to be logged at info", key)
+		}
+	}
+}
+
+func TestWithHeadOptionsSampling(t *testing.T) {
+	var methods []string
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithHeadOptionsSampling(0), httplog.RecordFunc(func(r httplog.Record) {
+		methods = append(methods, r.Method)
+	}))
+
+	for _, method := range []string{http.MethodHead, http.MethodOptions, http.MethodGet} {
+		logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/", nil))
+	}
+
+	if len(methods) != 1 || methods[0] != http.MethodGet {
+		t.Errorf("expected HEAD and OPTIONS requests to be suppressed got %v", methods)
+	}
+}
@@ -0,0 +1,101 @@
+package httplog
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+// sanitize replaces control characters, including newlines, with visible
+// escape sequences (nginx style \xHH, or \uHHHH outside ASCII) and invalid
+// UTF-8 with U+FFFD. Logged strings are attacker controlled so without this a
+// crafted path or header could forge log lines in line oriented sinks.
+func sanitize(s string) string {
+	if !needsSanitizing(s) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b.WriteRune(utf8.RuneError)
+		case r < 0x20 || r == 0x7f:
+			_, _ = fmt.Fprintf(&b, `\x%02X`, r)
+		case isUnsafeRune(r):
+			_, _ = fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+func needsSanitizing(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c >= 0x7f {
+			return !utf8.ValidString(s) || strings.IndexFunc(s, func(r rune) bool {
+				return r < 0x20 || r == 0x7f || isUnsafeRune(r)
+			}) >= 0
+		}
+	}
+	return false
+}
+
+// isUnsafeRune reports whether r is a C1 control character or a Unicode line
+// or paragraph separator.
+func isUnsafeRune(r rune) bool {
+	return (r >= 0x80 && r <= 0x9f) || r == '\u2028' || r == '\u2029'
+}
+
+// sensitiveNames are the parts of header, form field and JSON key names
+// that mark credentials, compared in lower case without "-" and "_".
+var sensitiveNames = []string{"authorization", "cookie", "password", "passwd", "secret", "token", "apikey", "credential"}
+
+// sensitiveName reports whether name, a header, form field or JSON key, holds
+// credentials that must not be logged, such as Authorization, Set-Cookie,
+// X-Api-Key or access_token.
+func sensitiveName(name string) bool {
+	name = strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(name))
+	for _, s := range sensitiveNames {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func sanitizeRecord(rec *Record) {
+	rec.Method = sanitize(rec.Method)
+	rec.Host = sanitize(rec.Host)
+	rec.Path = sanitize(rec.Path)
+	rec.Route = sanitize(rec.Route)
+	rec.Query = sanitize(rec.Query)
+	rec.UserAgent = sanitize(rec.UserAgent)
+	rec.ClientIP = sanitize(rec.ClientIP)
+	rec.RequestID = sanitize(rec.RequestID)
+	for i, a := range rec.Attrs {
+		rec.Attrs[i] = sanitizeAttr(a)
+	}
+}
+
+func sanitizeAttr(a slog.Attr) slog.Attr {
+	a.Key = sanitize(a.Key)
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if s := a.Value.String(); needsSanitizing(s) {
+			a.Value = slog.StringValue(sanitize(s))
+		}
+	case slog.KindGroup:
+		group := a.Value.Group()
+		attrs := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			attrs[i] = sanitizeAttr(ga)
+		}
+		a.Value = slog.GroupValue(attrs...)
+	}
+	return a
+}
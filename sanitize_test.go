@@ -0,0 +1,55 @@
+package httplog_test
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWrap_sanitize(t *testing.T) {
+	var out bytes.Buffer
+	logger := log.New(&out, "", 0)
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithBaggage("team"), httplog.JSON(logger, logger))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.URL.Path = "/a\n{\"type\": \"HTTP_REQUEST\"}"
+	r.Header.Set("User-Agent", "curl\u2028\x1b[31m")
+	r.Header.Set(httplog.RequestIDHeader, "id\r\n")
+	r.Header.Set(httplog.BaggageHeader, "team=a%0Ab")
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	line := out.String()
+	if strings.Count(line, "\n") != 1 {
+		t.Errorf("expected a single line got %q", line)
+	}
+	for _, want := range []string{
+		`"path": "/a\\x0A{\"type\": \"HTTP_REQUEST\"}"`,
+		`"user_agent": "curl\\u2028\\x1B[31m"`,
+		`"request_id": "id\\x0D\\x0A"`,
+		`"baggage": {"team": "a\\x0Ab"}`,
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("expected %q to contain %q", line, want)
+		}
+	}
+}
+
+func TestWrap_sanitizeUnchanged(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/caf%C3%A9", nil)
+	r.Header.Set("User-Agent", "Mozilla/5.0 (日本語)")
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	if rec.Path != "/café" || rec.UserAgent != "Mozilla/5.0 (日本語)" {
+		t.Errorf("expected printable strings unchanged got %q and %q", rec.Path, rec.UserAgent)
+	}
+}
@@ -0,0 +1,138 @@
+package httplog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+
+	"github.com/crhntr/httplog"
+)
+
+var updateSchema = flag.Bool("update", false, "regenerate httplog.schema.json")
+
+// jsonSchema generates the JSON Schema of the JSON output from the exported
+// fields of Record.
+func jsonSchema() map[string]any {
+	properties := map[string]any{
+		"type":   map[string]any{"const": "HTTP_REQUEST"},
+		"schema": map[string]any{"const": httplog.SchemaVersion},
+	}
+	required := []string{"type", "schema"}
+	rt := reflect.TypeOf(httplog.Record{})
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		var property map[string]any
+		switch {
+		case !field.IsExported(), field.Name == "Type":
+			continue
+		case field.Type == reflect.TypeOf(time.Duration(0)):
+			property = map[string]any{
+				"type":        []string{"string", "number"},
+				"description": "A Go duration string or a number, as set by WithDurationFormat.",
+			}
+		case field.Type.Kind() == reflect.String:
+			property = map[string]any{"type": "string"}
+		case field.Type.Kind() == reflect.Int:
+			property = map[string]any{"type": "integer"}
+		default:
+			// Request, ResponseHeader, Time and Attrs are not encoded as
+			// fields of their own.
+			continue
+		}
+		name := snakeCase(field.Name)
+		properties[name] = property
+		switch name {
+		case "method", "path", "duration", "status":
+			required = append(required, name)
+		}
+	}
+	return map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"$id":                  "https://github.com/crhntr/httplog/httplog.schema.json",
+		"title":                "httplog record",
+		"description":          "A line of JSON output. Fields added by options, such as WithBaggage, are additional properties.",
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": true,
+	}
+}
+
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func TestJSONSchema(t *testing.T) {
+	want, err := json.MarshalIndent(jsonSchema(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = append(want, '\n')
+	if *updateSchema {
+		if err := os.WriteFile("httplog.schema.json", want, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile("httplog.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("httplog.schema.json is out of date, run go test -run TestJSONSchema -update")
+	}
+}
+
+func TestJSON_matchesSchema(t *testing.T) {
+	var out bytes.Buffer
+	fn := httplog.JSONWriter(&out, nil)
+	fn(httplog.Record{
+		Method:    http.MethodGet,
+		Host:      "api.example.com",
+		Path:      "/items/1",
+		Route:     "/items/{id}",
+		Query:     "a=1",
+		UserAgent: "test",
+		ClientIP:  "192.0.2.1",
+		Status:    http.StatusOK,
+		Duration:  time.Millisecond,
+		RequestID: "some-id",
+		TraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+		Attrs:     []slog.Attr{slog.Bool("ok", true)},
+	})
+	var line map[string]any
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+
+	schema := jsonSchema()
+	properties := schema["properties"].(map[string]any)
+	for _, name := range schema["required"].([]string) {
+		if _, ok := line[name]; !ok {
+			t.Errorf("expected required field %s", name)
+		}
+	}
+	for name := range properties {
+		if _, ok := line[name]; !ok {
+			t.Errorf("expected the output to have property %s", name)
+		}
+	}
+	if line["schema"] != httplog.SchemaVersion {
+		t.Errorf("expected schema %q got %v", httplog.SchemaVersion, line["schema"])
+	}
+}
@@ -0,0 +1,83 @@
+package httplog
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// SecurityRule flags suspicious requests for WithSecurityRules.
+type SecurityRule struct {
+	Name  string
+	Match func(req *http.Request) bool
+}
+
+// MaxURLLength is the request URI length above which the "long_url" rule
+// matches.
+const MaxURLLength = 2048
+
+// DefaultSecurityRules are the rules WithSecurityRules uses when it is not
+// given any: "path_traversal", "null_byte", "long_url" and "exploit_path",
+// which matches probes for well known files and admin pages such as /.env
+// and /wp-login.php.
+var DefaultSecurityRules = []SecurityRule{
+	{Name: "path_traversal", Match: func(req *http.Request) bool {
+		uri := strings.ToLower(req.RequestURI)
+		for _, p := range []string{"../", "..\\", "..%2f", "..%5c", "%2e%2e", "%252e%252e"} {
+			if strings.Contains(uri, p) {
+				return true
+			}
+		}
+		return strings.Contains(req.URL.Path, "../")
+	}},
+	{Name: "null_byte", Match: func(req *http.Request) bool {
+		return strings.Contains(req.RequestURI, "%00") || strings.ContainsRune(req.URL.Path, 0)
+	}},
+	{Name: "long_url", Match: func(req *http.Request) bool {
+		return len(req.RequestURI) > MaxURLLength
+	}},
+	{Name: "exploit_path", Match: func(req *http.Request) bool {
+		path := strings.ToLower(req.URL.Path)
+		for _, p := range exploitPaths {
+			if strings.HasPrefix(path, p) {
+				return true
+			}
+		}
+		return false
+	}},
+}
+
+var exploitPaths = []string{
+	"/.env", "/.git/", "/.aws/", "/.ssh/", "/.htaccess", "/.ds_store",
+	"/wp-admin", "/wp-login.php", "/xmlrpc.php", "/phpmyadmin", "/pma/",
+	"/cgi-bin/", "/etc/passwd", "/actuator", "/vendor/phpunit", "/server-status",
+	"/boaform/", "/hnap1", "/console/", "/solr/admin",
+}
+
+// WithSecurityRules adds a security_flag field naming the matching rules,
+// separated by commas, to records of suspicious requests and raises their
+// level to at least slog.LevelWarn so they stand out to a SIEM.
+// DefaultSecurityRules is used when no rules are given.
+func WithSecurityRules(rules ...SecurityRule) Option {
+	if len(rules) == 0 {
+		rules = DefaultSecurityRules
+	}
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			if rec.Request == nil {
+				return
+			}
+			var flags []string
+			for _, rule := range rules {
+				if rule.Match(rec.Request) {
+					flags = append(flags, rule.Name)
+				}
+			}
+			if len(flags) == 0 {
+				return
+			}
+			rec.Attrs = append(rec.Attrs, slog.String("security_flag", strings.Join(flags, ",")))
+			rec.raiseLevel(slog.LevelWarn)
+		})
+	})
+}
@@ -0,0 +1,57 @@
+package httplog_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithSecurityRules(t *testing.T) {
+	for _, tt := range []struct {
+		Name  string
+		URI   string
+		Flags string
+	}{
+		{Name: "clean", URI: "/items/1?page=2"},
+		{Name: "traversal", URI: "/static/..%2f..%2fetc/hosts", Flags: "path_traversal"},
+		{Name: "null byte", URI: "/file.txt%00.png", Flags: "null_byte"},
+		{Name: "long", URI: "/?q=" + strings.Repeat("a", httplog.MaxURLLength), Flags: "long_url"},
+		{Name: "exploit", URI: "/.env", Flags: "exploit_path"},
+		{Name: "several", URI: "/.git/..%2fconfig", Flags: "path_traversal,exploit_path"},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var rec httplog.Record
+			h := httplog.Wrap(http.NotFoundHandler(), httplog.WithSecurityRules(), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.URI, nil))
+
+			got, _ := recordFields(rec)["security_flag"].(string)
+			if got != tt.Flags {
+				t.Errorf("expected security_flag %q got %q", tt.Flags, got)
+			}
+			wantLevel := slog.LevelInfo
+			if tt.Flags != "" {
+				wantLevel = slog.LevelWarn
+			}
+			if rec.Level() != wantLevel {
+				t.Errorf("expected level %s got %s", wantLevel, rec.Level())
+			}
+		})
+	}
+}
+
+func TestWithSecurityRules_custom(t *testing.T) {
+	var rec httplog.Record
+	h := httplog.Wrap(http.NotFoundHandler(), httplog.WithSecurityRules(httplog.SecurityRule{
+		Name:  "admin",
+		Match: func(req *http.Request) bool { return strings.HasPrefix(req.URL.Path, "/admin") },
+	}), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+
+	if got := recordFields(rec)["security_flag"]; got != "admin" {
+		t.Errorf("expected the custom rule to match got %v", got)
+	}
+}
@@ -0,0 +1,98 @@
+package httplog
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+)
+
+// Server sets up srv to log its requests and returns it. It wraps
+// srv.Handler, or http.DefaultServeMux when it is nil, with Wrap, the given
+// options and WithConnectionFields, and installs ConnContext and ConnState
+// hooks, calling an existing srv.ConnContext and srv.ConnState first, so
+// records carry the connection fields including conn_idle. When
+// srv.ErrorLog is nil, errors net/http logs itself, such as failed TLS
+// handshakes, are logged with the logger of WithContextLogger at level error
+// when the options include it, and passed to the RecordFunc sinks of the
+// options through ErrorLog otherwise; Func sinks do not receive them. Client addresses in these errors are
+// anonymized by WithMaskedIP or WithHashedIP, and records also pass through
+// the finalizers of options such as Map or WithEncryptedFields, which see
+// the client address as ClientIP.
+//
+//	srv := httplog.Server(&http.Server{Addr: ":8080", Handler: mux}, httplog.WithContextLogger(logger))
+//	log.Fatal(srv.ListenAndServe())
+func Server(srv *http.Server, options ...Option) *http.Server {
+	handler := srv.Handler
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	c := newConfig(append([]Option{WithConnectionFields()}, options...))
+	srv.Handler = c.wrap(handler)
+
+	conns := new(connTracker)
+	connContext := srv.ConnContext
+	srv.ConnContext = func(ctx context.Context, conn net.Conn) context.Context {
+		if connContext != nil {
+			ctx = connContext(ctx, conn)
+		}
+		return conns.connContext(ctx, conn)
+	}
+	connState := srv.ConnState
+	srv.ConnState = func(conn net.Conn, state http.ConnState) {
+		if connState != nil {
+			connState(conn, state)
+		}
+		conns.connState(conn, state)
+	}
+
+	switch {
+	case srv.ErrorLog != nil:
+	case c.logger != nil:
+		logger := slog.NewLogLogger(c.logger.Handler(), slog.LevelError)
+		if c.anonymize == nil {
+			srv.ErrorLog = logger
+			break
+		}
+		srv.ErrorLog = log.New(writerFunc(func(p []byte) (int, error) {
+			logger.Print(anonymizeServerError(string(p), c.anonymize))
+			return len(p), nil
+		}), "", 0)
+	default:
+		srv.ErrorLog = ErrorLog(c.serverErrorFunc())
+	}
+	return srv
+}
+
+// serverErrorFunc returns the RecordFunc for the records of ErrorLog. The
+// client address is set as ClientIP, anonymized and finalized like that of
+// a request, and written back to remote_addr and error_log.
+func (c *config) serverErrorFunc() RecordFunc {
+	fn := c.recordFunc()
+	return func(rec Record) {
+		addr, host, port := serverErrorAddr(rec)
+		if host != "" {
+			rec.ClientIP = host
+			if c.anonymize != nil {
+				rec.ClientIP = c.anonymize(host)
+			}
+			addr = replaceServerErrorAddr(&rec, addr, rec.ClientIP, port)
+		}
+		sanitizeRecord(&rec)
+		c.limits.truncate(&rec)
+		ip := rec.ClientIP
+		for _, finalize := range c.finalizers {
+			finalize(&rec)
+		}
+		if host != "" && rec.ClientIP != ip {
+			replaceServerErrorAddr(&rec, addr, rec.ClientIP, port)
+		}
+		fn(rec)
+	}
+}
+
+// writerFunc is an io.Writer calling itself.
+type writerFunc func(p []byte) (int, error)
+
+func (fn writerFunc) Write(p []byte) (int, error) { return fn(p) }
@@ -0,0 +1,107 @@
+package httplog_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestServer(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	records := make(chan httplog.Record, 2)
+	var states atomic.Int32
+	srv := httplog.Server(&http.Server{Handler: http.NotFoundHandler(), ConnState: func(net.Conn, http.ConnState) {
+		states.Add(1)
+	}}, httplog.WithContextLogger(logger), httplog.RecordFunc(func(rec httplog.Record) {
+		records <- rec
+	}))
+	server := httptest.NewUnstartedServer(srv.Handler)
+	server.Config = srv
+	server.Start()
+	defer server.Close()
+
+	client := server.Client()
+	for i := 0; i < 2; i++ {
+		res, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+	}
+	<-records
+	rec := <-records
+	if fields := recordFields(rec); rec.Status != http.StatusNotFound || fields["conn_reused"] != true {
+		t.Errorf("expected the second request to be logged on a reused connection got %v", rec.Attrs)
+	} else if idle, _ := fields["conn_idle"].(time.Duration); idle <= 0 {
+		t.Errorf("expected the idle time of the connection got %v", fields["conn_idle"])
+	}
+	if states.Load() == 0 {
+		t.Error("expected the existing ConnState to be called")
+	}
+
+	if srv.ErrorLog == nil {
+		t.Fatal("expected an ErrorLog")
+	}
+	srv.ErrorLog.Print("http: TLS handshake error from 192.0.2.1:1234: EOF")
+	if !strings.Contains(buf.String(), `"level":"ERROR","msg":"http: TLS handshake error from 192.0.2.1:1234: EOF"`) {
+		t.Errorf("expected the server error to be logged with the logger got %q", buf.String())
+	}
+}
+
+func TestServer_errorLogAnonymized(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const line = "http: TLS handshake error from 192.0.2.1:1234: EOF"
+
+	for _, tt := range []struct {
+		name    string
+		options []httplog.Option
+	}{
+		{name: "masked", options: []httplog.Option{httplog.WithMaskedIP()}},
+		{name: "encrypted", options: []httplog.Option{httplog.WithEncryptedFields(aead, "client_ip")}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var rec httplog.Record
+			srv := httplog.Server(&http.Server{}, append(tt.options, httplog.RecordFunc(func(r httplog.Record) { rec = r }))...)
+			srv.ErrorLog.Print(line)
+
+			fields := recordFields(rec)
+			if rec.ClientIP == "" || rec.ClientIP == "192.0.2.1" {
+				t.Errorf("expected the client IP to be redacted got %q", rec.ClientIP)
+			}
+			for _, key := range []string{"remote_addr", "error_log"} {
+				if s, _ := fields[key].(string); s == "" || strings.Contains(s, "192.0.2.1") {
+					t.Errorf("expected the client address to be redacted from %s got %q", key, s)
+				}
+			}
+		})
+	}
+
+	t.Run("logger", func(t *testing.T) {
+		var buf bytes.Buffer
+		srv := httplog.Server(&http.Server{}, httplog.WithMaskedIP(), httplog.WithContextLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+		srv.ErrorLog.Print(line)
+		if !strings.Contains(buf.String(), "from 192.0.2.0:1234") {
+			t.Errorf("expected the masked address to be logged got %q", buf.String())
+		}
+	})
+}
@@ -0,0 +1,32 @@
+package httplog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SetQueueWait records how long a concurrency limiter queued the request
+// before letting it through, logged as queue_wait. Calling it again, for
+// example from nested limiters, replaces the value. SetQueueWait does
+// nothing when ctx does not belong to a request handled by Wrap.
+func SetQueueWait(ctx context.Context, wait time.Duration) {
+	if info, ok := requestInfoFrom(ctx); ok {
+		info.setAttr(slog.Duration("queue_wait", wait))
+	}
+}
+
+// SetShed records that a load shedding middleware rejected the request,
+// logged as shed=true. A non-empty reason, such as "queue_full", is logged
+// as shed_reason. SetShed does nothing when ctx does not belong to a request
+// handled by Wrap.
+func SetShed(ctx context.Context, reason string) {
+	info, ok := requestInfoFrom(ctx)
+	if !ok {
+		return
+	}
+	info.setAttr(slog.Bool("shed", true))
+	if reason != "" {
+		info.setAttr(slog.String("shed_reason", reason))
+	}
+}
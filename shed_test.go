@@ -0,0 +1,45 @@
+package httplog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+// limiter is a minimal concurrency limiter reporting to httplog.
+func limiter(slots chan struct{}, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			httplog.SetQueueWait(r.Context(), time.Since(start))
+			next.ServeHTTP(w, r)
+		default:
+			httplog.SetShed(r.Context(), "queue_full")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+}
+
+func TestSetShed(t *testing.T) {
+	var rec httplog.Record
+	slots := make(chan struct{}, 1)
+	h := httplog.Wrap(limiter(slots, http.NotFoundHandler()), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	fields := recordFields(rec)
+	if _, ok := fields["queue_wait"].(time.Duration); !ok || fields["shed"] != nil {
+		t.Errorf("expected queue_wait and no shed got %v", fields)
+	}
+
+	slots <- struct{}{}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	fields = recordFields(rec)
+	if fields["shed"] != true || fields["shed_reason"] != "queue_full" || rec.Status != http.StatusServiceUnavailable {
+		t.Errorf("expected a shed request got %d %v", rec.Status, fields)
+	}
+}
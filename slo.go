@@ -0,0 +1,189 @@
+package httplog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// SLO is a service level objective measured from the access log by an
+// SLOTracker.
+type SLO struct {
+	// Name identifies the SLO in its records.
+	Name string
+	// Objective is the target fraction of good requests, for example 0.999.
+	Objective float64
+	// Latency, when set, adds a latency SLI: the fraction of requests that
+	// took at most Latency, measured against the same Objective.
+	Latency time.Duration
+	// Match, when set, limits the SLO to the records it returns true for,
+	// for example those of one route.
+	Match func(rec Record) bool
+}
+
+// DefaultSLOWindows are the windows of the common multi-window burn rate
+// alerts, such as a fast burn over both 5 minutes and 1 hour.
+var DefaultSLOWindows = []time.Duration{5 * time.Minute, 30 * time.Minute, time.Hour, 6 * time.Hour}
+
+// SLOTracker counts the good and bad requests of an SLO over several
+// windows. It is created with NewSLOTracker and reported by SLOBurnRates. An
+// SLOTracker is an Option so it can be passed to Wrap directly.
+//
+// A request is available unless its status is 500 or more, and fast when
+// it took at most SLO.Latency.
+type SLOTracker struct {
+	slo     SLO
+	windows []time.Duration
+	bucket  time.Duration
+
+	mu      sync.Mutex
+	buckets []sloBucket
+}
+
+type sloBucket struct {
+	start                  time.Time
+	total, available, fast int64
+}
+
+// NewSLOTracker returns an SLOTracker for slo over windows, or
+// DefaultSLOWindows when none are given. Requests are counted in buckets of
+// a tenth of the shortest window.
+func NewSLOTracker(slo SLO, windows ...time.Duration) *SLOTracker {
+	if len(windows) == 0 {
+		windows = DefaultSLOWindows
+	}
+	shortest, longest := windows[0], windows[0]
+	for _, w := range windows {
+		shortest, longest = min(shortest, w), max(longest, w)
+	}
+	bucket := max(shortest/10, 1)
+	return &SLOTracker{
+		slo:     slo,
+		windows: windows,
+		bucket:  bucket,
+		buckets: make([]sloBucket, int(longest/bucket)+1),
+	}
+}
+
+// Log counts rec when it belongs to the SLO.
+func (t *SLOTracker) Log(rec Record) {
+	if rec.Type != "" && rec.Type != TypeRequest {
+		return
+	}
+	if t.slo.Match != nil && !t.slo.Match(rec) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	start := timeNow().Truncate(t.bucket)
+	b := &t.buckets[int(start.UnixNano()/int64(t.bucket))%len(t.buckets)]
+	if !b.start.Equal(start) {
+		*b = sloBucket{start: start}
+	}
+	b.total++
+	if rec.Status < 500 {
+		b.available++
+	}
+	if rec.Duration <= t.slo.Latency {
+		b.fast++
+	}
+}
+
+func (t *SLOTracker) apply(c *config) { RecordFunc(t.Log).apply(c) }
+
+// BurnRate is the state of an SLO over one window. A burn rate is the rate
+// at which the error budget, 1 - Objective, is spent: 1 spends it exactly
+// over the SLO period and 14.4 spends a 30 day budget in about 2 days.
+type BurnRate struct {
+	Window           time.Duration
+	Requests         int64
+	Availability     float64
+	AvailabilityBurn float64
+	// LatencySLI and LatencyBurn are only set when SLO.Latency is.
+	LatencySLI  float64
+	LatencyBurn float64
+}
+
+// BurnRates returns the burn rates of the SLO over each window. The SLIs of
+// a window without requests are 1.
+func (t *SLOTracker) BurnRates() []BurnRate {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := timeNow().Truncate(t.bucket)
+	rates := make([]BurnRate, len(t.windows))
+	for i, window := range t.windows {
+		oldest := now.Add(-window + t.bucket)
+		var total, available, fast int64
+		for _, b := range t.buckets {
+			if b.total == 0 || b.start.Before(oldest) {
+				continue
+			}
+			total += b.total
+			available += b.available
+			fast += b.fast
+		}
+		rate := BurnRate{Window: window, Requests: total, Availability: ratio(available, total)}
+		rate.AvailabilityBurn = t.burn(rate.Availability)
+		if t.slo.Latency > 0 {
+			rate.LatencySLI = ratio(fast, total)
+			rate.LatencyBurn = t.burn(rate.LatencySLI)
+		}
+		rates[i] = rate
+	}
+	return rates
+}
+
+func ratio(good, total int64) float64 {
+	if total == 0 {
+		return 1
+	}
+	return float64(good) / float64(total)
+}
+
+func (t *SLOTracker) burn(sli float64) float64 {
+	budget := 1 - t.slo.Objective
+	if budget <= 0 {
+		return 0
+	}
+	return (1 - sli) / budget
+}
+
+// SLOBurnRates passes a record of type TypeSLO for each window of tracker
+// to fn every interval until ctx is done, so burn rate alerts can be built
+// on the logs alone. Run it in its own goroutine:
+//
+//	go httplog.SLOBurnRates(ctx, tracker, time.Minute, sink)
+//
+// The attrs are slo, objective, window, requests, availability and
+// availability_burn_rate, and latency_sli and latency_burn_rate for SLOs
+// with a Latency.
+func SLOBurnRates(ctx context.Context, tracker *SLOTracker, interval time.Duration, fn RecordFunc) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := timeNow()
+			for _, rate := range tracker.BurnRates() {
+				attrs := []slog.Attr{
+					slog.String("slo", tracker.slo.Name),
+					slog.Float64("objective", tracker.slo.Objective),
+					slog.Duration("window", rate.Window),
+					slog.Int64("requests", rate.Requests),
+					slog.Float64("availability", rate.Availability),
+					slog.Float64("availability_burn_rate", rate.AvailabilityBurn),
+				}
+				if tracker.slo.Latency > 0 {
+					attrs = append(attrs,
+						slog.Float64("latency_sli", rate.LatencySLI),
+						slog.Float64("latency_burn_rate", rate.LatencyBurn),
+					)
+				}
+				fn(Record{Type: TypeSLO, Time: now, Attrs: attrs})
+			}
+		}
+	}
+}
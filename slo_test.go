@@ -0,0 +1,73 @@
+package httplog_test
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestSLOTracker(t *testing.T) {
+	tracker := httplog.NewSLOTracker(httplog.SLO{
+		Name:      "api",
+		Objective: 0.99,
+		Latency:   100 * time.Millisecond,
+		Match:     func(rec httplog.Record) bool { return rec.Route != "/health" },
+	}, time.Minute, time.Hour)
+	for i := 0; i < 100; i++ {
+		rec := httplog.Record{Route: "/orders", Status: http.StatusOK, Duration: 10 * time.Millisecond}
+		switch {
+		case i < 2:
+			rec.Status = http.StatusServiceUnavailable
+		case i < 6:
+			rec.Duration = time.Second
+		}
+		tracker.Log(rec)
+	}
+	tracker.Log(httplog.Record{Route: "/health", Status: http.StatusInternalServerError})
+
+	rates := tracker.BurnRates()
+	if len(rates) != 2 {
+		t.Fatalf("expected a burn rate per window got %+v", rates)
+	}
+	for _, rate := range rates {
+		if rate.Requests != 100 || rate.Availability != 0.98 || rate.LatencySLI != 0.96 {
+			t.Errorf("unexpected SLIs %+v", rate)
+		}
+		if math.Abs(rate.AvailabilityBurn-2) > 1e-9 || math.Abs(rate.LatencyBurn-4) > 1e-9 {
+			t.Errorf("unexpected burn rates %+v", rate)
+		}
+	}
+}
+
+func TestSLOBurnRates(t *testing.T) {
+	tracker := httplog.NewSLOTracker(httplog.SLO{Name: "api", Objective: 0.999})
+	tracker.Log(httplog.Record{Status: http.StatusOK})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	records := make(chan httplog.Record, len(httplog.DefaultSLOWindows))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		httplog.SLOBurnRates(ctx, tracker, time.Millisecond, func(rec httplog.Record) {
+			select {
+			case records <- rec:
+			default:
+			}
+		})
+	}()
+	rec := <-records
+	cancel()
+	<-done
+
+	fields := recordFields(rec)
+	if rec.Type != httplog.TypeSLO || fields["slo"] != "api" || fields["window"] != 5*time.Minute || fields["availability_burn_rate"] != float64(0) {
+		t.Errorf("unexpected record %s %v", rec.Type, fields)
+	}
+	if _, ok := fields["latency_burn_rate"]; ok {
+		t.Errorf("expected no latency SLI without a Latency got %v", fields)
+	}
+}
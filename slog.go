@@ -0,0 +1,87 @@
+package httplog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// contextHandler adds request fields to records logged with a request context.
+type contextHandler struct {
+	handler slog.Handler
+	// base is the handler before the first WithGroup and grouped replays the
+	// WithGroup and WithAttrs calls since, so the request fields can be
+	// added at the top level of records in a group.
+	base    slog.Handler
+	grouped []func(slog.Handler) slog.Handler
+}
+
+// ContextHandler wraps h so that every record logged with the context of a
+// request handled by Wrap includes the request_id, method, path, and, when
+// propagated, trace_id of that request. Records logged with any other
+// context are passed through unchanged.
+func ContextHandler(h slog.Handler) slog.Handler {
+	if ch, ok := h.(contextHandler); ok {
+		return ch
+	}
+	return contextHandler{handler: h}
+}
+
+func (h contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	info, ok := requestInfoFrom(ctx)
+	if !ok {
+		return h.handler.Handle(ctx, record)
+	}
+	attrs := []slog.Attr{
+		slog.String("request_id", info.ID),
+		slog.String("method", info.Method),
+		slog.String("path", info.Path),
+	}
+	if info.Trace.TraceID != "" {
+		attrs = append(attrs, slog.String("trace_id", info.Trace.TraceID))
+	}
+	if len(h.grouped) == 0 {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+		return h.handler.Handle(ctx, record)
+	}
+	handler := h.base.WithAttrs(attrs)
+	for _, replay := range h.grouped {
+		handler = replay(handler)
+	}
+	return handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(h.grouped) == 0 {
+		return contextHandler{handler: h.handler.WithAttrs(attrs)}
+	}
+	return h.with(h.handler.WithAttrs(attrs), func(handler slog.Handler) slog.Handler {
+		return handler.WithAttrs(attrs)
+	})
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	if len(h.grouped) == 0 {
+		h.base = h.handler
+	}
+	return h.with(h.handler.WithGroup(name), func(handler slog.Handler) slog.Handler {
+		return handler.WithGroup(name)
+	})
+}
+
+// with returns h with handler and replay added to the calls since the first
+// group.
+func (h contextHandler) with(handler slog.Handler, replay func(slog.Handler) slog.Handler) contextHandler {
+	return contextHandler{
+		handler: handler,
+		base:    h.base,
+		grouped: append(h.grouped[:len(h.grouped):len(h.grouped)], replay),
+	}
+}
@@ -0,0 +1,86 @@
+package httplog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestContextHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(httplog.ContextHandler(slog.NewJSONHandler(&buf, nil)))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/greeting", func(w http.ResponseWriter, r *http.Request) {
+		logger.InfoContext(r.Context(), "hello")
+		w.WriteHeader(http.StatusOK)
+	})
+	logMux := httplog.Wrap(mux, httplog.Func(func(*http.Request, time.Duration, int) {}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/greeting", nil)
+	r.Header.Set(httplog.RequestIDHeader, "some-id")
+	r.Header.Set(httplog.TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	logMux.ServeHTTP(w, r)
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		"msg":        "hello",
+		"request_id": "some-id",
+		"method":     http.MethodGet,
+		"path":       "/greeting",
+		"trace_id":   "4bf92f3577b34da6a3ce929d0e0e4736",
+	} {
+		if got := line[key]; got != want {
+			t.Errorf("expected %s to be %q got %v", key, want, got)
+		}
+	}
+}
+
+func TestContextHandler_withoutRequest(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(httplog.ContextHandler(slog.NewJSONHandler(&buf, nil)))
+
+	logger.InfoContext(context.Background(), "hello")
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := line["request_id"]; ok {
+		t.Errorf("expected no request_id got %v", line["request_id"])
+	}
+}
+
+func TestContextHandler_group(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(httplog.ContextHandler(slog.NewJSONHandler(&buf, nil))).WithGroup("db").With("table", "users")
+
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.InfoContext(r.Context(), "query", "rows", 2)
+	}), httplog.Func(func(*http.Request, time.Duration, int) {}))
+	r := httptest.NewRequest(http.MethodGet, "/users", nil)
+	r.Header.Set(httplog.RequestIDHeader, "some-id")
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line["request_id"] != "some-id" || line["path"] != "/users" {
+		t.Errorf("expected the request fields at the top level got %v", line)
+	}
+	if db, _ := line["db"].(map[string]any); db["table"] != "users" || db["rows"] != float64(2) || db["request_id"] != nil {
+		t.Errorf("expected the logged fields in the db group got %v", line["db"])
+	}
+}
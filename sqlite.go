@@ -0,0 +1,83 @@
+package httplog
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SQLiteSchema holds the statements SQLite runs to create the table records
+// are written to and its indexes.
+var SQLiteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS http_requests (
+	id INTEGER PRIMARY KEY,
+	time TEXT NOT NULL,
+	method TEXT NOT NULL,
+	path TEXT NOT NULL,
+	route TEXT,
+	query TEXT,
+	status INTEGER NOT NULL,
+	duration_ms REAL NOT NULL,
+	request_id TEXT,
+	trace_id TEXT,
+	client_ip TEXT,
+	user_agent TEXT,
+	attrs TEXT
+)`,
+	`CREATE INDEX IF NOT EXISTS http_requests_time ON http_requests (time)`,
+	`CREATE INDEX IF NOT EXISTS http_requests_status ON http_requests (status)`,
+	`CREATE INDEX IF NOT EXISTS http_requests_path ON http_requests (path)`,
+}
+
+const sqliteInsert = `INSERT INTO http_requests
+	(time, method, path, route, query, status, duration_ms, request_id, trace_id, client_ip, user_agent, attrs)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// SQLite creates the SQLiteSchema tables in db and returns a sink that
+// inserts a row for each record. The caller opens db with the SQLite driver
+// of their choice, for example:
+//
+//	db, err := sql.Open("sqlite", "access.db")
+//	...
+//	sink, err := httplog.SQLite(ctx, db)
+//	...
+//	http.Handle("/", httplog.Wrap(mux, httplog.Async(sink, 1024)))
+//
+// Times are stored as RFC 3339 text in UTC so they sort and work with the
+// SQLite date functions. Attrs are stored as a JSON object. Failed inserts
+// are counted in Stats.WriteErrors.
+func SQLite(ctx context.Context, db *sql.DB, options ...FormatOption) (RecordFunc, error) {
+	for _, stmt := range SQLiteSchema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, err
+		}
+	}
+	f := newFormat(options)
+	return func(rec Record) {
+		var attrs any
+		if len(rec.Attrs) > 0 {
+			attrs = string(f.appendJSONAttrs(nil, rec.Attrs))
+		}
+		_, err := db.Exec(sqliteInsert,
+			rec.Time.UTC().Format(time.RFC3339Nano),
+			rec.Method,
+			rec.Path,
+			nullString(rec.Route),
+			nullString(rec.Query),
+			rec.Status,
+			float64(rec.Duration)/float64(time.Millisecond),
+			nullString(rec.RequestID),
+			nullString(rec.TraceID),
+			nullString(rec.ClientIP),
+			nullString(rec.UserAgent),
+			attrs,
+		)
+		if err != nil {
+			stats.writeErrors.Add(1)
+		}
+	}, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,98 @@
+package httplog_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+// recordingDriver is a database/sql driver that records the statements it
+// executes.
+type recordingDriver struct {
+	mu    sync.Mutex
+	execs []recordedExec
+}
+
+type recordedExec struct {
+	query string
+	args  []driver.Value
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) { return recordingConn{d}, nil }
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return recordingStmt{d: c.d, query: query}, nil
+}
+func (c recordingConn) Close() error              { return nil }
+func (c recordingConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+type recordingStmt struct {
+	d     *recordingDriver
+	query string
+}
+
+func (s recordingStmt) Close() error  { return nil }
+func (s recordingStmt) NumInput() int { return -1 }
+func (s recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.execs = append(s.d.execs, recordedExec{query: s.query, args: args})
+	return driver.RowsAffected(1), nil
+}
+func (s recordingStmt) Query([]driver.Value) (driver.Rows, error) { return nil, driver.ErrSkip }
+
+func TestSQLite(t *testing.T) {
+	d := new(recordingDriver)
+	sql.Register(t.Name(), d)
+	db, err := sql.Open(t.Name(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	sink, err := httplog.SQLite(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.execs) != len(httplog.SQLiteSchema) {
+		t.Fatalf("expected the schema to be created got %d statements", len(d.execs))
+	}
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	sink(httplog.Record{
+		Time:     start,
+		Method:   http.MethodGet,
+		Path:     "/items",
+		Status:   http.StatusOK,
+		Duration: 1500 * time.Microsecond,
+		Attrs:    []slog.Attr{slog.Bool("ok", true)},
+	})
+
+	insert := d.execs[len(d.execs)-1]
+	if !strings.HasPrefix(insert.query, "INSERT INTO http_requests") {
+		t.Fatalf("expected an insert got %q", insert.query)
+	}
+	for i, want := range map[int]driver.Value{
+		0:  "2024-05-01T12:00:00Z",
+		1:  http.MethodGet,
+		2:  "/items",
+		3:  nil,
+		5:  int64(http.StatusOK),
+		6:  1.5,
+		11: `{"ok": true}`,
+	} {
+		if got := insert.args[i]; got != want {
+			t.Errorf("expected argument %d to be %v got %v", i, want, got)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package httplog
+
+import (
+	"expvar"
+	"sync/atomic"
+)
+
+// Stats counts the records produced by httplog in this process, so operators
+// can tell whether the logs are complete.
+type Stats struct {
+	// Emitted is the number of records Wrap passed to its functions.
+	Emitted uint64 `json:"emitted"`
+	// Filtered is the number of records dropped by sampling or filtering
+	// options such as WithPreflightSampling or WithTraceSampling.
+	Filtered uint64 `json:"filtered"`
+	// SinkSkipped is the number of times the Filter and Sample functions
+	// kept an emitted record from a sink. Other sinks may still have written
+	// the record, so it is not part of the Emitted and Filtered total.
+	SinkSkipped uint64 `json:"sink_skipped"`
+	// QueueDropped is the number of records dropped because an Async queue
+	// or a ToChannel channel was full or closed.
+	QueueDropped uint64 `json:"queue_dropped"`
+	// QueueDroppedOldest is the number of the QueueDropped records that were
+	// queued and discarded for a newer record by the DropOldest policy.
+	QueueDroppedOldest uint64 `json:"queue_dropped_oldest"`
+	// QueueBlocked is the number of times the Block policy made a request
+	// wait for room in a full queue.
+	QueueBlocked uint64 `json:"queue_blocked"`
+	// WriteErrors is the number of records a sink failed to write.
+	WriteErrors uint64 `json:"write_errors"`
+
+	// OverheadNanos is the total time Wrap spent outside the wrapped handler
+	// building records, including the hooks and the extractors of options.
+	// Divided by Emitted plus Filtered it is the average cost of httplog per
+	// request.
+	OverheadNanos uint64 `json:"overhead_ns"`
+	// EmitNanos is the total time spent in the functions Wrap passes records
+	// to, formatting and writing them. For Async sinks it only covers
+	// queueing the record.
+	EmitNanos uint64 `json:"emit_ns"`
+}
+
+var stats struct {
+	emitted, filtered, queueDropped, writeErrors  atomic.Uint64
+	queueDroppedOldest, queueBlocked, sinkSkipped atomic.Uint64
+	overheadNanos, emitNanos                      atomic.Uint64
+}
+
+// ReadStats returns the current counters.
+func ReadStats() Stats {
+	return Stats{
+		Emitted:      stats.emitted.Load(),
+		Filtered:     stats.filtered.Load(),
+		QueueDropped: stats.queueDropped.Load(),
+		WriteErrors:  stats.writeErrors.Load(),
+
+		QueueDroppedOldest: stats.queueDroppedOldest.Load(),
+		QueueBlocked:       stats.queueBlocked.Load(),
+		SinkSkipped:        stats.sinkSkipped.Load(),
+
+		OverheadNanos: stats.overheadNanos.Load(),
+		EmitNanos:     stats.emitNanos.Load(),
+	}
+}
+
+// PublishExpvar publishes the counters returned by ReadStats as the expvar
+// variable "httplog", making them available on /debug/vars. Like
+// expvar.Publish it panics when called more than once.
+func PublishExpvar() {
+	expvar.Publish("httplog", expvar.Func(func() any {
+		return ReadStats()
+	}))
+}
+
+// WithOverheadField adds httplog_overhead, the time Wrap spent outside the
+// wrapped handler before emitting the record, to each record, so the cost
+// of the middleware can be checked per request. It does not include the
+// time sinks take, see Stats.EmitNanos.
+func WithOverheadField() Option {
+	return optionFunc(func(c *config) {
+		c.overheadField = true
+	})
+}
@@ -0,0 +1,88 @@
+package httplog_test
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestReadStats(t *testing.T) {
+	before := httplog.ReadStats()
+
+	records := make(chan httplog.Record)
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithPreflightSampling(0), httplog.ToChannel(records, httplog.DropNewest))
+	preflight := httptest.NewRequest(http.MethodOptions, "/", nil)
+	preflight.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	logMux.ServeHTTP(httptest.NewRecorder(), preflight)
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	failing := log.New(failingWriter{}, "", 0)
+	httplog.JSON(failing, failing)(httptest.NewRequest(http.MethodGet, "/", nil), 0, http.StatusOK)
+
+	after := httplog.ReadStats()
+	if got := after.Filtered - before.Filtered; got != 1 {
+		t.Errorf("expected 1 filtered record got %d", got)
+	}
+	if got := after.Emitted - before.Emitted; got != 1 {
+		t.Errorf("expected 1 emitted record got %d", got)
+	}
+	if got := after.QueueDropped - before.QueueDropped; got != 1 {
+		t.Errorf("expected 1 dropped record got %d", got)
+	}
+	if got := after.WriteErrors - before.WriteErrors; got != 1 {
+		t.Errorf("expected 1 write error got %d", got)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+func TestPublishExpvar(t *testing.T) {
+	httplog.PublishExpvar()
+
+	v := expvar.Get("httplog")
+	if v == nil {
+		t.Fatal("expected an httplog expvar")
+	}
+	var got httplog.Stats
+	if err := json.Unmarshal([]byte(v.String()), &got); err != nil {
+		t.Fatalf("expected the expvar to hold JSON stats: %s", err)
+	}
+}
+
+func TestWithOverheadField(t *testing.T) {
+	before := httplog.ReadStats()
+
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.WithOverheadField(), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+		time.Sleep(time.Millisecond)
+	}))
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var overhead time.Duration
+	for _, a := range rec.Attrs {
+		if a.Key == "httplog_overhead" {
+			overhead = a.Value.Duration()
+		}
+	}
+	if overhead <= 0 {
+		t.Errorf("expected a positive httplog_overhead got %v", rec.Attrs)
+	}
+
+	after := httplog.ReadStats()
+	if after.OverheadNanos-before.OverheadNanos < uint64(overhead) {
+		t.Errorf("expected the overhead to be counted in the stats")
+	}
+	if got := time.Duration(after.EmitNanos - before.EmitNanos); got < time.Millisecond {
+		t.Errorf("expected the time spent in sinks to be counted got %v", got)
+	}
+}
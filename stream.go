@@ -0,0 +1,216 @@
+package httplog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Reconnect backoff of HTTPStream.
+const (
+	streamMinBackoff = 100 * time.Millisecond
+	streamMaxBackoff = 30 * time.Second
+)
+
+// streamCloseTimeout bounds the time Close waits for the collector.
+const streamCloseTimeout = 10 * time.Second
+
+// HTTPStream streams records as NDJSON, the JSON lines of JSON, in the
+// chunked body of a long running POST request, for simple collectors that
+// read a request body line by line. It is created with NewHTTPStream. An
+// HTTPStream is an Option so it can be passed to Wrap directly.
+//
+// Records are buffered while the collector is unreachable and the stream
+// reconnects with exponential backoff. Lines written to a connection that
+// then breaks may be lost, so delivery is at most once.
+type HTTPStream struct {
+	url     string
+	client  *http.Client
+	format  format
+	lines   chan []byte
+	closing chan struct{}
+	done    chan struct{}
+	// cancel aborts the request to the collector when closing times out.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.RWMutex
+	closed bool
+	err    error
+}
+
+// NewHTTPStream returns an HTTPStream posting to url that buffers up to size
+// records. Records that do not fit in the buffer are dropped and counted in
+// Stats.QueueDropped. Call Close to send the buffered records before the
+// program exits.
+func NewHTTPStream(url string, size int, options ...FormatOption) *HTTPStream {
+	s := &HTTPStream{
+		url:     url,
+		client:  &http.Client{},
+		format:  newFormat(options),
+		lines:   make(chan []byte, size),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	go s.run()
+	return s
+}
+
+// Log queues rec. It never blocks.
+func (s *HTTPStream) Log(rec Record) {
+	line := append(s.format.appendJSON(nil, rec), '\n')
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		stats.queueDropped.Add(1)
+		return
+	}
+	select {
+	case s.lines <- line:
+	default:
+		stats.queueDropped.Add(1)
+	}
+}
+
+func (s *HTTPStream) apply(c *config) { RecordFunc(s.Log).apply(c) }
+
+// Close is CloseContext with a timeout of 10 seconds.
+func (s *HTTPStream) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), streamCloseTimeout)
+	defer cancel()
+	return s.CloseContext(ctx)
+}
+
+// CloseContext stops accepting records and waits until the buffered records
+// have been sent and the collector responded. Records still buffered when
+// the collector cannot be reached are dropped. When ctx is done first the
+// request to the collector is aborted and ctx.Err is returned.
+func (s *HTTPStream) CloseContext(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.lines)
+		close(s.closing)
+	}
+	s.mu.Unlock()
+	select {
+	case <-s.done:
+		s.cancel()
+		return s.status()
+	case <-ctx.Done():
+		s.cancel()
+		<-s.done
+		return ctx.Err()
+	}
+}
+
+// Ping reports an error once the HTTPStream is closed or while it is
+// disconnected from the collector. Before the first record is sent the
+// stream has not connected yet and Ping reports no error.
+func (s *HTTPStream) Ping(context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return errors.New("httplog: stream closed")
+	}
+	return s.err
+}
+
+func (s *HTTPStream) setStatus(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *HTTPStream) status() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.err
+}
+
+func (s *HTTPStream) run() {
+	defer close(s.done)
+	backoff := streamMinBackoff
+	var pending []byte
+	for {
+		if pending == nil {
+			line, ok := <-s.lines
+			if !ok {
+				return
+			}
+			pending = line
+		}
+		var delivered bool
+		pending, delivered = s.stream(pending)
+		if pending == nil {
+			return
+		}
+		if delivered {
+			backoff = streamMinBackoff
+		}
+		select {
+		case <-s.closing:
+			// Give up on the collector rather than block Close.
+			stats.queueDropped.Add(uint64(1 + len(s.lines)))
+			return
+		case <-time.After(backoff):
+			backoff = min(2*backoff, streamMaxBackoff)
+		}
+	}
+}
+
+// stream sends first and then the queued lines in one request. It returns
+// the line it failed to send, or nil once the lines channel is closed and
+// the request finished, and whether any line was sent.
+func (s *HTTPStream) stream(first []byte) (unsent []byte, delivered bool) {
+	pr, pw := io.Pipe()
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, s.url, pr)
+	if err != nil {
+		s.setStatus(err)
+		stats.writeErrors.Add(1)
+		return first, false
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	result := make(chan error, 1)
+	go func() {
+		res, err := s.client.Do(req)
+		if err == nil {
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+			if res.StatusCode >= 300 {
+				err = fmt.Errorf("httplog: stream collector responded %s", res.Status)
+			}
+		}
+		ended := err
+		if ended == nil {
+			ended = errors.New("httplog: stream collector ended the request")
+		}
+		s.setStatus(ended)
+		_ = pr.CloseWithError(ended)
+		result <- err
+	}()
+
+	line := first
+	for {
+		if _, err := pw.Write(line); err != nil {
+			<-result
+			return line, delivered
+		}
+		if !delivered {
+			delivered = true
+			s.setStatus(nil)
+		}
+		next, ok := <-s.lines
+		if !ok {
+			_ = pw.Close()
+			s.setStatus(<-result)
+			return nil, delivered
+		}
+		line = next
+	}
+}
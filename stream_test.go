@@ -0,0 +1,146 @@
+package httplog_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+type collector struct {
+	mu       sync.Mutex
+	requests int
+	paths    chan string
+	// failAfter ends the first request after reading that many lines.
+	failAfter int
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	c.requests++
+	first := c.requests == 1
+	c.mu.Unlock()
+	if r.Header.Get("Content-Type") != "application/x-ndjson" || r.TransferEncoding[0] != "chunked" {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+	scanner := bufio.NewScanner(r.Body)
+	for n := 1; scanner.Scan(); n++ {
+		var line struct{ Path string }
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.paths <- line.Path
+		if first && n == c.failAfter {
+			// Without Connection: close net/http would wait for the
+			// rest of the body before responding.
+			w.Header().Set("Connection", "close")
+			http.Error(w, "restarting", http.StatusServiceUnavailable)
+			return
+		}
+	}
+}
+
+func waitFor(t *testing.T, paths chan string, want string) {
+	t.Helper()
+	select {
+	case got := <-paths:
+		if got != want {
+			t.Errorf("expected %s got %s", want, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", want)
+	}
+}
+
+func TestHTTPStream(t *testing.T) {
+	c := &collector{paths: make(chan string, 10)}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	stream := httplog.NewHTTPStream(server.URL, 10)
+	stream.Log(httplog.Record{Method: http.MethodGet, Path: "/a"})
+	waitFor(t, c.paths, "/a")
+	if err := stream.Ping(context.Background()); err != nil {
+		t.Errorf("expected the stream to be connected got %v", err)
+	}
+	stream.Log(httplog.Record{Method: http.MethodGet, Path: "/b"})
+	waitFor(t, c.paths, "/b")
+	if err := stream.Close(); err != nil {
+		t.Errorf("unexpected error closing the stream: %v", err)
+	}
+	if c.requests != 1 {
+		t.Errorf("expected the records to share a request got %d", c.requests)
+	}
+	if err := stream.Ping(context.Background()); err == nil {
+		t.Error("expected an error once closed")
+	}
+}
+
+func TestHTTPStream_reconnect(t *testing.T) {
+	c := &collector{paths: make(chan string, 10), failAfter: 1}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	stream := httplog.NewHTTPStream(server.URL, 10)
+	defer stream.Close()
+	stream.Log(httplog.Record{Method: http.MethodGet, Path: "/a"})
+	waitFor(t, c.paths, "/a")
+	deadline := time.Now().Add(5 * time.Second)
+	for stream.Ping(context.Background()) == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the stream to notice the collector ended the request")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	stream.Log(httplog.Record{Method: http.MethodGet, Path: "/b"})
+	waitFor(t, c.paths, "/b")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.requests != 2 {
+		t.Errorf("expected a new request after the collector failed got %d", c.requests)
+	}
+}
+
+func TestHTTPStream_idle(t *testing.T) {
+	stream := httplog.NewHTTPStream("http://127.0.0.1:1", 10)
+	defer stream.Close()
+	if err := stream.Ping(context.Background()); err != nil {
+		t.Errorf("expected an idle stream to be healthy got %v", err)
+	}
+}
+
+func TestHTTPStream_CloseContext(t *testing.T) {
+	hang := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		<-hang
+	}))
+	defer server.Close()
+	defer close(hang)
+
+	stream := httplog.NewHTTPStream(server.URL, 10)
+	stream.Log(httplog.Record{Method: http.MethodGet, Path: "/a"})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	closed := make(chan error, 1)
+	go func() { closed <- stream.CloseContext(ctx) }()
+	select {
+	case err := <-closed:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the deadline to be exceeded got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected CloseContext to give up on a collector that does not respond")
+	}
+}
@@ -0,0 +1,182 @@
+package httplog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Level returns the level of the record: slog.LevelError for a status of
+// 500 or more and slog.LevelInfo otherwise, unless an option such as
+// WithSecurityRules raised it.
+func (rec Record) Level() slog.Level {
+	level := slog.LevelInfo
+	if rec.Status >= 500 {
+		level = slog.LevelError
+	}
+	if rec.minLevel > level {
+		level = rec.minLevel
+	}
+	return level
+}
+
+// LogValue implements slog.LogValuer so a record can be logged as a single
+// attribute, for example logger.Info("proxied", "http", rec). The fields are
+// those Structured logs and are only built when the line is written.
+func (rec Record) LogValue() slog.Value {
+	if rec.Type != "" && rec.Type != TypeRequest {
+		return slog.GroupValue(append([]slog.Attr{slog.String("type", rec.Type)}, rec.Attrs...)...)
+	}
+	return slog.GroupValue(newFormat(nil).attrs(rec)...)
+}
+
+// raiseLevel makes the level of rec at least level.
+func (rec *Record) raiseLevel(level slog.Level) {
+	if rec.minLevel < level {
+		rec.minLevel = level
+	}
+}
+
+// Structured returns a RecordFunc that logs records with logger. Records are
+// logged with the message "request", or "request error" at level error, and
+// the record fields as attributes. Records of other types are logged with
+// their type as the message and their attrs. WithMessages, WithStaticAttrs
+// and WithFieldGroups adapt the output to schemas that mandate their own
+// messages and layout.
+func Structured(logger *slog.Logger, options ...FormatOption) RecordFunc {
+	f := newFormat(options)
+	return func(rec Record) {
+		ctx := context.Background()
+		if rec.Request != nil {
+			ctx = rec.Request.Context()
+		}
+		level := rec.Level()
+		if !logger.Enabled(ctx, level) {
+			return
+		}
+		if rec.Type != "" && rec.Type != TypeRequest {
+			logger.LogAttrs(ctx, level, rec.Type, append(f.static[:len(f.static):len(f.static)], rec.Attrs...)...)
+			return
+		}
+		msg := f.message
+		if level >= slog.LevelError {
+			msg = f.errorMessage
+		}
+		logger.LogAttrs(ctx, level, msg, f.attrs(rec)...)
+	}
+}
+
+// WithMessages sets the messages Structured logs requests with, instead of
+// "request" and, at level error, "request error".
+func WithMessages(message, errorMessage string) FormatOption {
+	return func(f *format) {
+		f.message = message
+		f.errorMessage = errorMessage
+	}
+}
+
+// WithStaticAttrs adds attrs, such as a log source or dataset name, to every
+// line Structured logs. Unlike logger.With they are appended after the
+// fields of the record so they can be used with the groups of
+// WithFieldGroups.
+func WithStaticAttrs(attrs ...slog.Attr) FormatOption {
+	return func(f *format) {
+		f.static = append(f.static, attrs...)
+	}
+}
+
+// WithFieldGroups makes Structured nest the fields describing the request,
+// method, host, path, route, query, client_ip and user_agent, in a group
+// named request and the status and duration in a group named response. An
+// empty name leaves those fields at the top level. For example
+//
+//	httplog.WithFieldGroups("http.request", "http.response")
+//
+// The request_id, trace_id and the attrs of the record stay at the top
+// level.
+func WithFieldGroups(request, response string) FormatOption {
+	return func(f *format) {
+		f.requestGroup = request
+		f.responseGroup = response
+	}
+}
+
+// StructuredWriter is like Structured but logs to w with a slog.JSONHandler,
+// using the keys of WithProfile when given.
+func StructuredWriter(w io.Writer, options ...FormatOption) RecordFunc {
+	var opts slog.HandlerOptions
+	if p := newFormat(options).profile; p != nil {
+		opts.ReplaceAttr = p.replaceAttr
+	}
+	return Structured(slog.New(slog.NewJSONHandler(w, &opts)), options...)
+}
+
+func (f format) attrs(rec Record) []slog.Attr {
+	request := []slog.Attr{
+		slog.String("method", rec.Method),
+	}
+	if rec.Host != "" {
+		request = append(request, slog.String("host", rec.Host))
+	}
+	request = append(request, slog.String("path", rec.Path))
+	if rec.Route != "" {
+		request = append(request, slog.String("route", rec.Route))
+	}
+	if rec.Query != "" {
+		request = append(request, slog.String("query", rec.Query))
+	}
+	response := []slog.Attr{
+		f.durationAttr("duration", rec.Duration),
+		slog.Int("status", rec.Status),
+	}
+	var ids, client []slog.Attr
+	if rec.RequestID != "" {
+		ids = append(ids, slog.String("request_id", rec.RequestID))
+	}
+	if rec.TraceID != "" {
+		ids = append(ids, slog.String("trace_id", rec.TraceID))
+	}
+	if rec.ClientIP != "" {
+		client = append(client, slog.String("client_ip", rec.ClientIP))
+	}
+	if rec.UserAgent != "" {
+		client = append(client, slog.String("user_agent", rec.UserAgent))
+	}
+
+	attrs := make([]slog.Attr, 0, 10+len(rec.Attrs)+len(f.static))
+	if f.requestGroup == "" && f.responseGroup == "" {
+		attrs = append(attrs, request...)
+		attrs = append(attrs, response...)
+		attrs = append(attrs, ids...)
+		attrs = append(attrs, client...)
+	} else {
+		attrs = appendGroup(attrs, f.requestGroup, append(request, client...))
+		attrs = appendGroup(attrs, f.responseGroup, response)
+		attrs = append(attrs, ids...)
+	}
+	attrs = append(attrs, rec.Attrs...)
+	return append(attrs, f.static...)
+}
+
+// appendGroup appends attrs to dst in a group named name, or directly when
+// name is empty.
+func appendGroup(dst []slog.Attr, name string, attrs []slog.Attr) []slog.Attr {
+	if name == "" {
+		return append(dst, attrs...)
+	}
+	return append(dst, slog.Attr{Key: name, Value: slog.GroupValue(attrs...)})
+}
+
+func (f format) durationAttr(key string, d time.Duration) slog.Attr {
+	switch f.duration {
+	case DurationMilliseconds:
+		return slog.Float64(key, float64(d)/float64(time.Millisecond))
+	case DurationNanoseconds:
+		return slog.Int64(key, d.Nanoseconds())
+	case DurationSeconds:
+		return slog.Float64(key, d.Seconds())
+	default:
+		return slog.Duration(key, d)
+	}
+}
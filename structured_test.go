@@ -0,0 +1,141 @@
+package httplog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestStructured(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}), httplog.WithServiceInfo("billing", "", ""), httplog.Structured(logger, httplog.WithDurationFormat(httplog.DurationMilliseconds)))
+
+	r := httptest.NewRequest(http.MethodGet, "/users?id=1", nil)
+	r.Header.Set(httplog.RequestIDHeader, "some-id")
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]any{
+		"level":      "ERROR",
+		"msg":        "request error",
+		"method":     http.MethodGet,
+		"path":       "/users",
+		"query":      "id=1",
+		"status":     float64(http.StatusBadGateway),
+		"request_id": "some-id",
+		"service":    "billing",
+	} {
+		if got := line[key]; got != want {
+			t.Errorf("expected %s to be %v got %v", key, want, got)
+		}
+	}
+	if _, ok := line["duration"].(float64); !ok {
+		t.Errorf("expected a numeric duration got %v", line["duration"])
+	}
+}
+
+func TestStructured_disabled(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError}))
+	logMux := httplog.Wrap(http.NotFoundHandler(), httplog.Structured(logger))
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing logged below the handler level got %q", buf.String())
+	}
+}
+
+func TestStructuredWriter(t *testing.T) {
+	var buf bytes.Buffer
+	httplog.StructuredWriter(&buf)(httplog.Record{Method: http.MethodGet, Path: "/", Status: http.StatusOK})
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected a JSON line got %q: %s", buf.String(), err)
+	}
+	if line["msg"] != "request" || line["path"] != "/" {
+		t.Errorf("unexpected line %v", line)
+	}
+}
+
+func TestStructured_options(t *testing.T) {
+	var buf bytes.Buffer
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}), httplog.StructuredWriter(&buf,
+		httplog.WithMessages("http access", "http failure"),
+		httplog.WithStaticAttrs(slog.String("dataset", "access")),
+		httplog.WithFieldGroups("http.request", "http.response"),
+	))
+
+	r := httptest.NewRequest(http.MethodGet, "/users", nil)
+	r.Header.Set(httplog.RequestIDHeader, "some-id")
+	logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+	var line struct {
+		Msg       string `json:"msg"`
+		Dataset   string `json:"dataset"`
+		RequestID string `json:"request_id"`
+		Method    string `json:"method"`
+		Request   struct {
+			Method   string `json:"method"`
+			Path     string `json:"path"`
+			ClientIP string `json:"client_ip"`
+		} `json:"http.request"`
+		Response struct {
+			Status int `json:"status"`
+		} `json:"http.response"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line.Msg != "http access" || line.Dataset != "access" || line.RequestID != "some-id" {
+		t.Errorf("unexpected top level fields in %s", buf.String())
+	}
+	if line.Method != "" || line.Request.Method != http.MethodGet || line.Request.Path != "/users" || line.Request.ClientIP == "" {
+		t.Errorf("expected request fields in the http.request group got %s", buf.String())
+	}
+	if line.Response.Status != http.StatusTeapot {
+		t.Errorf("expected the status in the http.response group got %s", buf.String())
+	}
+}
+
+func TestRecord_LogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	logger.Info("proxied", "http", httplog.Record{
+		Method: http.MethodPost,
+		Path:   "/orders",
+		Status: http.StatusCreated,
+		Attrs:  []slog.Attr{slog.String("tenant", "acme")},
+	})
+
+	var line struct {
+		HTTP map[string]any `json:"http"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]any{
+		"method": http.MethodPost,
+		"path":   "/orders",
+		"status": float64(http.StatusCreated),
+		"tenant": "acme",
+	} {
+		if got := line.HTTP[key]; got != want {
+			t.Errorf("expected %s to be %v got %v", key, want, got)
+		}
+	}
+}
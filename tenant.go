@@ -0,0 +1,72 @@
+package httplog
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TenantExtractor returns the tenant a request belongs to, or an empty
+// string when it is not known.
+type TenantExtractor func(req *http.Request) string
+
+// WithTenant adds a tenant field, as returned by extract, to each record so
+// usage and errors can be reported per tenant.
+func WithTenant(extract TenantExtractor) Option {
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			if rec.Request == nil {
+				return
+			}
+			if tenant := extract(rec.Request); tenant != "" {
+				rec.Attrs = append(rec.Attrs, slog.String("tenant", tenant))
+			}
+		})
+	})
+}
+
+// TenantFromHeader returns a TenantExtractor reading the named request
+// header, such as "X-Tenant-Id".
+func TenantFromHeader(name string) TenantExtractor {
+	return func(req *http.Request) string {
+		return req.Header.Get(name)
+	}
+}
+
+// TenantFromSubdomain returns a TenantExtractor taking the tenant from the
+// label in front of domain in the request host, so "acme.example.com" is
+// tenant "acme" for the domain "example.com". Hosts outside domain and the
+// domain itself have no tenant.
+func TenantFromSubdomain(domain string) TenantExtractor {
+	suffix := "." + strings.ToLower(strings.Trim(domain, "."))
+	return func(req *http.Request) string {
+		host := req.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.ToLower(strings.TrimSuffix(host, "."))
+		sub, ok := strings.CutSuffix(host, suffix)
+		if !ok || sub == "" {
+			return ""
+		}
+		if i := strings.LastIndexByte(sub, '.'); i >= 0 {
+			sub = sub[i+1:]
+		}
+		return sub
+	}
+}
+
+// TenantFromPathPrefix returns a TenantExtractor taking the tenant from the
+// path segment following prefix, so "/t/acme/items" is tenant "acme" for
+// the prefix "/t/".
+func TenantFromPathPrefix(prefix string) TenantExtractor {
+	return func(req *http.Request) string {
+		rest, ok := strings.CutPrefix(req.URL.Path, prefix)
+		if !ok {
+			return ""
+		}
+		tenant, _, _ := strings.Cut(rest, "/")
+		return tenant
+	}
+}
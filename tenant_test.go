@@ -0,0 +1,50 @@
+package httplog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithTenant(t *testing.T) {
+	for _, tt := range []struct {
+		Name    string
+		Extract httplog.TenantExtractor
+		Host    string
+		Path    string
+		Header  string
+		Tenant  any
+	}{
+		{Name: "header", Extract: httplog.TenantFromHeader("X-Tenant-Id"), Header: "acme", Tenant: "acme"},
+		{Name: "missing header", Extract: httplog.TenantFromHeader("X-Tenant-Id")},
+		{Name: "subdomain", Extract: httplog.TenantFromSubdomain("example.com"), Host: "ACME.example.com:8443", Tenant: "acme"},
+		{Name: "nested subdomain", Extract: httplog.TenantFromSubdomain("example.com"), Host: "eu.acme.example.com", Tenant: "acme"},
+		{Name: "apex", Extract: httplog.TenantFromSubdomain("example.com"), Host: "example.com"},
+		{Name: "other domain", Extract: httplog.TenantFromSubdomain("example.com"), Host: "acme.example.org"},
+		{Name: "path prefix", Extract: httplog.TenantFromPathPrefix("/t/"), Path: "/t/acme/items", Tenant: "acme"},
+		{Name: "other path", Extract: httplog.TenantFromPathPrefix("/t/"), Path: "/items"},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var rec httplog.Record
+			h := httplog.Wrap(http.NotFoundHandler(), httplog.WithTenant(tt.Extract), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+			path := tt.Path
+			if path == "" {
+				path = "/"
+			}
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+			if tt.Host != "" {
+				r.Host = tt.Host
+			}
+			if tt.Header != "" {
+				r.Header.Set("X-Tenant-Id", tt.Header)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got := recordFields(rec)["tenant"]; got != tt.Tenant {
+				t.Errorf("expected tenant %v got %v", tt.Tenant, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package httplog
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// WithByteRates adds request_bytes, the bytes of the request body the
+// handler read, and response_bytes, the bytes of the response body it
+// wrote, along with request_bytes_per_sec and response_bytes_per_sec over
+// the duration of the request. Low rates on large bodies point at slow
+// clients or bandwidth bound endpoints.
+func WithByteRates() Option {
+	return optionFunc(func(c *config) {
+		c.inspectors = append(c.inspectors, func(req *http.Request) func(rec *Record) {
+			var read int64
+			if req.Body != nil && req.Body != http.NoBody {
+				body := req.Body
+				req.Body = struct {
+					io.Reader
+					io.Closer
+				}{
+					Reader: &countingReader{r: body, n: &read},
+					Closer: body,
+				}
+			}
+			return func(rec *Record) {
+				var written int64
+				if rec.writer != nil {
+					written = rec.writer.written
+				}
+				rec.Attrs = append(rec.Attrs,
+					slog.Int64("request_bytes", read),
+					slog.Int64("response_bytes", written),
+				)
+				if seconds := rec.Duration.Seconds(); seconds > 0 {
+					rec.Attrs = append(rec.Attrs,
+						slog.Float64("request_bytes_per_sec", float64(read)/seconds),
+						slog.Float64("response_bytes_per_sec", float64(written)/seconds),
+					)
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,37 @@
+package httplog_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithByteRates(t *testing.T) {
+	var rec httplog.Record
+	logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		time.Sleep(10 * time.Millisecond)
+		_, _ = io.WriteString(w, "hello, world")
+	}), httplog.WithByteRates(), httplog.RecordFunc(func(r httplog.Record) {
+		rec = r
+	}))
+
+	logMux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("x", 1000))))
+
+	fields := recordFields(rec)
+	if fields["request_bytes"] != int64(1000) || fields["response_bytes"] != int64(12) {
+		t.Errorf("unexpected byte counts %v", fields)
+	}
+	rate, _ := fields["request_bytes_per_sec"].(float64)
+	if want := 1000 / rec.Duration.Seconds(); rate != want {
+		t.Errorf("expected request_bytes_per_sec %f got %v", want, fields["request_bytes_per_sec"])
+	}
+	if rate, _ := fields["response_bytes_per_sec"].(float64); rate <= 0 || rate > 1200 {
+		t.Errorf("unexpected response_bytes_per_sec %v", fields["response_bytes_per_sec"])
+	}
+}
@@ -0,0 +1,76 @@
+package httplog
+
+import "strings"
+
+// TraceParentHeader is the W3C Trace Context header carrying the trace and parent span identifiers.
+const TraceParentHeader = "Traceparent"
+
+// traceParent holds the fields of a W3C traceparent header.
+type traceParent struct {
+	TraceID string
+	SpanID  string
+	Sampled bool
+}
+
+// parseTraceParent parses a W3C traceparent header value such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func parseTraceParent(value string) (traceParent, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return traceParent{}, false
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if len(version) != 2 || !isLowerHex(version) || version == "ff" {
+		return traceParent{}, false
+	}
+	if version == "00" && len(parts) != 4 {
+		return traceParent{}, false
+	}
+	if len(traceID) != 32 || !isLowerHex(traceID) || strings.Trim(traceID, "0") == "" {
+		return traceParent{}, false
+	}
+	if len(spanID) != 16 || !isLowerHex(spanID) || strings.Trim(spanID, "0") == "" {
+		return traceParent{}, false
+	}
+	if len(flags) != 2 || !isLowerHex(flags) {
+		return traceParent{}, false
+	}
+	return traceParent{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: fromHex(flags[1])&1 == 1,
+	}, true
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func fromHex(c byte) byte {
+	if c >= 'a' {
+		return c - 'a' + 10
+	}
+	return c - '0'
+}
+
+// WithTraceSampling only logs requests with propagated trace context when
+// the trace is sampled, keeping log volume in line with trace volume.
+// Requests without trace context and requests with a status of 500 or more
+// are always logged.
+func WithTraceSampling() Option {
+	return optionFunc(func(c *config) {
+		c.filters = append(c.filters, func(rec *Record) bool {
+			if rec.Status >= 500 {
+				return true
+			}
+			info, ok := requestInfoFrom(rec.Request.Context())
+			return !ok || info.Trace.TraceID == "" || info.Trace.Sampled
+		})
+	})
+}
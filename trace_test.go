@@ -0,0 +1,42 @@
+package httplog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithTraceSampling(t *testing.T) {
+	for _, tt := range []struct {
+		Name        string
+		TraceParent string
+		Status      int
+		WantLogged  bool
+	}{
+		{Name: "sampled", TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", Status: http.StatusOK, WantLogged: true},
+		{Name: "not sampled", TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", Status: http.StatusOK, WantLogged: false},
+		{Name: "not sampled error", TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", Status: http.StatusBadGateway, WantLogged: true},
+		{Name: "no trace context", Status: http.StatusOK, WantLogged: true},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			logged := false
+			logMux := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.Status)
+			}), httplog.WithTraceSampling(), httplog.RecordFunc(func(httplog.Record) {
+				logged = true
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.TraceParent != "" {
+				r.Header.Set(httplog.TraceParentHeader, tt.TraceParent)
+			}
+			logMux.ServeHTTP(httptest.NewRecorder(), r)
+
+			if logged != tt.WantLogged {
+				t.Errorf("expected logged to be %t", tt.WantLogged)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package httplog
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ClientTransport wraps next so fn receives a Record for each outbound
+// request. When next is nil http.DefaultTransport is used.
+//
+// The record carries the request ID and trace ID of the incoming request when
+// the outbound request uses its context and Host is the host of the request
+// URL. Its attrs hold a "timing" group with the time spent on the DNS
+// lookup, connecting and the TLS handshake, the time to the first response
+// byte ("ttfb") and whether a pooled connection was "reused". Phases that
+// did not happen, such as connecting on a reused connection, are omitted.
+// When the round trip fails Status is 0 and an "error" field is added.
+func ClientTransport(next http.RoundTripper, fn RecordFunc) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return clientTransport{next: next, fn: fn}
+}
+
+type clientTransport struct {
+	next http.RoundTripper
+	fn   RecordFunc
+}
+
+func (t clientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timing := new(connTiming)
+	traced := req.WithContext(httptrace.WithClientTrace(req.Context(), timing.trace()))
+	start := timeNow()
+	res, err := t.next.RoundTrip(traced)
+
+	rec := Record{
+		Request:  req,
+		Time:     start,
+		Method:   req.Method,
+		Path:     req.URL.Path,
+		Query:    req.URL.RawQuery,
+		Duration: timeSince(start),
+		Host:     req.URL.Host,
+	}
+	if info, ok := requestInfoFrom(req.Context()); ok {
+		rec.RequestID = info.ID
+		rec.TraceID = info.Trace.TraceID
+	}
+	if attempts, ok := req.Context().Value(attemptsKey{}).(*atomic.Int64); ok {
+		rec.Attrs = append(rec.Attrs, slog.Int64("attempt", attempts.Add(1)))
+	}
+	if timingAttrs := timing.attrs(start); len(timingAttrs) > 0 {
+		rec.Attrs = append(rec.Attrs, slog.Attr{Key: "timing", Value: slog.GroupValue(timingAttrs...)})
+	}
+	if err != nil {
+		rec.Attrs = append(rec.Attrs, slog.String("error", err.Error()))
+	} else {
+		rec.Status = res.StatusCode
+		rec.ResponseHeader = res.Header
+	}
+	stats.emitted.Add(1)
+	t.fn(rec)
+	return res, err
+}
+
+type attemptsKey struct{}
+
+// RetrySummary wraps a retrying transport, next, whose attempts go through a
+// ClientTransport. Each attempt record gets an "attempt" number starting at
+// 1 and, once next returns, fn receives a summary record with the number of
+// "attempts", the total elapsed time as Duration and the final Status.
+//
+//	client.Transport = httplog.RetrySummary(
+//		retry.NewTransport(httplog.ClientTransport(http.DefaultTransport, sink)),
+//		sink,
+//	)
+//
+// Attempts are only counted when the retrying transport keeps the context of
+// the request it was given.
+func RetrySummary(next http.RoundTripper, fn RecordFunc) http.RoundTripper {
+	return retrySummary{next: next, fn: fn}
+}
+
+type retrySummary struct {
+	next http.RoundTripper
+	fn   RecordFunc
+}
+
+func (t retrySummary) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempts := new(atomic.Int64)
+	start := timeNow()
+	res, err := t.next.RoundTrip(req.WithContext(context.WithValue(req.Context(), attemptsKey{}, attempts)))
+
+	rec := Record{
+		Request:  req,
+		Time:     start,
+		Method:   req.Method,
+		Path:     req.URL.Path,
+		Query:    req.URL.RawQuery,
+		Duration: timeSince(start),
+		Host:     req.URL.Host,
+		Attrs:    []slog.Attr{slog.Int64("attempts", attempts.Load())},
+	}
+	if info, ok := requestInfoFrom(req.Context()); ok {
+		rec.RequestID = info.ID
+		rec.TraceID = info.Trace.TraceID
+	}
+	if err != nil {
+		rec.Attrs = append(rec.Attrs, slog.String("error", err.Error()))
+	} else {
+		rec.Status = res.StatusCode
+		rec.ResponseHeader = res.Header
+	}
+	stats.emitted.Add(1)
+	t.fn(rec)
+	return res, err
+}
+
+// connTiming collects connection timings from an httptrace.ClientTrace. The
+// transport may call the hooks from dialing goroutines so access is guarded.
+type connTiming struct {
+	mu                        sync.Mutex
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	firstByte                 time.Time
+	reused, gotConn           bool
+}
+
+func (c *connTiming) set(t *time.Time, once bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if once && !t.IsZero() {
+		return
+	}
+	*t = timeNow()
+}
+
+func (c *connTiming) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { c.set(&c.dnsStart, true) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { c.set(&c.dnsDone, false) },
+		ConnectStart:      func(string, string) { c.set(&c.connectStart, true) },
+		ConnectDone:       func(string, string, error) { c.set(&c.connectDone, false) },
+		TLSHandshakeStart: func() { c.set(&c.tlsStart, true) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { c.set(&c.tlsDone, false) },
+		GotFirstResponseByte: func() {
+			c.set(&c.firstByte, true)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.gotConn = true
+			c.reused = info.Reused
+		},
+	}
+}
+
+func (c *connTiming) attrs(start time.Time) []slog.Attr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var attrs []slog.Attr
+	if !c.dnsStart.IsZero() && !c.dnsDone.IsZero() {
+		attrs = append(attrs, slog.Duration("dns", c.dnsDone.Sub(c.dnsStart)))
+	}
+	if !c.connectStart.IsZero() && !c.connectDone.IsZero() {
+		attrs = append(attrs, slog.Duration("connect", c.connectDone.Sub(c.connectStart)))
+	}
+	if !c.tlsStart.IsZero() && !c.tlsDone.IsZero() {
+		attrs = append(attrs, slog.Duration("tls", c.tlsDone.Sub(c.tlsStart)))
+	}
+	if !c.firstByte.IsZero() {
+		attrs = append(attrs, slog.Duration("ttfb", c.firstByte.Sub(start)))
+	}
+	if c.gotConn {
+		attrs = append(attrs, slog.Bool("reused", c.reused))
+	}
+	return attrs
+}
@@ -0,0 +1,144 @@
+package httplog_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestClientTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	var records []httplog.Record
+	client := &http.Client{Transport: httplog.ClientTransport(server.Client().Transport, func(rec httplog.Record) {
+		records = append(records, rec)
+	})}
+	for i := 0; i < 2; i++ {
+		res, err := client.Get(server.URL + "/items?page=2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records got %d", len(records))
+	}
+	rec := records[0]
+	if rec.Status != http.StatusAccepted || rec.Path != "/items" || rec.Query != "page=2" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+	first, ok := groupFields(rec, "timing")
+	if !ok {
+		t.Fatalf("expected timing group got %v", rec.Attrs)
+	}
+	for _, key := range []string{"connect", "tls", "ttfb"} {
+		if _, ok := first[key]; !ok {
+			t.Errorf("expected %s timing got %v", key, first)
+		}
+	}
+	if first["reused"] != false {
+		t.Errorf("expected a new connection got %v", first["reused"])
+	}
+	second, _ := groupFields(records[1], "timing")
+	if second["reused"] != true {
+		t.Errorf("expected a reused connection got %v", second["reused"])
+	}
+	if _, ok := second["connect"]; ok {
+		t.Errorf("expected no connect timing on a reused connection got %v", second)
+	}
+}
+
+func TestClientTransport_requestContext(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	var rec httplog.Record
+	client := &http.Client{Transport: httplog.ClientTransport(nil, func(r httplog.Record) { rec = r })}
+	h := httplog.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, server.URL, nil)
+		res, err := client.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		_ = res.Body.Close()
+	}), httplog.Func(func(*http.Request, time.Duration, int) {}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(httplog.RequestIDHeader, "some-id")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if rec.RequestID != "some-id" {
+		t.Errorf("expected request id some-id got %q", rec.RequestID)
+	}
+	if rec.Status != http.StatusNotFound {
+		t.Errorf("expected status 404 got %d", rec.Status)
+	}
+}
+
+type retryTransport struct {
+	next http.RoundTripper
+	max  int
+}
+
+func (t retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for i := 1; ; i++ {
+		res, err := t.next.RoundTrip(req.Clone(req.Context()))
+		if err != nil || res.StatusCode != http.StatusServiceUnavailable || i == t.max {
+			return res, err
+		}
+		_ = res.Body.Close()
+	}
+}
+
+func TestRetrySummary(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var records []httplog.Record
+	sink := httplog.RecordFunc(func(rec httplog.Record) { records = append(records, rec) })
+	client := &http.Client{Transport: httplog.RetrySummary(retryTransport{
+		next: httplog.ClientTransport(nil, sink),
+		max:  5,
+	}, sink)}
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	if len(records) != 4 {
+		t.Fatalf("expected 3 attempts and a summary got %d records", len(records))
+	}
+	for i, rec := range records[:3] {
+		if got := recordFields(rec)["attempt"]; got != int64(i+1) {
+			t.Errorf("expected attempt %d got %v", i+1, got)
+		}
+	}
+	summary := records[3]
+	if got := recordFields(summary)["attempts"]; got != int64(3) {
+		t.Errorf("expected 3 attempts got %v", got)
+	}
+	if summary.Status != http.StatusOK {
+		t.Errorf("expected final status 200 got %d", summary.Status)
+	}
+	if summary.Duration < records[0].Duration+records[1].Duration+records[2].Duration {
+		t.Errorf("expected the summary duration to cover all attempts")
+	}
+}
@@ -0,0 +1,114 @@
+package httplog
+
+import (
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// VersionExtractor returns the API version a request asks for, or an empty
+// string when it does not say.
+type VersionExtractor func(req *http.Request) string
+
+// WithAPIVersion adds api_version, the first version returned by extract,
+// to each record so deprecations and migrations can be tracked from
+// traffic. VersionFromPath and VersionFromAccept are used when no extractors
+// are given. Numeric versions are logged with a "v" prefix, so a path
+// /v2/items, an Accept header application/vnd.acme.v2+json and a header
+// value 2 are all logged as v2. Other values, such as dates, are logged as
+// they are.
+func WithAPIVersion(extract ...VersionExtractor) Option {
+	if len(extract) == 0 {
+		extract = []VersionExtractor{VersionFromPath(), VersionFromAccept()}
+	}
+	return optionFunc(func(c *config) {
+		c.extractors = append(c.extractors, func(rec *Record) {
+			if rec.Request == nil {
+				return
+			}
+			for _, fn := range extract {
+				if v := fn(rec.Request); v != "" {
+					rec.Attrs = append(rec.Attrs, slog.String("api_version", normalizeVersion(v)))
+					return
+				}
+			}
+		})
+	})
+}
+
+// VersionFromPath returns a VersionExtractor reading a version from the
+// first path segment, such as /v2/items.
+func VersionFromPath() VersionExtractor {
+	return func(req *http.Request) string {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+		if isVersion(segment) {
+			return segment
+		}
+		return ""
+	}
+}
+
+// VersionFromAccept returns a VersionExtractor reading a version from a
+// vendor media type in the Accept header, such as
+// application/vnd.acme.v2+json, or from its version parameter, such as
+// application/json; version=2.
+func VersionFromAccept() VersionExtractor {
+	return func(req *http.Request) string {
+		for _, accept := range strings.Split(req.Header.Get("Accept"), ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+			if err != nil {
+				continue
+			}
+			if v := params["version"]; v != "" {
+				return v
+			}
+			_, subtype, _ := strings.Cut(mediaType, "/")
+			subtype, _, _ = strings.Cut(subtype, "+")
+			if !strings.HasPrefix(subtype, "vnd.") {
+				continue
+			}
+			for _, part := range strings.Split(subtype, ".") {
+				if isVersion(part) {
+					return part
+				}
+			}
+		}
+		return ""
+	}
+}
+
+// VersionFromHeader returns a VersionExtractor reading the named header,
+// such as "Api-Version".
+func VersionFromHeader(name string) VersionExtractor {
+	return func(req *http.Request) string {
+		return req.Header.Get(name)
+	}
+}
+
+// isVersion reports whether s looks like v2 or v2.1.
+func isVersion(s string) bool {
+	return len(s) > 1 && (s[0] == 'v' || s[0] == 'V') && isVersionNumber(s[1:])
+}
+
+func isVersionNumber(s string) bool {
+	if s == "" || s[0] == '.' || s[len(s)-1] == '.' {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeVersion(v string) string {
+	if isVersionNumber(v) {
+		return "v" + v
+	}
+	if isVersion(v) {
+		return "v" + v[1:]
+	}
+	return v
+}
@@ -0,0 +1,47 @@
+package httplog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestWithAPIVersion(t *testing.T) {
+	for _, tt := range []struct {
+		Name    string
+		Extract []httplog.VersionExtractor
+		Path    string
+		Header  http.Header
+		Want    any
+	}{
+		{Name: "path", Path: "/v2/items", Want: "v2"},
+		{Name: "path minor", Path: "/V1.1/items", Want: "v1.1"},
+		{Name: "not a version", Path: "/videos/1"},
+		{Name: "vendor media type", Path: "/items", Header: http.Header{"Accept": {"application/vnd.acme.v3+json"}}, Want: "v3"},
+		{Name: "media type parameter", Path: "/items", Header: http.Header{"Accept": {"text/html, application/json; version=2"}}, Want: "v2"},
+		{Name: "path first", Path: "/v1/items", Header: http.Header{"Accept": {"application/vnd.acme.v3+json"}}, Want: "v1"},
+		{
+			Name:    "header",
+			Extract: []httplog.VersionExtractor{httplog.VersionFromHeader("Api-Version")},
+			Path:    "/v1/items",
+			Header:  http.Header{"Api-Version": {"2024-05-01"}},
+			Want:    "2024-05-01",
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var rec httplog.Record
+			h := httplog.Wrap(http.NotFoundHandler(), httplog.WithAPIVersion(tt.Extract...), httplog.RecordFunc(func(r httplog.Record) { rec = r }))
+			r := httptest.NewRequest(http.MethodGet, tt.Path, nil)
+			for name, values := range tt.Header {
+				r.Header[name] = values
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got := recordFields(rec)["api_version"]; got != tt.Want {
+				t.Errorf("expected api_version %v got %v", tt.Want, got)
+			}
+		})
+	}
+}
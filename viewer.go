@@ -0,0 +1,153 @@
+package httplog
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Viewer returns a handler serving an HTML page of the records in ring,
+// newest first, for quick triage from a browser. The table can be sorted by
+// clicking a column header and filtered by status class with the status
+// query parameter, for example ?status=5xx. A sparkline shows the durations
+// of the listed requests.
+//
+// The page shows paths, queries and client addresses so mount it behind
+// authentication:
+//
+//	ring := httplog.NewRing(1000)
+//	http.Handle("/", httplog.Wrap(mux, ring, httplog.JSONWriter(os.Stdout, os.Stderr)))
+//	admin.Handle("/debug/requests", httplog.Viewer(ring))
+func Viewer(ring *Ring) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class := r.URL.Query().Get("status")
+		var rows []viewerRow
+		for _, rec := range ring.Records() {
+			if rec.Type != "" && rec.Type != TypeRequest {
+				continue
+			}
+			if class != "" && !strings.HasPrefix(class, strconv.Itoa(rec.Status/100)) {
+				continue
+			}
+			rows = append(rows, viewerRow{
+				Record: rec,
+				Millis: float64(rec.Duration) / float64(time.Millisecond),
+				Class:  rec.Status / 100,
+			})
+		}
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].Time.After(rows[j].Time) })
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = viewerTemplate.Execute(w, viewerPage{
+			Rows:      rows,
+			Status:    class,
+			Classes:   []string{"2xx", "3xx", "4xx", "5xx"},
+			Sparkline: sparkline(rows, 300, 40),
+		})
+	})
+}
+
+type viewerPage struct {
+	Rows      []viewerRow
+	Status    string
+	Classes   []string
+	Sparkline string
+}
+
+type viewerRow struct {
+	Record
+	Millis float64
+	Class  int
+}
+
+// sparkline returns the points of an SVG polyline of row durations, oldest
+// on the left.
+func sparkline(rows []viewerRow, width, height float64) string {
+	if len(rows) < 2 {
+		return ""
+	}
+	var max float64
+	for _, row := range rows {
+		if row.Millis > max {
+			max = row.Millis
+		}
+	}
+	if max == 0 {
+		max = 1
+	}
+	var b strings.Builder
+	for i := range rows {
+		row := rows[len(rows)-1-i]
+		x := float64(i) / float64(len(rows)-1) * width
+		y := height - row.Millis/max*height
+		fmt.Fprintf(&b, "%.1f,%.1f ", x, y)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+var viewerTemplate = template.Must(template.New("viewer").Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>httplog</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+table { border-collapse: collapse; width: 100%; font-size: 90%; }
+th, td { text-align: left; padding: 2px 8px; border-bottom: 1px solid #ddd; }
+th { cursor: pointer; background: #f4f4f4; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+tr.s4 td.status { color: #b36b00; }
+tr.s5 td.status { color: #c00; font-weight: bold; }
+nav a { margin-right: 1em; }
+nav a.active { font-weight: bold; }
+</style>
+</head>
+<body>
+<nav>
+<a href="?"{{if not .Status}} class="active"{{end}}>all</a>
+{{- range .Classes}}
+<a href="?status={{.}}"{{if eq . $.Status}} class="active"{{end}}>{{.}}</a>
+{{- end}}
+</nav>
+{{with .Sparkline}}<p><svg width="300" height="40" role="img" aria-label="durations"><polyline fill="none" stroke="#36c" stroke-width="1" points="{{.}}"/></svg></p>{{end}}
+<table id="requests">
+<thead><tr><th>time</th><th>method</th><th>path</th><th>status</th><th>duration (ms)</th><th>client</th><th>request id</th></tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr class="s{{.Class}}">
+<td data-sort="{{.Time.UnixNano}}">{{.Time.Format "15:04:05.000"}}</td>
+<td>{{.Method}}</td>
+<td>{{.Path}}{{with .Query}}?{{.}}{{end}}</td>
+<td class="status num">{{.Status}}</td>
+<td class="num" data-sort="{{.Millis}}">{{printf "%.3f" .Millis}}</td>
+<td>{{.ClientIP}}</td>
+<td>{{.RequestID}}</td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+<script>
+document.querySelectorAll("#requests th").forEach(function (th, column) {
+  var ascending = false;
+  th.addEventListener("click", function () {
+    var tbody = document.querySelector("#requests tbody");
+    var rows = Array.from(tbody.rows);
+    ascending = !ascending;
+    rows.sort(function (a, b) {
+      var x = a.cells[column].dataset.sort || a.cells[column].textContent;
+      var y = b.cells[column].dataset.sort || b.cells[column].textContent;
+      var n = parseFloat(x) - parseFloat(y);
+      var c = isNaN(n) ? x.localeCompare(y) : n;
+      return ascending ? c : -c;
+    });
+    rows.forEach(function (row) { tbody.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,37 @@
+package httplog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crhntr/httplog"
+)
+
+func TestViewer(t *testing.T) {
+	ring := httplog.NewRing(10)
+	start := time.Now()
+	ring.Log(httplog.Record{Time: start, Method: http.MethodGet, Path: "/ok", Status: http.StatusOK, Duration: time.Millisecond})
+	ring.Log(httplog.Record{Time: start.Add(time.Second), Method: http.MethodGet, Path: "/<script>", Status: http.StatusBadGateway, Duration: 2 * time.Millisecond})
+
+	w := httptest.NewRecorder()
+	httplog.Viewer(ring).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	body := w.Body.String()
+	if !strings.Contains(body, "<polyline") || !strings.Contains(body, "/ok") {
+		t.Errorf("expected a sparkline and both rows got %s", body)
+	}
+	if strings.Contains(body, "/<script>") || !strings.Contains(body, "/&lt;script&gt;") {
+		t.Errorf("expected paths to be escaped")
+	}
+	if strings.Index(body, "/&lt;script&gt;") > strings.Index(body, "/ok") {
+		t.Errorf("expected the newest request first")
+	}
+
+	w = httptest.NewRecorder()
+	httplog.Viewer(ring).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?status=5xx", nil))
+	if body := w.Body.String(); strings.Contains(body, "/ok") || !strings.Contains(body, `class="s5"`) {
+		t.Errorf("expected only 5xx rows got %s", body)
+	}
+}